@@ -22,6 +22,7 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deprecation"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/destinationrule"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/gateway"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/grpc"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/injection"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/multicluster"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/schema"
@@ -45,6 +46,7 @@ func All() []analysis.Analyzer {
 		&gateway.CertificateAnalyzer{},
 		&gateway.SecretAnalyzer{},
 		&gateway.ConflictingGatewayAnalyzer{},
+		&grpc.UnsupportedFieldsAnalyzer{},
 		&injection.Analyzer{},
 		&injection.ImageAnalyzer{},
 		&injection.ImageAutoAnalyzer{},
@@ -31,6 +31,7 @@ import (
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/deprecation"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/destinationrule"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/gateway"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/grpc"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/injection"
 	"istio.io/istio/galley/pkg/config/analysis/analyzers/multicluster"
 	schemaValidation "istio.io/istio/galley/pkg/config/analysis/analyzers/schema"
@@ -569,6 +570,17 @@ var testGrid = []testCase{
 			{msg.ImageAutoWithoutInjectionError, "Pod injected-pod.default"},
 		},
 	},
+	{
+		name: "Detect fields not supported by proxyless gRPC clients",
+		inputFiles: []string{
+			"testdata/grpc-unsupported-fields.yaml",
+		},
+		analyzer: &grpc.UnsupportedFieldsAnalyzer{},
+		expected: []message{
+			{msg.UnsupportedGrpcFields, "DestinationRule unsupported.default"},
+			{msg.UnsupportedGrpcFields, "VirtualService unsupported.default"},
+		},
+	},
 }
 
 // regex patterns for analyzer names that should be explicitly ignored for testing
@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpc
+
+import (
+	"strings"
+
+	"istio.io/api/annotation"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/galley/pkg/config/analysis"
+	"istio.io/istio/galley/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/galley/pkg/config/analysis/msg"
+	"istio.io/istio/pilot/pkg/networking/grpcgen"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// UnsupportedFieldsAnalyzer reports DestinationRule and VirtualService fields that are ignored by
+// proxyless gRPC clients, if there are any such clients in the mesh.
+type UnsupportedFieldsAnalyzer struct{}
+
+var _ analysis.Analyzer = &UnsupportedFieldsAnalyzer{}
+
+// Metadata implements Analyzer.
+func (a *UnsupportedFieldsAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "grpc.UnsupportedFieldsAnalyzer",
+		Description: "Checks for DestinationRule and VirtualService fields that are not supported by proxyless gRPC clients",
+		Inputs: collection.Names{
+			collections.K8SCoreV1Pods.Name(),
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer.
+func (a *UnsupportedFieldsAnalyzer) Analyze(c analysis.Context) {
+	if !hasProxylessGrpcClients(c) {
+		return
+	}
+	c.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		fields := grpcgen.UnsupportedDestinationRuleFields(r.Message.(*v1alpha3.DestinationRule))
+		if len(fields) > 0 {
+			m := msg.NewUnsupportedGrpcFields(r, "DestinationRule", r.Metadata.FullName.String(), strings.Join(fields, ", "))
+			c.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), m)
+		}
+		return true
+	})
+	c.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(r *resource.Instance) bool {
+		vs := r.Message.(*v1alpha3.VirtualService)
+		if !appliesToMesh(vs) {
+			return true
+		}
+		fields := grpcgen.UnsupportedVirtualServiceFields(vs)
+		if len(fields) > 0 {
+			m := msg.NewUnsupportedGrpcFields(r, "VirtualService", r.Metadata.FullName.String(), strings.Join(fields, ", "))
+			c.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), m)
+		}
+		return true
+	})
+}
+
+// hasProxylessGrpcClients checks if any pod was injected with one of the gRPC templates.
+func hasProxylessGrpcClients(c analysis.Context) bool {
+	found := false
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		for _, t := range strings.Split(r.Metadata.Annotations[annotation.InjectTemplates.Name], ",") {
+			if strings.HasPrefix(strings.TrimSpace(t), "grpc-") {
+				found = true
+				return false
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// appliesToMesh checks if the VirtualService applies to sidecars, rather than only to gateways.
+func appliesToMesh(vs *v1alpha3.VirtualService) bool {
+	if len(vs.GetGateways()) == 0 {
+		return true
+	}
+	for _, gw := range vs.GetGateways() {
+		if gw == util.MeshGateway {
+			return true
+		}
+	}
+	return false
+}
@@ -188,6 +188,10 @@ var (
 	// NamespaceInjectionEnabledByDefault defines a diag.MessageType for message "NamespaceInjectionEnabledByDefault".
 	// Description: user namespace should be injectable if Istio is installed with enableNamespacesByDefault enabled and neither injection label is set.
 	NamespaceInjectionEnabledByDefault = diag.NewMessageType(diag.Info, "IST0148", "is enabled for Istio injection, as Istio is installed with enableNamespacesByDefault as true.")
+
+	// UnsupportedGrpcFields defines a diag.MessageType for message "UnsupportedGrpcFields".
+	// Description: Proxyless gRPC clients ignore some configuration fields.
+	UnsupportedGrpcFields = diag.NewMessageType(diag.Info, "IST0149", "%s %s sets fields that are ignored by the proxyless gRPC clients in the mesh: %s")
 )
 
 // All returns a list of all known message types.
@@ -238,6 +242,7 @@ func All() []*diag.MessageType {
 		ImageAutoWithoutInjectionWarning,
 		ImageAutoWithoutInjectionError,
 		NamespaceInjectionEnabledByDefault,
+		UnsupportedGrpcFields,
 	}
 }
 
@@ -690,3 +695,14 @@ func NewNamespaceInjectionEnabledByDefault(r *resource.Instance) diag.Message {
 		r,
 	)
 }
+
+// NewUnsupportedGrpcFields returns a new diag.Message based on UnsupportedGrpcFields.
+func NewUnsupportedGrpcFields(r *resource.Instance, resourceType string, resourceName string, fields string) diag.Message {
+	return diag.NewMessage(
+		UnsupportedGrpcFields,
+		r,
+		resourceType,
+		resourceName,
+		fields,
+	)
+}
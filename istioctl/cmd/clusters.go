@@ -19,20 +19,41 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
 
 	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/cluster"
 )
 
+// gatewayProbeTimeout is the timeout of a connection to a network gateway when probing its reachability.
+const gatewayProbeTimeout = 3 * time.Second
+
 // TODO move to multicluster package; requires exposing some private funcs/vars in this package
 func clustersCommand() *cobra.Command {
 	var opts clioptions.ControlPlaneOptions
+	var probeGateways bool
 	cmd := &cobra.Command{
 		Use:   "remote-clusters",
 		Short: "Lists the remote clusters each istiod instance is connected to.",
+		Long: `Lists the remote clusters each istiod instance is connected to, with their sync diagnostics:
+how long the initial informer sync took, the number of services and endpoints each cluster contributes,
+the number of API server watch errors, the time of the last event by resource type, and when the
+credential of the remote secret expires. The network gateways known to each istiod are listed as well,
+and can be probed for reachability from the machine running istioctl with --probe-gateways.`,
+		Example: `  # List the remote clusters of the default revision
+  istioctl x remote-clusters
+
+  # Also check that the network gateways accept connections
+  istioctl x remote-clusters --probe-gateways`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
 			if err != nil {
@@ -42,29 +63,87 @@ func clustersCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return writeMulticlusterStatus(cmd.OutOrStdout(), res)
+			if err := writeMulticlusterStatus(cmd.OutOrStdout(), res, time.Now()); err != nil {
+				return err
+			}
+			gateways, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace, "/debug/networkz")
+			if err != nil {
+				return err
+			}
+			var probe func(addr string) error
+			if probeGateways {
+				probe = probeGateway
+			}
+			return writeNetworkGateways(cmd.OutOrStdout(), gateways, probe)
 		},
 	}
+	cmd.Flags().BoolVar(&probeGateways, "probe-gateways", false,
+		"Check that each network gateway accepts TCP connections from the machine running istioctl")
 	opts.AttachControlPlaneFlags(cmd)
 	return cmd
 }
 
-func writeMulticlusterStatus(out io.Writer, input map[string][]byte) error {
+func writeMulticlusterStatus(out io.Writer, input map[string][]byte, now time.Time) error {
 	statuses, err := parseClusterStatuses(input)
 	if err != nil {
 		return err
 	}
 	w := new(tabwriter.Writer).Init(out, 0, 8, 5, ' ', 0)
-	_, _ = fmt.Fprintln(w, "NAME\tSECRET\tSTATUS\tISTIOD")
-	for istiod, clusters := range statuses {
-		for _, c := range clusters {
-			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.ID, c.SecretName, c.SyncStatus, istiod)
+	_, _ = fmt.Fprintln(w, "NAME\tSECRET\tSTATUS\tSYNC TIME\tSERVICES\tENDPOINTS\tAPI ERRORS\tLAST EVENT\tCREDENTIAL EXPIRY\tISTIOD")
+	istiods := make([]string, 0, len(statuses))
+	for istiod := range statuses {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	for _, istiod := range istiods {
+		for _, c := range statuses[istiod] {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n", c.ID, c.SecretName, c.SyncStatus,
+				syncTime(c, now), c.Services, c.Endpoints, c.APIServerErrors, lastEvent(c.LastEvents, now),
+				credentialExpiry(c.CredentialExpiry, now), istiod)
 		}
 	}
 	_ = w.Flush()
 	return nil
 }
 
+// syncTime describes how long the initial sync of a cluster took, or has been running for.
+func syncTime(c cluster.DebugInfo, now time.Time) string {
+	switch {
+	case c.SyncDuration > 0:
+		return c.SyncDuration.Round(time.Millisecond).String()
+	case c.SyncStarted != nil:
+		return duration.HumanDuration(now.Sub(*c.SyncStarted)) + " (running)"
+	default:
+		return "-"
+	}
+}
+
+// lastEvent describes the most recent informer event of a cluster and its resource type.
+func lastEvent(events map[string]time.Time, now time.Time) string {
+	var latestType string
+	var latest time.Time
+	for t, ts := range events {
+		if ts.After(latest) || (ts.Equal(latest) && t < latestType) {
+			latestType, latest = t, ts
+		}
+	}
+	if latestType == "" {
+		return "-"
+	}
+	return fmt.Sprintf("%s ago (%s)", duration.HumanDuration(now.Sub(latest)), latestType)
+}
+
+func credentialExpiry(expiry *time.Time, now time.Time) string {
+	switch {
+	case expiry == nil:
+		return "-"
+	case expiry.Before(now):
+		return fmt.Sprintf("EXPIRED %s ago", duration.HumanDuration(now.Sub(*expiry)))
+	default:
+		return "in " + duration.HumanDuration(expiry.Sub(now))
+	}
+}
+
 func parseClusterStatuses(input map[string][]byte) (map[string][]cluster.DebugInfo, error) {
 	statuses := make(map[string][]cluster.DebugInfo, len(input))
 	for istiodKey, bytes := range input {
@@ -72,7 +151,62 @@ func parseClusterStatuses(input map[string][]byte) (map[string][]cluster.DebugIn
 		if err := json.Unmarshal(bytes, &parsed); err != nil {
 			return nil, err
 		}
+		sort.Slice(parsed, func(i, j int) bool { return parsed[i].ID < parsed[j].ID })
 		statuses[istiodKey] = parsed
 	}
 	return statuses, nil
 }
+
+// writeNetworkGateways prints the network gateways known to each istiod. If probe is set, the reachability of
+// each gateway is reported as well.
+func writeNetworkGateways(out io.Writer, input map[string][]byte, probe func(addr string) error) error {
+	gateways := make(map[string][]model.NetworkGateway, len(input))
+	for istiod, bytes := range input {
+		var parsed []model.NetworkGateway
+		if err := json.Unmarshal(bytes, &parsed); err != nil {
+			return err
+		}
+		gateways[istiod] = parsed
+	}
+	_, _ = fmt.Fprintln(out)
+	w := new(tabwriter.Writer).Init(out, 0, 8, 5, ' ', 0)
+	header := []string{"NETWORK", "CLUSTER", "GATEWAY"}
+	if probe != nil {
+		header = append(header, "REACHABLE")
+	}
+	header = append(header, "ISTIOD")
+	_, _ = fmt.Fprintln(w, strings.Join(header, "\t"))
+	probed := map[string]string{}
+	istiods := make([]string, 0, len(gateways))
+	for istiod := range gateways {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	for _, istiod := range istiods {
+		for _, gw := range gateways[istiod] {
+			addr := net.JoinHostPort(gw.Addr, strconv.Itoa(int(gw.Port)))
+			row := []string{string(gw.Network), string(gw.Cluster), addr}
+			if probe != nil {
+				if _, ok := probed[addr]; !ok {
+					probed[addr] = "yes"
+					if err := probe(addr); err != nil {
+						probed[addr] = fmt.Sprintf("no: %v", err)
+					}
+				}
+				row = append(row, probed[addr])
+			}
+			row = append(row, istiod)
+			_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+	}
+	return w.Flush()
+}
+
+// probeGateway checks that a network gateway accepts TCP connections.
+func probeGateway(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, gatewayProbeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
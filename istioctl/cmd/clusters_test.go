@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// nolint: lll
+func TestWriteMulticlusterStatus(t *testing.T) {
+	input := map[string][]byte{
+		"istiod-b.istio-system": []byte(`[{"id":"remote","secretName":"istio-system/istio-remote-secret-remote","syncStatus":"syncing",
+"syncStarted":"2021-08-01T11:59:30Z","apiServerErrors":3}]`),
+		"istiod-a.istio-system": []byte(`[
+{"id":"remote","secretName":"istio-system/istio-remote-secret-remote","syncStatus":"synced",
+"syncStarted":"2021-08-01T10:00:00Z","syncDuration":1500000000,"services":10,"endpoints":25,
+"lastEvents":{"Pods":"2021-08-01T11:59:00Z","Services":"2021-08-01T11:50:00Z"},"credentialExpiry":"2021-08-31T12:00:00Z"},
+{"id":"expired","secretName":"istio-system/istio-remote-secret-expired","syncStatus":"timeout",
+"credentialExpiry":"2021-08-01T10:00:00Z"}]`),
+	}
+	now := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	if err := writeMulticlusterStatus(&out, input, now); err != nil {
+		t.Fatal(err)
+	}
+	want := `NAME        SECRET                                       STATUS      SYNC TIME         SERVICES     ENDPOINTS     API ERRORS     LAST EVENT         CREDENTIAL EXPIRY     ISTIOD
+expired     istio-system/istio-remote-secret-expired     timeout     -                 0            0             0              -                  EXPIRED 120m ago      istiod-a.istio-system
+remote      istio-system/istio-remote-secret-remote      synced      1.5s              10           25            0              60s ago (Pods)     in 30d                istiod-a.istio-system
+remote      istio-system/istio-remote-secret-remote      syncing     30s (running)     0            0             3              -                  -                     istiod-b.istio-system
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteNetworkGateways(t *testing.T) {
+	input := map[string][]byte{
+		"istiod-a.istio-system": []byte(`[{"Network":"network-1","Cluster":"primary","Addr":"10.0.0.1","Port":15443},
+{"Network":"network-2","Cluster":"remote","Addr":"10.0.0.2","Port":15443}]`),
+	}
+	probed := 0
+	probe := func(addr string) error {
+		probed++
+		if addr == "10.0.0.2:15443" {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	var out bytes.Buffer
+	if err := writeNetworkGateways(&out, input, probe); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+NETWORK       CLUSTER     GATEWAY            REACHABLE                  ISTIOD
+network-1     primary     10.0.0.1:15443     yes                        istiod-a.istio-system
+network-2     remote      10.0.0.2:15443     no: connection refused     istiod-a.istio-system
+`
+	if got := out.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if probed != 2 {
+		t.Errorf("expected 2 probes, got %d", probed)
+	}
+}
@@ -0,0 +1,434 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	pilotcontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/kube"
+)
+
+func gatewayDescribeCmd() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	cmd := &cobra.Command{
+		Use:     "gateway <gateway>",
+		Aliases: []string{"gw"},
+		Short:   "Describe gateways and their Istio configuration [kube-only]",
+		Long: `Analyzes a Gateway, the gateway pods and Services it selects, and the VirtualServices bound to it.
+For each server it reports the hosts, the listener of the gateway proxy, the VirtualServices attached
+to its hosts and port, and whether its TLS credential can be resolved.`,
+		Example: `  istioctl experimental describe gateway bookinfo-gateway.bookinfo`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("expecting gateway name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gwName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+
+			configClient, err := configStoreFactory()
+			if err != nil {
+				return err
+			}
+			gw, err := configClient.NetworkingV1alpha3().Gateways(ns).Get(context.TODO(), gwName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			client, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return err
+			}
+
+			writer := cmd.OutOrStdout()
+			fmt.Fprintf(writer, "Gateway: %s\n", kname(gw.ObjectMeta))
+			fmt.Fprintf(writer, "Selector: %s\n", k8s_labels.Set(gw.Spec.Selector))
+
+			pods, svcs, err := findGatewayWorkloads(client, gw)
+			if err != nil {
+				return err
+			}
+			printGatewayWorkloads(writer, gw, pods, svcs)
+
+			// Listeners are read from the config dump of the first running gateway pod
+			var listeners map[uint32][]string
+			var listenerPod *v1.Pod
+			for i := range pods {
+				if pods[i].Status.Phase == v1.PodRunning {
+					listenerPod = &pods[i]
+					break
+				}
+			}
+			if listenerPod != nil {
+				kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+				if err != nil {
+					return err
+				}
+				listeners, err = gatewayListeners(kubeClient, listenerPod)
+				if err != nil {
+					fmt.Fprintf(writer, "WARNING: Skipping listener information: %v\n", err)
+				}
+			}
+
+			vsList, err := configClient.NetworkingV1alpha3().VirtualServices(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			credentialNamespace := gw.Namespace
+			if len(pods) > 0 {
+				credentialNamespace = pods[0].Namespace
+			}
+			for _, server := range gw.Spec.Servers {
+				fmt.Fprintf(writer, "--------------------\n")
+				printGatewayServer(writer, gw, server)
+				if listenerPod != nil && listeners != nil {
+					port := gatewayTargetPort(server.Port.Number, listenerPod, svcs)
+					if names := listeners[port]; len(names) > 0 {
+						fmt.Fprintf(writer, "   Listener: %s on %s\n", strings.Join(names, ", "), kname(listenerPod.ObjectMeta))
+					} else {
+						fmt.Fprintf(writer, "   WARNING: No listener on port %d of %s\n", port, kname(listenerPod.ObjectMeta))
+					}
+				}
+				if server.Tls != nil {
+					fmt.Fprintf(writer, "   TLS: %s\n", gatewayCredentialStatus(client, credentialNamespace, server.Tls))
+				}
+				bound := gatewayVirtualServices(gw, server, vsList.Items)
+				if len(bound) == 0 {
+					fmt.Fprintf(writer, "   VirtualServices: none\n")
+					continue
+				}
+				fmt.Fprintf(writer, "   VirtualServices:\n")
+				for _, line := range bound {
+					fmt.Fprintf(writer, "      %s\n", line)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Long += "\n\n" + ExperimentalMsg
+	opts.AttachControlPlaneFlags(cmd)
+	return cmd
+}
+
+// findGatewayWorkloads returns the pods selected by a Gateway, and the Services exposing them.
+func findGatewayWorkloads(client kubernetes.Interface, gw *clientnetworking.Gateway) ([]v1.Pod, []v1.Service, error) {
+	if len(gw.Spec.Selector) == 0 {
+		return nil, nil, nil
+	}
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: k8s_labels.SelectorFromSet(gw.Spec.Selector).String(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var svcs []v1.Service
+	seen := map[string]bool{}
+	for _, pod := range pods.Items {
+		if seen[pod.Namespace] {
+			continue
+		}
+		seen[pod.Namespace] = true
+		nsSvcs, err := client.CoreV1().Services(pod.Namespace).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, svc := range nsSvcs.Items {
+			if len(svc.Spec.Selector) > 0 && k8s_labels.SelectorFromSet(svc.Spec.Selector).Matches(k8s_labels.Set(pod.Labels)) {
+				svcs = append(svcs, svc)
+			}
+		}
+	}
+	return pods.Items, svcs, nil
+}
+
+func printGatewayWorkloads(writer io.Writer, gw *clientnetworking.Gateway, pods []v1.Pod, svcs []v1.Service) {
+	if len(pods) == 0 {
+		fmt.Fprintf(writer, "WARNING: No pods match the selector of Gateway %s\n", kname(gw.ObjectMeta))
+		return
+	}
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, kname(pod.ObjectMeta))
+	}
+	fmt.Fprintf(writer, "Gateway pods: %s\n", strings.Join(names, ", "))
+	if len(svcs) == 0 {
+		fmt.Fprintf(writer, "WARNING: No Services select the gateway pods\n")
+	}
+	for _, svc := range svcs {
+		fmt.Fprintf(writer, "Gateway Service: %s (%s)\n", kname(svc.ObjectMeta), svc.Spec.Type)
+		if addrs := serviceAddresses(svc); len(addrs) > 0 {
+			fmt.Fprintf(writer, "   Addresses: %s\n", strings.Join(addrs, ", "))
+		}
+		ports := make([]string, 0, len(svc.Spec.Ports))
+		for _, port := range svc.Spec.Ports {
+			ports = append(ports, fmt.Sprintf("%d -> %s (%s)", port.Port, port.TargetPort.String(), port.Name))
+		}
+		fmt.Fprintf(writer, "   Ports: %s\n", strings.Join(ports, ", "))
+	}
+}
+
+// serviceAddresses returns the external addresses of a Service, followed by its cluster IP.
+func serviceAddresses(svc v1.Service) []string {
+	var addrs []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			addrs = append(addrs, ingress.IP)
+		}
+		if ingress.Hostname != "" {
+			addrs = append(addrs, ingress.Hostname)
+		}
+	}
+	addrs = append(addrs, svc.Spec.ExternalIPs...)
+	if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != v1.ClusterIPNone {
+		addrs = append(addrs, svc.Spec.ClusterIP)
+	}
+	return addrs
+}
+
+// gatewayListeners returns the names of the active listeners of a gateway proxy by port.
+func gatewayListeners(kubeClient kube.ExtendedClient, pod *v1.Pod) (map[uint32][]string, error) {
+	byConfigDump, err := kubeClient.EnvoyDo(context.TODO(), pod.Name, pod.Namespace, "GET", "config_dump")
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command on gateway %s: %v", kname(pod.ObjectMeta), err)
+	}
+	cd := configdump.Wrapper{}
+	if err := cd.UnmarshalJSON(byConfigDump); err != nil {
+		return nil, fmt.Errorf("can't parse config_dump of gateway %s: %v", kname(pod.ObjectMeta), err)
+	}
+	dump, err := cd.GetDynamicListenerDump(true)
+	if err != nil {
+		return nil, err
+	}
+	listeners := map[uint32][]string{}
+	for _, dl := range dump.DynamicListeners {
+		l := &listener.Listener{}
+		if err := dl.ActiveState.Listener.UnmarshalTo(l); err != nil {
+			return nil, err
+		}
+		if sa := l.GetAddress().GetSocketAddress(); sa != nil {
+			listeners[sa.GetPortValue()] = append(listeners[sa.GetPortValue()], l.Name)
+		}
+	}
+	return listeners, nil
+}
+
+// gatewayTargetPort returns the port a gateway proxy listens on for a server port. Servers bind to the
+// target port of the Service port with the same number, or to the server port if no Service exposes it.
+func gatewayTargetPort(port uint32, pod *v1.Pod, svcs []v1.Service) uint32 {
+	for _, svc := range svcs {
+		for i := range svc.Spec.Ports {
+			if uint32(svc.Spec.Ports[i].Port) != port {
+				continue
+			}
+			if target, err := pilotcontroller.FindPort(pod, &svc.Spec.Ports[i]); err == nil {
+				return uint32(target)
+			}
+		}
+	}
+	return port
+}
+
+func printGatewayServer(writer io.Writer, gw *clientnetworking.Gateway, server *v1alpha3.Server) {
+	name := ""
+	if server.Port.Name != "" {
+		name = ", " + server.Port.Name
+	}
+	if server.Name != "" {
+		fmt.Fprintf(writer, "Server %s: port %d (%s%s)\n", server.Name, server.Port.Number, server.Port.Protocol, name)
+	} else {
+		fmt.Fprintf(writer, "Server: port %d (%s%s)\n", server.Port.Number, server.Port.Protocol, name)
+	}
+	fmt.Fprintf(writer, "   Hosts: %s\n", strings.Join(server.Hosts, ", "))
+	if server.Bind != "" {
+		fmt.Fprintf(writer, "   Bind: %s\n", server.Bind)
+	}
+}
+
+// gatewayCredentialStatus describes the TLS settings of a server, and whether the Secret of its credentialName
+// exists in the namespace of the gateway pods with the expected keys.
+func gatewayCredentialStatus(client kubernetes.Interface, namespace string, tls *v1alpha3.ServerTLSSettings) string {
+	mode := tls.Mode.String()
+	if tls.Mode != v1alpha3.ServerTLSSettings_SIMPLE && tls.Mode != v1alpha3.ServerTLSSettings_MUTUAL {
+		return mode
+	}
+	if tls.CredentialName == "" {
+		if tls.ServerCertificate != "" {
+			return fmt.Sprintf("%s with certificate file %s", mode, tls.ServerCertificate)
+		}
+		return fmt.Sprintf("%s, WARNING: no credentialName or certificate file", mode)
+	}
+	status := fmt.Sprintf("%s with credentialName %s", mode, tls.CredentialName)
+	secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), tls.CredentialName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("%s, WARNING: secret %s/%s not found", status, namespace, tls.CredentialName)
+		}
+		return fmt.Sprintf("%s, WARNING: can't get secret %s/%s: %v", status, namespace, tls.CredentialName, err)
+	}
+	if !hasKeys(secret, "tls.crt", "tls.key") && !hasKeys(secret, "cert", "key") {
+		return fmt.Sprintf("%s, WARNING: secret %s/%s has neither tls.crt and tls.key nor cert and key",
+			status, namespace, tls.CredentialName)
+	}
+	if tls.Mode == v1alpha3.ServerTLSSettings_MUTUAL && !hasKeys(secret, "ca.crt") && !hasKeys(secret, "cacert") {
+		caName := tls.CredentialName + "-cacert"
+		if _, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), caName, metav1.GetOptions{}); err != nil {
+			return fmt.Sprintf("%s, WARNING: no CA certificate in secret %s/%s or secret %s/%s",
+				status, namespace, tls.CredentialName, namespace, caName)
+		}
+	}
+	return fmt.Sprintf("%s, secret %s/%s resolved", status, namespace, tls.CredentialName)
+}
+
+func hasKeys(secret *v1.Secret, keys ...string) bool {
+	for _, k := range keys {
+		if len(secret.Data[k]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// gatewayVirtualServices describes the VirtualServices bound to a server of a Gateway, with the hosts they
+// serve on it and the number of routes which apply to the port of the server.
+func gatewayVirtualServices(gw *clientnetworking.Gateway, server *v1alpha3.Server, vss []clientnetworking.VirtualService) []string {
+	var res []string
+	for i := range vss {
+		vs := &vss[i]
+		if !virtualServiceBindsGateway(vs, gw) {
+			continue
+		}
+		var hosts []string
+		for _, h := range vs.Spec.Hosts {
+			for _, sh := range server.Hosts {
+				if namespacedHostMatches(sh, gw.Namespace, vs.Namespace, h) {
+					hosts = append(hosts, h)
+					break
+				}
+			}
+		}
+		if len(hosts) == 0 {
+			continue
+		}
+		routes := virtualServiceRoutesForPort(vs, server.Port.Number)
+		if len(routes) == 0 {
+			continue
+		}
+		res = append(res, fmt.Sprintf("%s for %s: %s", kname(vs.ObjectMeta), strings.Join(hosts, ", "), strings.Join(routes, ", ")))
+	}
+	sort.Strings(res)
+	return res
+}
+
+// virtualServiceBindsGateway returns true if a VirtualService lists a Gateway in its gateways.
+func virtualServiceBindsGateway(vs *clientnetworking.VirtualService, gw *clientnetworking.Gateway) bool {
+	for _, ref := range vs.Spec.Gateways {
+		ns, name := vs.Namespace, ref
+		if i := strings.Index(ref, "/"); i >= 0 {
+			ns, name = ref[:i], ref[i+1:]
+		}
+		if ns == gw.Namespace && name == gw.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// namespacedHostMatches returns true if a host in the namespace/host format of Gateway servers and Sidecar
+// egress listeners selects host h of a config in configNamespace. A "." namespace refers to localNamespace.
+func namespacedHostMatches(namespacedHost, localNamespace, configNamespace, h string) bool {
+	ns, hostname := "*", namespacedHost
+	if i := strings.Index(namespacedHost, "/"); i >= 0 {
+		ns, hostname = namespacedHost[:i], namespacedHost[i+1:]
+	}
+	switch ns {
+	case "*":
+	case "~":
+		return false
+	case ".":
+		if configNamespace != localNamespace {
+			return false
+		}
+	default:
+		if configNamespace != ns {
+			return false
+		}
+	}
+	return host.Name(hostname).Matches(host.Name(h))
+}
+
+// virtualServiceRoutesForPort counts the routes of a VirtualService which apply to a port.
+func virtualServiceRoutesForPort(vs *clientnetworking.VirtualService, port uint32) []string {
+	var res []string
+	http := 0
+	for _, r := range vs.Spec.Http {
+		if len(r.Match) == 0 || anyPortMatches(port, len(r.Match), func(i int) uint32 { return r.Match[i].Port }) {
+			http++
+		}
+	}
+	tcp := 0
+	for _, r := range vs.Spec.Tcp {
+		if len(r.Match) == 0 || anyPortMatches(port, len(r.Match), func(i int) uint32 { return r.Match[i].Port }) {
+			tcp++
+		}
+	}
+	tls := 0
+	for _, r := range vs.Spec.Tls {
+		if len(r.Match) == 0 || anyPortMatches(port, len(r.Match), func(i int) uint32 { return r.Match[i].Port }) {
+			tls++
+		}
+	}
+	if http > 0 {
+		res = append(res, fmt.Sprintf("%d HTTP route(s)", http))
+	}
+	if tcp > 0 {
+		res = append(res, fmt.Sprintf("%d TCP route(s)", tcp))
+	}
+	if tls > 0 {
+		res = append(res, fmt.Sprintf("%d TLS route(s)", tls))
+	}
+	return res
+}
+
+// anyPortMatches returns true if any of n route matches applies to a port. A match without a port applies to
+// all ports.
+func anyPortMatches(port uint32, n int, matchPort func(i int) uint32) bool {
+	for i := 0; i < n; i++ {
+		if p := matchPort(i); p == 0 || p == port {
+			return true
+		}
+	}
+	return false
+}
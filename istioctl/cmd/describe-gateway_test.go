@@ -0,0 +1,172 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+)
+
+const gatewayListenerDump = `{
+  "configs": [
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.ListenersConfigDump",
+      "dynamic_listeners": [
+        {
+          "name": "0.0.0.0_8080",
+          "active_state": {
+            "listener": {
+              "@type": "type.googleapis.com/envoy.config.listener.v3.Listener",
+              "name": "0.0.0.0_8080",
+              "address": {"socket_address": {"address": "0.0.0.0", "port_value": 8080}}
+            }
+          }
+        }
+      ]
+    }
+  ]
+}`
+
+func TestDescribeGateway(t *testing.T) {
+	labels := map[string]string{"istio": "ingressgateway"}
+	k8sConfigs := []runtime.Object{
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingress-1", Namespace: "istio-system", Labels: labels},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		},
+		&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system"},
+			Spec: v1.ServiceSpec{
+				Type:      v1.ServiceTypeLoadBalancer,
+				Selector:  labels,
+				ClusterIP: "10.96.0.10",
+				Ports: []v1.ServicePort{
+					{Name: "http2", Port: 80, TargetPort: intstr.FromInt(8080)},
+					{Name: "https", Port: 443, TargetPort: intstr.FromInt(8443)},
+				},
+			},
+			Status: v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: "34.1.2.3"}}}},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bookinfo-cert", Namespace: "istio-system"},
+			Data:       map[string][]byte{"tls.crt": []byte("crt"), "tls.key": []byte("key")},
+		},
+	}
+	istioConfigs := []runtime.Object{
+		&clientnetworking.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Name: "bookinfo-gateway", Namespace: "bookinfo"},
+			Spec: v1alpha3.Gateway{
+				Selector: labels,
+				Servers: []*v1alpha3.Server{
+					{
+						Port:  &v1alpha3.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+						Hosts: []string{"*"},
+					},
+					{
+						Port:  &v1alpha3.Port{Number: 443, Name: "https", Protocol: "HTTPS"},
+						Hosts: []string{"./bookinfo.example.com"},
+						Tls:   &v1alpha3.ServerTLSSettings{Mode: v1alpha3.ServerTLSSettings_SIMPLE, CredentialName: "bookinfo-cert"},
+					},
+					{
+						Port:  &v1alpha3.Port{Number: 8443, Name: "mtls", Protocol: "HTTPS"},
+						Hosts: []string{"*"},
+						Tls:   &v1alpha3.ServerTLSSettings{Mode: v1alpha3.ServerTLSSettings_MUTUAL, CredentialName: "missing-cert"},
+					},
+				},
+			},
+		},
+		&clientnetworking.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Name: "bookinfo", Namespace: "bookinfo"},
+			Spec: v1alpha3.VirtualService{
+				Hosts:    []string{"bookinfo.example.com"},
+				Gateways: []string{"bookinfo-gateway"},
+				Http: []*v1alpha3.HTTPRoute{
+					{Match: []*v1alpha3.HTTPMatchRequest{{Port: 80}}},
+					{},
+				},
+			},
+		},
+		&clientnetworking.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "other"},
+			Spec: v1alpha3.VirtualService{
+				Hosts:    []string{"other.example.com"},
+				Gateways: []string{"bookinfo/bookinfo-gateway"},
+				Http:     []*v1alpha3.HTTPRoute{{}},
+			},
+		},
+	}
+	cases := []execAndK8sConfigTestCase{
+		{ // case 0 no gateway name
+			args:           strings.Split("x describe gateway", " "),
+			expectedString: "Error: expecting gateway name",
+			wantException:  true,
+		},
+		{ // case 1 unknown gateway
+			args:           strings.Split("x describe gw not-a-gateway", " "),
+			expectedString: "gateways.networking.istio.io \"not-a-gateway\" not found",
+			wantException:  true,
+		},
+		{ // case 2
+			k8sConfigs:       k8sConfigs,
+			istioConfigs:     istioConfigs,
+			execClientConfig: map[string][]byte{"ingress-1": []byte(gatewayListenerDump)},
+			args:             strings.Split("x describe gateway bookinfo-gateway.bookinfo", " "),
+			expectedOutput: `Gateway: bookinfo-gateway.bookinfo
+Selector: istio=ingressgateway
+Gateway pods: ingress-1.istio-system
+Gateway Service: istio-ingressgateway.istio-system (LoadBalancer)
+   Addresses: 34.1.2.3, 10.96.0.10
+   Ports: 80 -> 8080 (http2), 443 -> 8443 (https)
+--------------------
+Server: port 80 (HTTP, http)
+   Hosts: *
+   Listener: 0.0.0.0_8080 on ingress-1.istio-system
+   VirtualServices:
+      bookinfo.bookinfo for bookinfo.example.com: 2 HTTP route(s)
+      other.other for other.example.com: 1 HTTP route(s)
+--------------------
+Server: port 443 (HTTPS, https)
+   Hosts: ./bookinfo.example.com
+   WARNING: No listener on port 8443 of ingress-1.istio-system
+   TLS: SIMPLE with credentialName bookinfo-cert, secret istio-system/bookinfo-cert resolved
+   VirtualServices:
+      bookinfo.bookinfo for bookinfo.example.com: 1 HTTP route(s)
+--------------------
+Server: port 8443 (HTTPS, mtls)
+   Hosts: *
+   WARNING: No listener on port 8443 of ingress-1.istio-system
+   TLS: MUTUAL with credentialName missing-cert, WARNING: secret istio-system/missing-cert not found
+   VirtualServices:
+      bookinfo.bookinfo for bookinfo.example.com: 1 HTTP route(s)
+      other.other for other.example.com: 1 HTTP route(s)
+`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecAndK8sConfigTestCaseTestOutput(t, c)
+		})
+	}
+}
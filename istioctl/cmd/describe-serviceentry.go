@@ -0,0 +1,281 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s_labels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+)
+
+// serviceEntryClusterTypes are the Envoy cluster types generated for each resolution of a ServiceEntry.
+var serviceEntryClusterTypes = map[v1alpha3.ServiceEntry_Resolution]string{
+	v1alpha3.ServiceEntry_NONE:   "ORIGINAL_DST",
+	v1alpha3.ServiceEntry_STATIC: "EDS",
+	v1alpha3.ServiceEntry_DNS:    "STRICT_DNS",
+}
+
+func serviceEntryDescribeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "serviceentry <serviceentry>",
+		Aliases: []string{"se"},
+		Short:   "Describe ServiceEntries and their Istio configuration [kube-only]",
+		Long: `Analyzes a ServiceEntry and reports its resolution, the clusters generated for it, its endpoints
+including the WorkloadEntries and pods it selects, and which Sidecars import it.`,
+		Example: `  istioctl experimental describe serviceentry external-api.default`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("expecting service entry name")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			seName, ns := handlers.InferPodInfo(args[0], handlers.HandleNamespace(namespace, defaultNamespace))
+
+			configClient, err := configStoreFactory()
+			if err != nil {
+				return err
+			}
+			se, err := configClient.NetworkingV1alpha3().ServiceEntries(ns).Get(context.TODO(), seName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			client, err := interfaceFactory(kubeconfig)
+			if err != nil {
+				return err
+			}
+
+			writer := cmd.OutOrStdout()
+			printServiceEntry(writer, se)
+			if err := printServiceEntryClusters(writer, configClient, se); err != nil {
+				return err
+			}
+			if err := printServiceEntryEndpoints(writer, client, configClient, se); err != nil {
+				return err
+			}
+			return printServiceEntrySidecars(writer, configClient, se)
+		},
+	}
+
+	cmd.Long += "\n\n" + ExperimentalMsg
+	return cmd
+}
+
+func printServiceEntry(writer io.Writer, se *clientnetworking.ServiceEntry) {
+	fmt.Fprintf(writer, "ServiceEntry: %s\n", kname(se.ObjectMeta))
+	fmt.Fprintf(writer, "Hosts: %s\n", strings.Join(se.Spec.Hosts, ", "))
+	if len(se.Spec.Addresses) > 0 {
+		fmt.Fprintf(writer, "Addresses: %s\n", strings.Join(se.Spec.Addresses, ", "))
+	}
+	fmt.Fprintf(writer, "Location: %s\n", se.Spec.Location)
+	fmt.Fprintf(writer, "Resolution: %s\n", se.Spec.Resolution)
+	ports := make([]string, 0, len(se.Spec.Ports))
+	for _, port := range se.Spec.Ports {
+		ports = append(ports, fmt.Sprintf("%d/%s (%s)", port.Number, port.Protocol, port.Name))
+	}
+	fmt.Fprintf(writer, "Ports: %s\n", strings.Join(ports, ", "))
+	exportTo := "*"
+	if len(se.Spec.ExportTo) > 0 {
+		exportTo = strings.Join(se.Spec.ExportTo, ", ")
+	}
+	fmt.Fprintf(writer, "Exported to: %s\n", exportTo)
+}
+
+// printServiceEntryClusters prints the clusters generated for each host and port of a ServiceEntry, including
+// the subset clusters of DestinationRules for its hosts.
+func printServiceEntryClusters(writer io.Writer, configClient istioclient.Interface, se *clientnetworking.ServiceEntry) error {
+	drs, err := configClient.NetworkingV1alpha3().DestinationRules(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	clusterType := serviceEntryClusterTypes[se.Spec.Resolution]
+	fmt.Fprintf(writer, "Clusters:\n")
+	for _, h := range se.Spec.Hosts {
+		for _, port := range se.Spec.Ports {
+			fmt.Fprintf(writer, "   %s (%s)\n",
+				model.BuildSubsetKey(model.TrafficDirectionOutbound, "", host.Name(h), int(port.Number)), clusterType)
+			for i := range drs.Items {
+				dr := &drs.Items[i]
+				if !host.Name(h).SubsetOf(host.Name(dr.Spec.Host)) {
+					continue
+				}
+				for _, subset := range dr.Spec.Subsets {
+					fmt.Fprintf(writer, "   %s (%s, DestinationRule %s)\n",
+						model.BuildSubsetKey(model.TrafficDirectionOutbound, subset.Name, host.Name(h), int(port.Number)),
+						clusterType, kname(dr.ObjectMeta))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// printServiceEntryEndpoints prints the endpoints of a ServiceEntry, which are either listed inline or selected
+// from the WorkloadEntries and pods of its namespace.
+func printServiceEntryEndpoints(writer io.Writer, client kubernetes.Interface, configClient istioclient.Interface,
+	se *clientnetworking.ServiceEntry) error {
+	fmt.Fprintf(writer, "Endpoints:\n")
+	if len(se.Spec.Endpoints) > 0 {
+		for _, ep := range se.Spec.Endpoints {
+			fmt.Fprintf(writer, "   %s%s\n", ep.Address, workloadEntryDetails(ep))
+		}
+		return nil
+	}
+	if se.Spec.WorkloadSelector != nil {
+		selector := k8s_labels.SelectorFromSet(se.Spec.WorkloadSelector.Labels)
+		wes, err := configClient.NetworkingV1alpha3().WorkloadEntries(se.Namespace).List(context.TODO(),
+			metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return err
+		}
+		pods, err := client.CoreV1().Pods(se.Namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return err
+		}
+		if len(wes.Items) == 0 && len(pods.Items) == 0 {
+			fmt.Fprintf(writer, "   WARNING: No WorkloadEntries or pods match workloadSelector %s\n", selector)
+			return nil
+		}
+		for i := range wes.Items {
+			we := &wes.Items[i]
+			fmt.Fprintf(writer, "   %s (WorkloadEntry %s)%s\n", we.Spec.Address, kname(we.ObjectMeta), workloadEntryDetails(&we.Spec))
+		}
+		for _, pod := range pods.Items {
+			fmt.Fprintf(writer, "   %s (Pod %s)\n", pod.Status.PodIP, kname(pod.ObjectMeta))
+		}
+		return nil
+	}
+	switch se.Spec.Resolution {
+	case v1alpha3.ServiceEntry_DNS:
+		fmt.Fprintf(writer, "   %s (resolved by DNS)\n", strings.Join(se.Spec.Hosts, ", "))
+	case v1alpha3.ServiceEntry_NONE:
+		fmt.Fprintf(writer, "   none, requests are forwarded to their original destination\n")
+	default:
+		fmt.Fprintf(writer, "   WARNING: No endpoints for resolution %s\n", se.Spec.Resolution)
+	}
+	return nil
+}
+
+func workloadEntryDetails(we *v1alpha3.WorkloadEntry) string {
+	var details []string
+	if len(we.Ports) > 0 {
+		ports := make([]string, 0, len(we.Ports))
+		for name, port := range we.Ports {
+			ports = append(ports, fmt.Sprintf("%s: %d", name, port))
+		}
+		sort.Strings(ports)
+		details = append(details, "ports "+strings.Join(ports, ", "))
+	}
+	if we.Network != "" {
+		details = append(details, "network "+we.Network)
+	}
+	if we.Locality != "" {
+		details = append(details, "locality "+we.Locality)
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(details, "; ") + "]"
+}
+
+// printServiceEntrySidecars reports which Sidecars import a ServiceEntry through their egress hosts.
+func printServiceEntrySidecars(writer io.Writer, configClient istioclient.Interface, se *clientnetworking.ServiceEntry) error {
+	sidecars, err := configClient.NetworkingV1alpha3().Sidecars(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(writer, "Sidecars:\n")
+	var rootSidecar *clientnetworking.Sidecar
+	for i := range sidecars.Items {
+		sc := &sidecars.Items[i]
+		if sc.Namespace == istioNamespace && sc.Spec.WorkloadSelector == nil {
+			rootSidecar = sc
+		}
+		name := kname(sc.ObjectMeta)
+		if sc.Spec.WorkloadSelector != nil {
+			name += fmt.Sprintf(" (workloads %s)", k8s_labels.Set(sc.Spec.WorkloadSelector.Labels))
+		}
+		switch {
+		case !serviceEntryExportedTo(se, sc.Namespace):
+			fmt.Fprintf(writer, "   %s: not exported to namespace %s\n", name, sc.Namespace)
+		case sidecarImports(sc, se, sc.Namespace):
+			fmt.Fprintf(writer, "   %s: imported\n", name)
+		default:
+			fmt.Fprintf(writer, "   %s: not imported by egress hosts\n", name)
+		}
+	}
+	if rootSidecar == nil {
+		fmt.Fprintf(writer, "Namespaces without a Sidecar import it if it is exported to them\n")
+		return nil
+	}
+	// The root namespace Sidecar applies to each namespace without a Sidecar, so "." refers to that namespace
+	fmt.Fprintf(writer, "Namespaces without a Sidecar use %s: %s in namespace %s, %s in other namespaces\n",
+		kname(rootSidecar.ObjectMeta), importedString(sidecarImports(rootSidecar, se, se.Namespace)), se.Namespace,
+		importedString(sidecarImports(rootSidecar, se, "")))
+	return nil
+}
+
+// serviceEntryExportedTo returns true if a ServiceEntry is visible in a namespace.
+func serviceEntryExportedTo(se *clientnetworking.ServiceEntry, ns string) bool {
+	if len(se.Spec.ExportTo) == 0 {
+		return true
+	}
+	for _, e := range se.Spec.ExportTo {
+		if e == "*" || e == ns || (e == "." && se.Namespace == ns) {
+			return true
+		}
+	}
+	return false
+}
+
+// sidecarImports returns true if any egress host of a Sidecar applied in namespace ns selects a host of a
+// ServiceEntry. A Sidecar without egress listeners imports all services.
+func sidecarImports(sc *clientnetworking.Sidecar, se *clientnetworking.ServiceEntry, ns string) bool {
+	if len(sc.Spec.Egress) == 0 {
+		return true
+	}
+	for _, egress := range sc.Spec.Egress {
+		for _, eh := range egress.Hosts {
+			for _, h := range se.Spec.Hosts {
+				if namespacedHostMatches(eh, ns, se.Namespace, h) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func importedString(imported bool) string {
+	if imported {
+		return "imported"
+	}
+	return "not imported"
+}
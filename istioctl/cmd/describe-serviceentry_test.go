@@ -0,0 +1,139 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+)
+
+func TestDescribeServiceEntry(t *testing.T) {
+	labels := map[string]string{"app": "legacy"}
+	istioConfigs := []runtime.Object{
+		&clientnetworking.ServiceEntry{
+			ObjectMeta: metav1.ObjectMeta{Name: "external-api", Namespace: "default"},
+			Spec: v1alpha3.ServiceEntry{
+				Hosts:      []string{"api.example.com"},
+				Location:   v1alpha3.ServiceEntry_MESH_EXTERNAL,
+				Resolution: v1alpha3.ServiceEntry_DNS,
+				Ports:      []*v1alpha3.Port{{Number: 443, Name: "tls", Protocol: "TLS"}},
+				ExportTo:   []string{".", "team-a"},
+			},
+		},
+		&clientnetworking.ServiceEntry{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default"},
+			Spec: v1alpha3.ServiceEntry{
+				Hosts:            []string{"legacy.internal"},
+				Location:         v1alpha3.ServiceEntry_MESH_INTERNAL,
+				Resolution:       v1alpha3.ServiceEntry_STATIC,
+				Ports:            []*v1alpha3.Port{{Number: 8080, Name: "http", Protocol: "HTTP"}},
+				WorkloadSelector: &v1alpha3.WorkloadSelector{Labels: labels},
+			},
+		},
+		&clientnetworking.WorkloadEntry{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm-1", Namespace: "default", Labels: labels},
+			Spec:       v1alpha3.WorkloadEntry{Address: "10.0.0.3", Network: "vm-network"},
+		},
+		&clientnetworking.DestinationRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "external-api", Namespace: "default"},
+			Spec: v1alpha3.DestinationRule{
+				Host:    "api.example.com",
+				Subsets: []*v1alpha3.Subset{{Name: "v1"}},
+			},
+		},
+		&clientnetworking.Sidecar{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "istio-system"},
+			Spec: v1alpha3.Sidecar{
+				Egress: []*v1alpha3.IstioEgressListener{{Hosts: []string{"./*", "istio-system/*"}}},
+			},
+		},
+		&clientnetworking.Sidecar{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "team-a"},
+			Spec: v1alpha3.Sidecar{
+				Egress: []*v1alpha3.IstioEgressListener{{Hosts: []string{"default/api.example.com"}}},
+			},
+		},
+		&clientnetworking.Sidecar{
+			ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "team-b"},
+		},
+	}
+	k8sConfigs := []runtime.Object{
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy-1", Namespace: "default", Labels: labels},
+			Status:     v1.PodStatus{PodIP: "10.1.0.5"},
+		},
+	}
+	cases := []execAndK8sConfigTestCase{
+		{ // case 0 no service entry name
+			args:           strings.Split("x describe serviceentry", " "),
+			expectedString: "Error: expecting service entry name",
+			wantException:  true,
+		},
+		{ // case 1 unknown service entry
+			args:           strings.Split("x describe se not-a-service-entry", " "),
+			expectedString: "serviceentries.networking.istio.io \"not-a-service-entry\" not found",
+			wantException:  true,
+		},
+		{ // case 2 DNS resolution, subsets and exportTo
+			istioConfigs: istioConfigs,
+			args:         strings.Split("x describe serviceentry external-api.default", " "),
+			expectedOutput: `ServiceEntry: external-api
+Hosts: api.example.com
+Location: MESH_EXTERNAL
+Resolution: DNS
+Ports: 443/TLS (tls)
+Exported to: ., team-a
+Clusters:
+   outbound|443||api.example.com (STRICT_DNS)
+   outbound|443|v1|api.example.com (STRICT_DNS, DestinationRule external-api)
+Endpoints:
+   api.example.com (resolved by DNS)
+Sidecars:
+   default.istio-system: not exported to namespace istio-system
+   default.team-a: imported
+   default.team-b: not exported to namespace team-b
+Namespaces without a Sidecar use default.istio-system: imported in namespace default, not imported in other namespaces
+`,
+		},
+		{ // case 3 workload selector
+			k8sConfigs:   k8sConfigs,
+			istioConfigs: istioConfigs,
+			args:         strings.Split("x describe serviceentry legacy.default", " "),
+			expectedString: `Endpoints:
+   10.0.0.3 (WorkloadEntry vm-1) [network vm-network]
+   10.1.0.5 (Pod legacy-1)
+Sidecars:
+   default.istio-system: not imported by egress hosts
+   default.team-a: not imported by egress hosts
+   default.team-b: imported
+Namespaces without a Sidecar use default.istio-system: imported in namespace default, not imported in other namespaces
+`,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecAndK8sConfigTestCaseTestOutput(t, c)
+		})
+	}
+}
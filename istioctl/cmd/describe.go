@@ -176,6 +176,8 @@ func describe() *cobra.Command {
 
 	describeCmd.AddCommand(podDescribeCmd())
 	describeCmd.AddCommand(svcDescribeCmd())
+	describeCmd.AddCommand(gatewayDescribeCmd())
+	describeCmd.AddCommand(serviceEntryDescribeCmd())
 	return describeCmd
 }
 
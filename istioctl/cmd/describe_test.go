@@ -16,21 +16,27 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"strings"
 	"testing"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
 	"istio.io/istio/pilot/test/util"
 )
 
 // execAndK8sConfigTestCase lets a test case hold some Envoy, Istio, and Kubernetes configuration
 type execAndK8sConfigTestCase struct {
-	k8sConfigs []runtime.Object // Canned K8s configuration
-	namespace  string
+	k8sConfigs       []runtime.Object  // Canned K8s configuration
+	istioConfigs     []runtime.Object  // Canned Istio configuration
+	execClientConfig map[string][]byte // Canned Envoy configuration
+	namespace        string
 
 	args []string
 
@@ -81,7 +87,16 @@ func verifyExecAndK8sConfigTestCaseTestOutput(t *testing.T, c execAndK8sConfigTe
 	t.Helper()
 
 	// Override the Istio config factory
-	configStoreFactory = mockClientFactoryGenerator()
+	configStoreFactory = mockClientFactoryGenerator(func(client istioclient.Interface) {
+		for _, cfg := range c.istioConfigs {
+			if err := createIstioConfig(client, cfg); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	// Override the Envoy config factory
+	kubeClientWithRevision = mockClientExecFactoryGenerator(c.execClientConfig)
 
 	// Override the K8s config factory
 	interfaceFactory = mockInterfaceFactoryGenerator(c.k8sConfigs)
@@ -129,3 +144,24 @@ func mockInterfaceFactoryGenerator(k8sConfigs []runtime.Object) func(kubeconfig
 
 	return outFactory
 }
+
+func createIstioConfig(client istioclient.Interface, cfg runtime.Object) error {
+	var err error
+	switch c := cfg.(type) {
+	case *clientnetworking.Gateway:
+		_, err = client.NetworkingV1alpha3().Gateways(c.Namespace).Create(context.TODO(), c, metav1.CreateOptions{})
+	case *clientnetworking.VirtualService:
+		_, err = client.NetworkingV1alpha3().VirtualServices(c.Namespace).Create(context.TODO(), c, metav1.CreateOptions{})
+	case *clientnetworking.DestinationRule:
+		_, err = client.NetworkingV1alpha3().DestinationRules(c.Namespace).Create(context.TODO(), c, metav1.CreateOptions{})
+	case *clientnetworking.ServiceEntry:
+		_, err = client.NetworkingV1alpha3().ServiceEntries(c.Namespace).Create(context.TODO(), c, metav1.CreateOptions{})
+	case *clientnetworking.WorkloadEntry:
+		_, err = client.NetworkingV1alpha3().WorkloadEntries(c.Namespace).Create(context.TODO(), c, metav1.CreateOptions{})
+	case *clientnetworking.Sidecar:
+		_, err = client.NetworkingV1alpha3().Sidecars(c.Namespace).Create(context.TODO(), c, metav1.CreateOptions{})
+	default:
+		err = fmt.Errorf("unsupported config %T", cfg)
+	}
+	return err
+}
@@ -0,0 +1,163 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/tag"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+func injectDiffCommand() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	cmd := &cobra.Command{
+		Use:   "inject-diff [<type>/]<name>[.<namespace>]",
+		Short: "Show the changes sidecar injection would make to a running workload",
+		Long: `
+Runs a running pod through the current injection configuration of its revision, or of the revision
+given with --revision, and prints how the injected pod would differ from the running pod.
+
+Pods that show differences in containers, volumes, annotations or labels must be restarted to pick up
+the current injection configuration, for example after an upgrade or a change to the injection template.
+`,
+		Example: `  # Show what would change for a pod after an upgrade of its revision
+  istioctl x inject-diff productpage-v1-5b9f8d9b8-abcde.bookinfo
+
+  # Compare a pod of a deployment against the injection template of the canary revision
+  istioctl x inject-diff deployment/productpage-v1 -n bookinfo --revision canary`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			podName, ns, err := handlers.InferPodInfoFromTypedResource(args[0],
+				handlers.HandleNamespace(namespace, defaultNamespace),
+				client.UtilFactory())
+			if err != nil {
+				return err
+			}
+			pod, err := client.CoreV1().Pods(ns).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			revision := opts.Revision
+			if revision == "" {
+				revision = podRevision(pod)
+			}
+			injector, err := setUpExternalInjector(kubeconfig, revision)
+			if err != nil {
+				return err
+			}
+			diff, err := injector.Diff(pod)
+			if err != nil {
+				return fmt.Errorf("failed to compute injection diff for %s.%s: %v", podName, ns, err)
+			}
+			printInjectDiff(cmd.OutOrStdout(), pod, revision, diff)
+			return nil
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	return cmd
+}
+
+// podRevision returns the revision that injected a pod, defaulting to the default revision.
+func podRevision(pod *corev1.Pod) string {
+	if rev, f := pod.Labels[label.IoIstioRev.Name]; f {
+		return rev
+	}
+	if status, f := pod.Annotations[annotation.SidecarStatus.Name]; f {
+		var s inject.SidecarInjectionStatus
+		if err := json.Unmarshal([]byte(status), &s); err == nil && s.Revision != "" {
+			return s.Revision
+		}
+	}
+	return tag.DefaultRevisionName
+}
+
+func printInjectDiff(w io.Writer, pod *corev1.Pod, revision string, diff *inject.PodDiff) {
+	if diff.InjectionSkipped {
+		fmt.Fprintf(w, "Pod %s.%s is not injected by revision %q\n", pod.Name, pod.Namespace, revision)
+		return
+	}
+	if diff.Empty() {
+		fmt.Fprintf(w, "Pod %s.%s is up to date with revision %q\n", pod.Name, pod.Namespace, revision)
+		return
+	}
+	fmt.Fprintf(w, "Pod %s.%s differs from the injection output of revision %q, restart required\n",
+		pod.Name, pod.Namespace, revision)
+	printContainerDiffs(w, "Containers", diff.Containers)
+	printContainerDiffs(w, "Init containers", diff.InitContainers)
+	printFieldDiffs(w, "Volumes", "  ", diff.Volumes)
+	printFieldDiffs(w, "Annotations", "  ", diff.Annotations)
+	printFieldDiffs(w, "Labels", "  ", diff.Labels)
+}
+
+func printContainerDiffs(w io.Writer, title string, diffs []inject.ContainerDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, c := range diffs {
+		fmt.Fprintf(w, "  %s %s\n", changeMarker(c.Change), c.Name)
+		printFieldDiffs(w, "", "      ", c.Fields)
+	}
+}
+
+func printFieldDiffs(w io.Writer, title string, indent string, diffs []inject.FieldDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+	if title != "" {
+		fmt.Fprintf(w, "%s:\n", title)
+	}
+	for _, f := range diffs {
+		switch f.Change {
+		case inject.Added:
+			fmt.Fprintf(w, "%s%s %s: %s\n", indent, changeMarker(f.Change), f.Name, f.New)
+		case inject.Removed:
+			fmt.Fprintf(w, "%s%s %s: %s\n", indent, changeMarker(f.Change), f.Name, f.Old)
+		default:
+			if f.Old == "" && f.New == "" {
+				fmt.Fprintf(w, "%s%s %s\n", indent, changeMarker(f.Change), f.Name)
+			} else {
+				fmt.Fprintf(w, "%s%s %s: %s -> %s\n", indent, changeMarker(f.Change), f.Name, f.Old, f.New)
+			}
+		}
+	}
+}
+
+func changeMarker(c inject.ChangeType) string {
+	switch c {
+	case inject.Added:
+		return "+"
+	case inject.Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
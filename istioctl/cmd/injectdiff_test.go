@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/kube/inject"
+)
+
+func TestPrintInjectDiff(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "productpage", Namespace: "bookinfo"}}
+	cases := []struct {
+		name string
+		diff *inject.PodDiff
+		want string
+	}{
+		{
+			name: "up to date",
+			diff: &inject.PodDiff{},
+			want: "Pod productpage.bookinfo is up to date with revision \"canary\"\n",
+		},
+		{
+			name: "skipped",
+			diff: &inject.PodDiff{InjectionSkipped: true},
+			want: "Pod productpage.bookinfo is not injected by revision \"canary\"\n",
+		},
+		{
+			name: "changes",
+			diff: &inject.PodDiff{
+				Containers: []inject.ContainerDiff{{
+					Name:   "istio-proxy",
+					Change: inject.Modified,
+					Fields: []inject.FieldDiff{
+						{Name: "image", Change: inject.Modified, Old: "proxyv2:1.11.0", New: "proxyv2:1.12.0"},
+						{Name: "env FOO", Change: inject.Added, New: "bar"},
+						{Name: "spec", Change: inject.Modified},
+					},
+				}},
+				Volumes: []inject.FieldDiff{{Name: "istio-token", Change: inject.Removed, Old: "{}"}},
+			},
+			want: `Pod productpage.bookinfo differs from the injection output of revision "canary", restart required
+Containers:
+  ~ istio-proxy
+      ~ image: proxyv2:1.11.0 -> proxyv2:1.12.0
+      + env FOO: bar
+      ~ spec
+Volumes:
+  - istio-token: {}
+`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printInjectDiff(&out, pod, "canary", tt.diff)
+			if out.String() != tt.want {
+				t.Fatalf("got:\n%v\nwant:\n%v", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestToDiffPath(t *testing.T) {
+	cases := map[string]string{
+		"/inject":                        "/inject/diff",
+		"/inject/cluster/c1/net/n1":      "/inject/diff/cluster/c1/net/n1",
+		"/inject/:ENV:cluster=c1:ENV:x=": "/inject/diff/:ENV:cluster=c1:ENV:x=",
+	}
+	for in, want := range cases {
+		if got := toDiffPath(in); got != want {
+			t.Errorf("toDiffPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strings"
@@ -67,13 +68,92 @@ type ExternalInjector struct {
 }
 
 func (e ExternalInjector) Inject(pod *corev1.Pod) ([]byte, error) {
-	cc := e.clientConfig
-	if cc == nil {
+	if e.clientConfig == nil {
 		return nil, nil
 	}
+	podBytes, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+	rev := &admission.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admission.SchemeGroupVersion.String(),
+			Kind:       "AdmissionReview",
+		},
+		Request: &admission.AdmissionRequest{
+			Object: runtime.RawExtension{Raw: podBytes},
+			Kind: metav1.GroupVersionKind{
+				Group:   admission.GroupName,
+				Version: admission.SchemeGroupVersion.Version,
+				Kind:    "AdmissionRequest",
+			},
+			Resource:           metav1.GroupVersionResource{},
+			SubResource:        "",
+			RequestKind:        nil,
+			RequestResource:    nil,
+			RequestSubResource: "",
+			Name:               pod.Name,
+			Namespace:          pod.Namespace,
+		},
+		Response: nil,
+	}
+	revBytes, err := json.Marshal(rev)
+	if err != nil {
+		return nil, err
+	}
+	body, err := e.post(revBytes, false)
+	if err != nil {
+		return nil, err
+	}
+	var obj runtime.Object
+	var ar *kube.AdmissionReview
+	out, _, err := deserializer.Decode(body, nil, obj)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode body: %v", err)
+	}
+	ar, err = kube.AdmissionReviewKubeToAdapter(out)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode object: %v", err)
+	}
+
+	return ar.Response.Patch, nil
+}
+
+// Diff asks the injection webhook which changes injection would make to an existing pod.
+func (e ExternalInjector) Diff(pod *corev1.Pod) (*inject.PodDiff, error) {
+	if e.clientConfig == nil {
+		return nil, fmt.Errorf("no injection webhook found")
+	}
+	podBytes, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+	body, err := e.post(podBytes, true)
+	if err != nil {
+		return nil, err
+	}
+	diff := &inject.PodDiff{}
+	if err := json.Unmarshal(body, diff); err != nil {
+		return nil, fmt.Errorf("could not decode diff: %v: %s", err, string(body))
+	}
+	return diff, nil
+}
+
+// post sends the request body to the injection webhook. If diff is set, the request is sent to the diff
+// endpoint rather than the injection endpoint, keeping any parameters passed in the path.
+func (e ExternalInjector) post(reqBody []byte, diff bool) ([]byte, error) {
+	cc := e.clientConfig
 	var address string
 	if cc.URL != nil {
 		address = *cc.URL
+		if diff {
+			u, err := url.Parse(address)
+			if err != nil {
+				return nil, err
+			}
+			u.Path = toDiffPath(u.Path)
+			address = u.String()
+		}
 	}
 	var certPool *x509.CertPool
 	if len(cc.CABundle) > 0 {
@@ -115,7 +195,11 @@ func (e ExternalInjector) Inject(pod *corev1.Pod) ([]byte, error) {
 		if err := f.Start(); err != nil {
 			return nil, err
 		}
-		address = fmt.Sprintf("https://%s%s", f.Address(), *cc.Service.Path)
+		path := *cc.Service.Path
+		if diff {
+			path = toDiffPath(path)
+		}
+		address = fmt.Sprintf("https://%s%s", f.Address(), path)
 		tlsClientConfig.ServerName = fmt.Sprintf("%s.%s.%s", cc.Service.Name, cc.Service.Namespace, "svc")
 		defer func() {
 			f.Close()
@@ -128,37 +212,7 @@ func (e ExternalInjector) Inject(pod *corev1.Pod) ([]byte, error) {
 			TLSClientConfig: tlsClientConfig,
 		},
 	}
-	podBytes, err := json.Marshal(pod)
-	if err != nil {
-		return nil, err
-	}
-	rev := &admission.AdmissionReview{
-		TypeMeta: metav1.TypeMeta{
-			APIVersion: admission.SchemeGroupVersion.String(),
-			Kind:       "AdmissionReview",
-		},
-		Request: &admission.AdmissionRequest{
-			Object: runtime.RawExtension{Raw: podBytes},
-			Kind: metav1.GroupVersionKind{
-				Group:   admission.GroupName,
-				Version: admission.SchemeGroupVersion.Version,
-				Kind:    "AdmissionRequest",
-			},
-			Resource:           metav1.GroupVersionResource{},
-			SubResource:        "",
-			RequestKind:        nil,
-			RequestResource:    nil,
-			RequestSubResource: "",
-			Name:               pod.Name,
-			Namespace:          pod.Namespace,
-		},
-		Response: nil,
-	}
-	revBytes, err := json.Marshal(rev)
-	if err != nil {
-		return nil, err
-	}
-	resp, err := client.Post(address, "application/json", bytes.NewBuffer(revBytes))
+	resp, err := client.Post(address, "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
 		return nil, err
 	}
@@ -167,18 +221,15 @@ func (e ExternalInjector) Inject(pod *corev1.Pod) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	var obj runtime.Object
-	var ar *kube.AdmissionReview
-	out, _, err := deserializer.Decode(body, nil, obj)
-	if err != nil {
-		return nil, fmt.Errorf("could not decode body: %v", err)
-	}
-	ar, err = kube.AdmissionReviewKubeToAdapter(out)
-	if err != nil {
-		return nil, fmt.Errorf("could not decode object: %v", err)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("injection webhook returned %v: %s", resp.Status, strings.TrimSpace(string(body)))
 	}
+	return body, nil
+}
 
-	return ar.Response.Patch, nil
+// toDiffPath converts an injection path, such as /inject/cluster/c1, to the matching diff path.
+func toDiffPath(path string) string {
+	return strings.Replace(path, "/inject", "/inject"+inject.DiffPathSuffix, 1)
 }
 
 var (
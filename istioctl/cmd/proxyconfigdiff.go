@@ -0,0 +1,151 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/istioctl/pkg/writer/compare"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	fileSourcePrefix   = "file:"
+	istiodSourcePrefix = "istiod:"
+)
+
+// ConfigDumpsDifferError indicates that the compared config dumps differ, so scripts can check the exit code.
+type ConfigDumpsDifferError struct{}
+
+func (ConfigDumpsDifferError) Error() string {
+	return "config dumps differ"
+}
+
+func xdsProxyConfig() *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:     "proxy-config",
+		Short:   "Experimental commands to inspect proxy configuration",
+		Long:    `A group of experimental commands used to inspect and compare proxy configuration`,
+		Aliases: []string{"pc"},
+	}
+	configCmd.AddCommand(proxyConfigDiffCmd())
+	return configCmd
+}
+
+func proxyConfigDiffCmd() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	cmd := &cobra.Command{
+		Use:   "diff <source> <source>",
+		Short: "Show the semantic differences between two proxy config dumps",
+		Long: `
+Compares two config dumps and prints the differences of each xDS type: clusters, listeners, routes,
+endpoints, secrets and extension configs (ECDS). Resources are matched by name, and fields which change
+with every push, such as version_info and last_updated, are ignored. Secrets are compared by SDS name only,
+so no key material is printed.
+
+The command exits with status 80 if the config dumps differ, and 0 if they match.
+
+Each source is one of:
+  file:<path>                        a config dump JSON file, or - for stdin
+  istiod:<pod-name>[.<namespace>]    the config Istiod generates for a proxy, from /debug/config_dump
+  [<type>/]<name>[.<namespace>]      the config dump of a running proxy, including endpoints
+`,
+		Example: `  # Compare the configuration of two pods
+  istioctl x proxy-config diff productpage-v1-5b9f8d9b8-abcde.bookinfo productpage-v1-5b9f8d9b8-fghij.bookinfo
+
+  # Compare the configuration of a proxy with the configuration Istiod generates for it
+  istioctl x pc diff istiod:productpage-v1-5b9f8d9b8-abcde.bookinfo productpage-v1-5b9f8d9b8-abcde.bookinfo
+
+  # Compare a saved config dump with the current configuration of a deployment
+  istioctl x pc diff file:before.json deployment/productpage-v1 -n bookinfo`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(c *cobra.Command, args []string) error {
+			var client kube.ExtendedClient
+			dumps := make([][]byte, 0, len(args))
+			for _, source := range args {
+				if !strings.HasPrefix(source, fileSourcePrefix) && client == nil {
+					var err error
+					client, err = kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+					if err != nil {
+						return fmt.Errorf("failed to create k8s client: %v", err)
+					}
+				}
+				dump, err := fetchConfigDump(client, source)
+				if err != nil {
+					return err
+				}
+				dumps = append(dumps, dump)
+			}
+			comparator, err := compare.NewDumpComparator(c.OutOrStdout(), args[0], dumps[0], args[1], dumps[1])
+			if err != nil {
+				return err
+			}
+			differ, err := comparator.Diff()
+			if err != nil {
+				return err
+			}
+			if differ {
+				return ConfigDumpsDifferError{}
+			}
+			return nil
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	return cmd
+}
+
+// fetchConfigDump returns the config dump of a proxy diff source.
+func fetchConfigDump(client kube.ExtendedClient, source string) ([]byte, error) {
+	if strings.HasPrefix(source, fileSourcePrefix) {
+		return readFile(strings.TrimPrefix(source, fileSourcePrefix))
+	}
+	if strings.HasPrefix(source, istiodSourcePrefix) {
+		podName, ns := handlers.InferPodInfo(strings.TrimPrefix(source, istiodSourcePrefix),
+			handlers.HandleNamespace(namespace, defaultNamespace))
+		path := fmt.Sprintf("/debug/config_dump?proxyID=%s.%s", podName, ns)
+		istiodDumps, err := client.AllDiscoveryDo(context.TODO(), istioNamespace, path)
+		if err != nil {
+			return nil, err
+		}
+		// Only the Istiod instance the proxy is connected to returns a config dump
+		for _, resp := range istiodDumps {
+			dump := struct {
+				Configs []json.RawMessage `json:"configs"`
+			}{}
+			if err := json.Unmarshal(resp, &dump); err == nil && len(dump.Configs) > 0 {
+				return resp, nil
+			}
+		}
+		return nil, fmt.Errorf("unable to find config dump of %s.%s in Istiod responses", podName, ns)
+	}
+	podName, ns, err := handlers.InferPodInfoFromTypedResource(source,
+		handlers.HandleNamespace(namespace, defaultNamespace),
+		client.UtilFactory())
+	if err != nil {
+		return nil, err
+	}
+	dump, err := client.EnvoyDo(context.TODO(), podName, ns, "GET", "config_dump?include_eds")
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command on %s.%s sidecar: %v", podName, ns, err)
+	}
+	return dump, nil
+}
@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProxyConfigDiff(t *testing.T) {
+	cases := []execTestCase{
+		{ // case 0: version_info and last_updated are ignored
+			args: strings.Split("x proxy-config diff file:testdata/configdump-diff/before.json "+
+				"file:testdata/configdump-diff/after.json", " "),
+			expectedOutput: `--- file:testdata/configdump-diff/before.json
++++ file:testdata/configdump-diff/after.json
+Clusters match
+Endpoints:
+  ~ outbound|9080||reviews.default.svc.cluster.local
+      endpoints[0].lb_endpoints[0].endpoint.address.socket_address.address: "10.0.0.1" -> "10.0.0.2"
+Error: config dumps differ
+`,
+			wantException: true,
+		},
+		{ // case 1: short name "pc"
+			args: strings.Split("x pc diff file:testdata/configdump-diff/before.json "+
+				"file:testdata/configdump-diff/before.json", " "),
+			expectedOutput: `--- file:testdata/configdump-diff/before.json
++++ file:testdata/configdump-diff/before.json
+Clusters match
+Endpoints match
+`,
+		},
+		{ // case 2: two sources are required
+			args:          strings.Split("x proxy-config diff file:testdata/configdump-diff/before.json", " "),
+			wantException: true,
+		},
+		{ // case 3: missing file
+			args:          strings.Split("x proxy-config diff file:testdata/configdump-diff/before.json file:missing.json", " "),
+			wantException: true,
+		},
+	}
+
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("case %d %s", i, strings.Join(c.args, " ")), func(t *testing.T) {
+			verifyExecTestOutput(t, c)
+		})
+	}
+}
+
+func TestProxyConfigDiffExitCode(t *testing.T) {
+	cases := []struct {
+		after    string
+		wantCode int
+	}{
+		{after: "before.json", wantCode: 0},
+		{after: "after.json", wantCode: ExitConfigDumpsDiffer},
+	}
+	for _, c := range cases {
+		t.Run(c.after, func(t *testing.T) {
+			var out bytes.Buffer
+			rootCmd := GetRootCmd([]string{
+				"x", "proxy-config", "diff",
+				"file:testdata/configdump-diff/before.json", "file:testdata/configdump-diff/" + c.after,
+			})
+			rootCmd.SetOut(&out)
+			rootCmd.SetErr(&out)
+			code := 0
+			if err := rootCmd.Execute(); err != nil {
+				code = GetExitCode(err)
+			}
+			if code != c.wantCode {
+				t.Fatalf("got exit code %v, want %v; output:\n%s", code, c.wantCode, out.String())
+			}
+		})
+	}
+}
@@ -218,6 +218,9 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(AuthZ())
 	rootCmd.AddCommand(seeExperimentalCmd("authz"))
 	experimentalCmd.AddCommand(uninjectCommand())
+	experimentalCmd.AddCommand(injectDiffCommand())
+	experimentalCmd.AddCommand(upgradeCanaryCommand())
+	experimentalCmd.AddCommand(xdsProxyConfig())
 	experimentalCmd.AddCommand(metricsCmd)
 	experimentalCmd.AddCommand(describe())
 	experimentalCmd.AddCommand(addToMeshCmd())
@@ -34,6 +34,7 @@ const (
 
 	// below here are non-zero exit codes that don't indicate an error with istioctl itself
 	ExitAnalyzerFoundIssues = 79 // istioctl analyze found issues, for CI/CD
+	ExitConfigDumpsDiffer   = 80 // istioctl x proxy-config diff found differences
 )
 
 func GetExitCode(e error) int {
@@ -48,6 +49,8 @@ func GetExitCode(e error) int {
 		return ExitDataError
 	case AnalyzerFoundIssuesError:
 		return ExitAnalyzerFoundIssues
+	case ConfigDumpsDifferError:
+		return ExitConfigDumpsDiffer
 	default:
 		return ExitUnknownError
 	}
@@ -25,6 +25,7 @@ var KnownErrorCode = map[error]int{
 	CommandParseError{e: errors.New("command parse error")}: ExitIncorrectUsage,
 	FileParseError{}:                                        ExitDataError,
 	AnalyzerFoundIssuesError{}:                              ExitAnalyzerFoundIssues,
+	ConfigDumpsDifferError{}:                                ExitConfigDumpsDiffer,
 }
 
 func TestKnownExitStrings(t *testing.T) {
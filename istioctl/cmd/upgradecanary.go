@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/canary"
+	"istio.io/istio/istioctl/pkg/tag"
+	"istio.io/istio/operator/cmd/mesh"
+	"istio.io/istio/operator/pkg/manifest"
+	"istio.io/istio/operator/pkg/util/clog"
+)
+
+type upgradeCanaryArgs struct {
+	from          string
+	to            string
+	inFilenames   []string
+	set           []string
+	manifestsPath string
+	skipInstall   bool
+	batchSize     int
+	batchTimeout  time.Duration
+	readyTimeout  time.Duration
+}
+
+func upgradeCanaryCommand() *cobra.Command {
+	args := &upgradeCanaryArgs{}
+	cmd := &cobra.Command{
+		Use:   "upgrade-canary",
+		Short: "Migrate workloads to a new control plane revision in batches of namespaces",
+		Long: `
+Installs a new control plane revision and migrates the workloads of an existing revision to it.
+
+Namespaces labeled with the old revision are relabeled with the new revision, and revision tags referencing
+the old revision, including the default tag, are moved to the new revision. Namespaces are migrated in batches:
+the deployments of each batch are restarted, and the migration only continues once they are rolled out, injected
+by the new revision, and their proxies are in sync with the control plane. If a batch does not become healthy
+within the batch timeout, all namespaces and revision tags are moved back to the old revision and restarted.
+
+The old revision is not removed; once the migration is verified, use 'istioctl x uninstall --revision' to remove it.
+`,
+		Example: `  # Install revision 1-12-0 and migrate all workloads of the default revision, two namespaces at a time
+  istioctl x upgrade-canary --revision 1-12-0 --batch-size 2
+
+  # Migrate workloads from revision 1-11-0 to the already installed revision 1-12-0
+  istioctl x upgrade-canary --from 1-11-0 --revision 1-12-0 --skip-install`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if args.to == "" {
+				return fmt.Errorf("--revision must be set")
+			}
+			if !args.skipInstall {
+				if err := installCanary(cmd, args); err != nil {
+					return err
+				}
+			}
+			client, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			u := canary.NewUpgrader(client, canary.Options{
+				From:           args.from,
+				To:             args.to,
+				IstioNamespace: istioNamespace,
+				ManifestsPath:  args.manifestsPath,
+				BatchSize:      args.batchSize,
+				BatchTimeout:   args.batchTimeout,
+			}, cmd.OutOrStdout())
+			return u.Run(context.Background())
+		},
+	}
+	cmd.Flags().StringVar(&args.from, "from", tag.DefaultRevisionName, "Control plane revision to migrate workloads from.")
+	cmd.Flags().StringVarP(&args.to, "revision", "r", "", "Control plane revision to install and migrate workloads to.")
+	cmd.Flags().StringSliceVarP(&args.inFilenames, "filename", "f", nil,
+		"Path to file containing IstioOperator custom resource for the new revision.")
+	cmd.Flags().StringArrayVarP(&args.set, "set", "s", nil, "Override an IstioOperator value for the new revision.")
+	cmd.Flags().StringVarP(&args.manifestsPath, "manifests", "d", "", mesh.ManifestsFlagHelpStr)
+	cmd.Flags().BoolVar(&args.skipInstall, "skip-install", false, "Do not install the new revision, it must exist already.")
+	cmd.Flags().IntVar(&args.batchSize, "batch-size", 1, "Number of namespaces to migrate at once.")
+	cmd.Flags().DurationVar(&args.batchTimeout, "batch-timeout", 5*time.Minute,
+		"Maximum time for the workloads of a batch to become healthy before rolling back.")
+	cmd.Flags().DurationVar(&args.readyTimeout, "readiness-timeout", 300*time.Second,
+		"Maximum time to wait for the resources of the new revision to be ready.")
+	return cmd
+}
+
+// installCanary installs the new control plane revision, like 'istioctl install --revision'.
+func installCanary(cmd *cobra.Command, args *upgradeCanaryArgs) error {
+	l := clog.NewConsoleLogger(cmd.OutOrStdout(), cmd.ErrOrStderr(), nil)
+	restConfig, _, client, err := mesh.K8sConfig(kubeconfig, configContext)
+	if err != nil {
+		return fmt.Errorf("fetch Kubernetes config file: %v", err)
+	}
+	setFlags := append([]string{"revision=" + args.to}, args.set...)
+	if args.manifestsPath != "" {
+		setFlags = append(setFlags, "installPackagePath="+args.manifestsPath)
+	}
+	_, iop, err := manifest.GenerateConfig(args.inFilenames, setFlags, false, restConfig, l)
+	if err != nil {
+		return fmt.Errorf("generate config: %v", err)
+	}
+	if _, err := mesh.InstallManifests(iop, false, false, restConfig, client, args.readyTimeout, l); err != nil {
+		return fmt.Errorf("failed to install revision %q: %v", args.to, err)
+	}
+	return nil
+}
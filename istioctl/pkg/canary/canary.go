@@ -0,0 +1,527 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package canary migrates the workloads of a control plane revision to another revision, in batches of namespaces,
+// checking the health of the migrated workloads after each batch and rolling back on failure.
+package canary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/tag"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+)
+
+const (
+	// istioInjectionLabel enables injection by the default revision.
+	istioInjectionLabel = "istio-injection"
+	// restartedAtAnnotation is the annotation set by `kubectl rollout restart`.
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+// Options configures an Upgrader.
+type Options struct {
+	// From is the revision to migrate workloads from.
+	From string
+	// To is the revision to migrate workloads to. It must be installed already.
+	To string
+	// IstioNamespace is the namespace of the control plane.
+	IstioNamespace string
+	// ManifestsPath is used to render revision tag webhooks.
+	ManifestsPath string
+	// BatchSize is the number of namespaces migrated at once.
+	BatchSize int
+	// BatchTimeout is the maximum time for the workloads of a batch to become healthy.
+	BatchTimeout time.Duration
+	// PollInterval is the interval between health checks.
+	PollInterval time.Duration
+}
+
+// Upgrader migrates workloads from one revision to another.
+//
+// Namespaces labeled with the old revision are relabeled with the new revision, batch by batch. When migrating from
+// the default revision without a default tag, namespaces labeled with istio-injection=enabled are relabeled the same
+// way. Revision tags referencing the old revision, including the default tag, are then moved to the new revision, and
+// the namespaces using them are restarted, batch by batch. After each batch, the Deployments, StatefulSets and
+// DaemonSets of the batch must be rolled out, their pods injected by the new revision, and have their configuration
+// in sync with the control plane. Other pods, such as those of Jobs, are not restarted and not checked. If any batch
+// fails, all namespaces and tags are moved back to the old revision and restarted.
+type Upgrader struct {
+	client kube.ExtendedClient
+	opts   Options
+	out    io.Writer
+	// discoveryDo queries the debug endpoints of the control plane, overridden in tests
+	discoveryDo func(ctx context.Context, namespace, path string) (map[string][]byte, error)
+
+	// migrated records the namespaces relabeled so far, and movedTags the tags moved so far, for rollbacks.
+	migrated  []string
+	movedTags []string
+	// injectionLabeled records the migrated namespaces that used the istio-injection label.
+	injectionLabeled map[string]bool
+	// restarted records all namespaces restarted so far, which must be restarted again after a rollback.
+	restarted []string
+}
+
+// NewUpgrader creates an Upgrader.
+func NewUpgrader(client kube.ExtendedClient, opts Options, out io.Writer) *Upgrader {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	return &Upgrader{
+		client:           client,
+		opts:             opts,
+		out:              out,
+		discoveryDo:      client.AllDiscoveryDo,
+		injectionLabeled: map[string]bool{},
+	}
+}
+
+// Run performs the migration. On failure, the migration is rolled back and the cause is returned.
+func (u *Upgrader) Run(ctx context.Context) error {
+	if u.opts.From == u.opts.To {
+		return fmt.Errorf("cannot upgrade revision %q to itself", u.opts.To)
+	}
+	whs, err := tag.GetWebhooksWithRevision(ctx, u.client, u.opts.To)
+	if err != nil {
+		return err
+	}
+	if len(whs) == 0 {
+		return fmt.Errorf("revision %q is not installed", u.opts.To)
+	}
+
+	namespaces, err := u.namespacesWithLabel(ctx, fmt.Sprintf("%s=%s", label.IoIstioRev.Name, u.opts.From))
+	if err != nil {
+		return err
+	}
+	tags, tagNamespaces, err := u.tagsOfRevision(ctx)
+	if err != nil {
+		return err
+	}
+	if u.opts.From == tag.DefaultRevisionName && !contains(tags, tag.DefaultRevisionName) {
+		// Without a default tag, istio-injection=enabled is served by the webhook of the default revision itself
+		injectionNamespaces, err := u.namespacesWithLabel(ctx, istioInjectionLabel+"=enabled")
+		if err != nil {
+			return err
+		}
+		for _, ns := range injectionNamespaces {
+			u.injectionLabeled[ns] = true
+		}
+		namespaces = append(namespaces, injectionNamespaces...)
+		sort.Strings(namespaces)
+	}
+	if len(namespaces) == 0 && len(tags) == 0 {
+		return fmt.Errorf("no namespaces or revision tags use revision %q", u.opts.From)
+	}
+	fmt.Fprintf(u.out, "Migrating %d namespaces and %d revision tags from revision %q to %q\n",
+		len(namespaces)+len(tagNamespaces), len(tags), u.opts.From, u.opts.To)
+
+	for _, batch := range batches(namespaces, u.opts.BatchSize) {
+		for _, ns := range batch {
+			u.migrated = append(u.migrated, ns)
+			if err := u.setNamespaceRevision(ctx, ns, u.opts.To, u.injectionLabeled[ns]); err != nil {
+				return u.rollback(ctx, err)
+			}
+		}
+		if err := u.migrateBatch(ctx, batch); err != nil {
+			return u.rollback(ctx, err)
+		}
+	}
+
+	for _, t := range tags {
+		fmt.Fprintf(u.out, "Moving revision tag %q to revision %q\n", t, u.opts.To)
+		u.movedTags = append(u.movedTags, t)
+		if err := u.setTag(ctx, t, u.opts.To); err != nil {
+			return u.rollback(ctx, err)
+		}
+	}
+	for _, batch := range batches(tagNamespaces, u.opts.BatchSize) {
+		if err := u.migrateBatch(ctx, batch); err != nil {
+			return u.rollback(ctx, err)
+		}
+	}
+	fmt.Fprintf(u.out, "Migration to revision %q complete\n", u.opts.To)
+	return nil
+}
+
+// migrateBatch restarts the workloads of a batch of namespaces and waits for them to become healthy.
+func (u *Upgrader) migrateBatch(ctx context.Context, batch []string) error {
+	fmt.Fprintf(u.out, "Restarting namespaces %v\n", batch)
+	for _, ns := range batch {
+		u.restarted = append(u.restarted, ns)
+		if err := u.restartNamespace(ctx, ns); err != nil {
+			return err
+		}
+	}
+	var lastErr error
+	timeout := time.After(u.opts.BatchTimeout)
+	for {
+		lastErr = nil
+		for _, ns := range batch {
+			if err := u.checkNamespace(ctx, ns); err != nil {
+				lastErr = err
+				break
+			}
+		}
+		if lastErr == nil {
+			fmt.Fprintf(u.out, "Namespaces %v are healthy on revision %q\n", batch, u.opts.To)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			return fmt.Errorf("namespaces %v did not become healthy within %v: %v", batch, u.opts.BatchTimeout, lastErr)
+		case <-time.After(u.opts.PollInterval):
+		}
+	}
+}
+
+// rollback moves all migrated namespaces and tags back to the old revision, and restarts the affected namespaces.
+func (u *Upgrader) rollback(ctx context.Context, cause error) error {
+	fmt.Fprintf(u.out, "Migration failed: %v\nRolling back to revision %q\n", cause, u.opts.From)
+	var errs []error
+	for _, t := range u.movedTags {
+		if err := u.setTag(ctx, t, u.opts.From); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, ns := range u.migrated {
+		var err error
+		if u.injectionLabeled[ns] {
+			err = u.patchNamespace(ctx, ns, fmt.Sprintf(`{"metadata":{"labels":{%q:null,%q:"enabled"}}}`,
+				label.IoIstioRev.Name, istioInjectionLabel))
+		} else {
+			err = u.setNamespaceRevision(ctx, ns, u.opts.From, false)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, ns := range u.restarted {
+		if err := u.restartNamespace(ctx, ns); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%v; rollback failed: %v", cause, errs)
+	}
+	fmt.Fprintf(u.out, "Rolled back %d namespaces and %d revision tags to revision %q\n",
+		len(u.restarted), len(u.movedTags), u.opts.From)
+	return cause
+}
+
+// tagsOfRevision returns the revision tags referencing the old revision, and the namespaces using them.
+func (u *Upgrader) tagsOfRevision(ctx context.Context) ([]string, []string, error) {
+	whs, err := tag.GetTagWebhooks(ctx, u.client)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tags, namespaces []string
+	for _, wh := range whs {
+		rev, err := tag.GetWebhookRevision(wh)
+		if err != nil || rev != u.opts.From {
+			continue
+		}
+		t, err := tag.GetWebhookTagName(wh)
+		if err != nil {
+			continue
+		}
+		tags = append(tags, t)
+		ns, err := tag.GetNamespacesWithTag(ctx, u.client, t)
+		if err != nil {
+			return nil, nil, err
+		}
+		namespaces = append(namespaces, ns...)
+		if t == tag.DefaultRevisionName {
+			ns, err := u.namespacesWithLabel(ctx, istioInjectionLabel+"=enabled")
+			if err != nil {
+				return nil, nil, err
+			}
+			namespaces = append(namespaces, ns...)
+		}
+	}
+	sort.Strings(tags)
+	sort.Strings(namespaces)
+	return tags, namespaces, nil
+}
+
+func (u *Upgrader) setTag(ctx context.Context, t, revision string) error {
+	manifests, err := tag.Generate(ctx, u.client, &tag.GenerateOptions{
+		Tag:           t,
+		Revision:      revision,
+		ManifestsPath: u.opts.ManifestsPath,
+		Overwrite:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate revision tag %q: %v", t, err)
+	}
+	return tag.Create(u.client, manifests)
+}
+
+func (u *Upgrader) namespacesWithLabel(ctx context.Context, selector string) ([]string, error) {
+	nsList, err := u.client.Kube().CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]string, 0, len(nsList.Items))
+	for _, ns := range nsList.Items {
+		res = append(res, ns.Name)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// setNamespaceRevision labels a namespace with a revision. If removeInjectionLabel is set, the istio-injection label,
+// which takes precedence over the revision label, is removed.
+func (u *Upgrader) setNamespaceRevision(ctx context.Context, ns, revision string, removeInjectionLabel bool) error {
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:%q}}}`, label.IoIstioRev.Name, revision)
+	if removeInjectionLabel {
+		patch = fmt.Sprintf(`{"metadata":{"labels":{%q:%q,%q:null}}}`, label.IoIstioRev.Name, revision, istioInjectionLabel)
+	}
+	return u.patchNamespace(ctx, ns, patch)
+}
+
+func (u *Upgrader) patchNamespace(ctx context.Context, ns, patch string) error {
+	_, err := u.client.Kube().CoreV1().Namespaces().Patch(ctx, ns, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to label namespace %s: %v", ns, err)
+	}
+	return nil
+}
+
+// restartNamespace triggers a rolling restart of all Deployments, StatefulSets and DaemonSets in a namespace, like
+// `kubectl rollout restart`.
+func (u *Upgrader) restartNamespace(ctx context.Context, ns string) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+		restartedAtAnnotation, time.Now().Format(time.RFC3339)))
+	apps := u.client.Kube().AppsV1()
+	deployments, err := apps.Deployments(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range deployments.Items {
+		if _, err := apps.Deployments(ns).Patch(ctx, d.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to restart deployment %s/%s: %v", ns, d.Name, err)
+		}
+	}
+	statefulSets, err := apps.StatefulSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, s := range statefulSets.Items {
+		if _, err := apps.StatefulSets(ns).Patch(ctx, s.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to restart statefulset %s/%s: %v", ns, s.Name, err)
+		}
+	}
+	daemonSets, err := apps.DaemonSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range daemonSets.Items {
+		if _, err := apps.DaemonSets(ns).Patch(ctx, d.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to restart daemonset %s/%s: %v", ns, d.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkNamespace verifies that all Deployments, StatefulSets and DaemonSets in a namespace are rolled out, and that
+// their injected pods are ready, injected by the new revision, and in sync with the control plane. Pods not owned by
+// these workloads are not restarted by the migration, so are not checked.
+func (u *Upgrader) checkNamespace(ctx context.Context, ns string) error {
+	if err := u.checkRolledOut(ctx, ns); err != nil {
+		return err
+	}
+	// Pods of Deployments are owned by their ReplicaSets
+	replicaSets, err := u.client.Kube().AppsV1().ReplicaSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	deploymentReplicaSets := map[string]bool{}
+	for _, rs := range replicaSets.Items {
+		if ref := metav1.GetControllerOf(&rs); ref != nil && ref.Kind == "Deployment" {
+			deploymentReplicaSets[rs.Name] = true
+		}
+	}
+
+	pods, err := u.client.Kube().CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	var injected []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if _, f := pod.Annotations[annotation.SidecarStatus.Name]; !f {
+			continue
+		}
+		ref := metav1.GetControllerOf(&pod)
+		if ref == nil || !(ref.Kind == "StatefulSet" || ref.Kind == "DaemonSet" ||
+			ref.Kind == "ReplicaSet" && deploymentReplicaSets[ref.Name]) {
+			continue
+		}
+		if rev := podRevision(pod); rev != u.opts.To {
+			return fmt.Errorf("pod %s/%s is injected by revision %q", ns, pod.Name, rev)
+		}
+		if !podReady(pod) {
+			return fmt.Errorf("pod %s/%s is not ready", ns, pod.Name)
+		}
+		injected = append(injected, pod)
+	}
+	if len(injected) == 0 {
+		return nil
+	}
+	return u.checkSynced(ctx, injected)
+}
+
+func (u *Upgrader) checkRolledOut(ctx context.Context, ns string) error {
+	apps := u.client.Kube().AppsV1()
+	deployments, err := apps.Deployments(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range deployments.Items {
+		replicas := replicasOrDefault(d.Spec.Replicas)
+		if d.Status.ObservedGeneration < d.Generation || d.Status.UpdatedReplicas != replicas ||
+			d.Status.Replicas != replicas || d.Status.AvailableReplicas != replicas {
+			return fmt.Errorf("deployment %s/%s is not rolled out", ns, d.Name)
+		}
+	}
+	statefulSets, err := apps.StatefulSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, s := range statefulSets.Items {
+		replicas := replicasOrDefault(s.Spec.Replicas)
+		if s.Status.ObservedGeneration < s.Generation || s.Status.UpdatedReplicas != replicas ||
+			s.Status.ReadyReplicas != replicas || s.Status.CurrentRevision != s.Status.UpdateRevision {
+			return fmt.Errorf("statefulset %s/%s is not rolled out", ns, s.Name)
+		}
+	}
+	daemonSets, err := apps.DaemonSets(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, d := range daemonSets.Items {
+		if d.Status.ObservedGeneration < d.Generation || d.Status.UpdatedNumberScheduled != d.Status.DesiredNumberScheduled ||
+			d.Status.NumberAvailable != d.Status.DesiredNumberScheduled {
+			return fmt.Errorf("daemonset %s/%s is not rolled out", ns, d.Name)
+		}
+	}
+	return nil
+}
+
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// checkSynced verifies that the control plane has pushed configuration to the proxies of all pods, and that the
+// configuration was acknowledged, using the same data as `istioctl proxy-status`.
+func (u *Upgrader) checkSynced(ctx context.Context, pods []corev1.Pod) error {
+	results, err := u.discoveryDo(ctx, u.opts.IstioNamespace, "/debug/syncz")
+	if err != nil {
+		return fmt.Errorf("failed to get proxy status: %v", err)
+	}
+	statuses := map[string]xds.SyncStatus{}
+	for _, res := range results {
+		var ss []xds.SyncStatus
+		if err := json.Unmarshal(res, &ss); err != nil {
+			return fmt.Errorf("failed to parse proxy status: %v", err)
+		}
+		for _, s := range ss {
+			statuses[s.ProxyID] = s
+		}
+	}
+	for _, pod := range pods {
+		id := pod.Name + "." + pod.Namespace
+		s, f := statuses[id]
+		if !f {
+			return fmt.Errorf("proxy %s is not connected to the control plane", id)
+		}
+		for _, sent := range [][2]string{
+			{s.ClusterSent, s.ClusterAcked},
+			{s.ListenerSent, s.ListenerAcked},
+			{s.RouteSent, s.RouteAcked},
+			{s.EndpointSent, s.EndpointAcked},
+		} {
+			if sent[0] != sent[1] {
+				return fmt.Errorf("proxy %s is not in sync with the control plane", id)
+			}
+		}
+	}
+	return nil
+}
+
+func podRevision(pod corev1.Pod) string {
+	var status inject.SidecarInjectionStatus
+	if err := json.Unmarshal([]byte(pod.Annotations[annotation.SidecarStatus.Name]), &status); err == nil &&
+		status.Revision != "" {
+		return status.Revision
+	}
+	if rev, f := pod.Labels[label.IoIstioRev.Name]; f {
+		return rev
+	}
+	return tag.DefaultRevisionName
+}
+
+func podReady(pod corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func batches(items []string, size int) [][]string {
+	var res [][]string
+	for len(items) > 0 {
+		n := size
+		if n > len(items) {
+			n = len(items)
+		}
+		res = append(res, items[:n])
+		items = items[n:]
+	}
+	return res
+}
@@ -0,0 +1,252 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package canary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	admit_v1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"istio.io/api/annotation"
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestUpgrader(t *testing.T) {
+	cases := []struct {
+		name         string
+		podRevision  string
+		synced       bool
+		wantErr      bool
+		wantRevision string
+	}{
+		{name: "healthy", podRevision: "new", synced: true, wantRevision: "new"},
+		{name: "wrong revision", podRevision: "old", synced: true, wantErr: true, wantRevision: "old"},
+		{name: "not synced", podRevision: "new", synced: false, wantErr: true, wantRevision: "old"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			client := kube.NewFakeClient(
+				&admit_v1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{
+					Name:   "istio-sidecar-injector-new",
+					Labels: map[string]string{label.IoIstioRev.Name: "new"},
+				}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "app",
+					Labels: map[string]string{label.IoIstioRev.Name: "old"},
+				}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+				rolledOutDeployment("app", "app"),
+				replicaSet("app", "app-1234", "app"),
+				injectedPod("app", "app-1", tt.podRevision, "ReplicaSet", "app-1234"),
+				rolledOutStatefulSet("app", "db"),
+				injectedPod("app", "db-0", tt.podRevision, "StatefulSet", "db"),
+				// Pods not owned by restarted workloads keep their revision, and are not checked
+				injectedPod("app", "job-1", "old", "Job", "job"),
+				injectedPod("app", "bare", "old", "", ""),
+			)
+			out := &bytes.Buffer{}
+			u := NewUpgrader(client, Options{
+				From:         "old",
+				To:           "new",
+				BatchTimeout: 50 * time.Millisecond,
+				PollInterval: 10 * time.Millisecond,
+			}, out)
+			u.discoveryDo = func(context.Context, string, string) (map[string][]byte, error) {
+				statuses := []xds.SyncStatus{
+					{ProxyID: "app-1.app", ClusterSent: "1", ClusterAcked: "1"},
+					{ProxyID: "db-0.app", ClusterSent: "1", ClusterAcked: "1"},
+				}
+				if !tt.synced {
+					statuses[0].ClusterAcked = ""
+				}
+				b, err := json.Marshal(statuses)
+				return map[string][]byte{"istiod-new": b}, err
+			}
+
+			err := u.Run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v; output:\n%s", err, tt.wantErr, out.String())
+			}
+			ns, err := client.Kube().CoreV1().Namespaces().Get(context.TODO(), "app", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ns.Labels[label.IoIstioRev.Name]; got != tt.wantRevision {
+				t.Errorf("got namespace revision %q, want %q", got, tt.wantRevision)
+			}
+			dep, err := client.Kube().AppsV1().Deployments("app").Get(context.TODO(), "app", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, f := dep.Spec.Template.Annotations[restartedAtAnnotation]; !f {
+				t.Errorf("expected deployment to be restarted")
+			}
+			sts, err := client.Kube().AppsV1().StatefulSets("app").Get(context.TODO(), "db", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, f := sts.Spec.Template.Annotations[restartedAtAnnotation]; !f {
+				t.Errorf("expected statefulset to be restarted")
+			}
+		})
+	}
+}
+
+func TestUpgraderInjectionLabel(t *testing.T) {
+	cases := []struct {
+		name       string
+		synced     bool
+		wantErr    bool
+		wantLabels map[string]string
+	}{
+		{
+			name:       "migrated",
+			synced:     true,
+			wantLabels: map[string]string{label.IoIstioRev.Name: "new"},
+		},
+		{
+			name:       "rolled back",
+			synced:     false,
+			wantErr:    true,
+			wantLabels: map[string]string{istioInjectionLabel: "enabled"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			client := kube.NewFakeClient(
+				&admit_v1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{
+					Name:   "istio-sidecar-injector-new",
+					Labels: map[string]string{label.IoIstioRev.Name: "new"},
+				}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "app",
+					Labels: map[string]string{istioInjectionLabel: "enabled"},
+				}},
+				rolledOutDeployment("app", "app"),
+				replicaSet("app", "app-1234", "app"),
+				injectedPod("app", "app-1", "new", "ReplicaSet", "app-1234"),
+			)
+			out := &bytes.Buffer{}
+			u := NewUpgrader(client, Options{
+				From:         "default",
+				To:           "new",
+				BatchTimeout: 50 * time.Millisecond,
+				PollInterval: 10 * time.Millisecond,
+			}, out)
+			u.discoveryDo = func(context.Context, string, string) (map[string][]byte, error) {
+				status := xds.SyncStatus{ProxyID: "app-1.app", ClusterSent: "1", ClusterAcked: "1"}
+				if !tt.synced {
+					status.ClusterAcked = ""
+				}
+				b, err := json.Marshal([]xds.SyncStatus{status})
+				return map[string][]byte{"istiod-new": b}, err
+			}
+			err := u.Run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v; output:\n%s", err, tt.wantErr, out.String())
+			}
+			ns, err := client.Kube().CoreV1().Namespaces().Get(context.TODO(), "app", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ns.Labels, tt.wantLabels) {
+				t.Errorf("got namespace labels %v, want %v", ns.Labels, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestUpgraderNothingToMigrate(t *testing.T) {
+	client := kube.NewFakeClient(
+		&admit_v1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{
+			Name:   "istio-sidecar-injector-new",
+			Labels: map[string]string{label.IoIstioRev.Name: "new"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app"}},
+	)
+	u := NewUpgrader(client, Options{From: "default", To: "new"}, &bytes.Buffer{})
+	if err := u.Run(context.Background()); err == nil {
+		t.Fatal("expected an error when no namespace uses the revision")
+	}
+}
+
+func TestBatches(t *testing.T) {
+	got := batches([]string{"a", "b", "c"}, 2)
+	want := [][]string{{"a", "b"}, {"c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func rolledOutDeployment(ns, name string) runtime.Object {
+	replicas := int32(1)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+}
+
+func rolledOutStatefulSet(ns, name string) runtime.Object {
+	replicas := int32(1)
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1},
+	}
+}
+
+func replicaSet(ns, name, deployment string) runtime.Object {
+	controller := true
+	return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       ns,
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: deployment, Controller: &controller}},
+	}}
+}
+
+func injectedPod(ns, name, revision, ownerKind, owner string) runtime.Object {
+	var owners []metav1.OwnerReference
+	if ownerKind != "" {
+		controller := true
+		owners = []metav1.OwnerReference{{Kind: ownerKind, Name: owner, Controller: &controller}}
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+			Annotations: map[string]string{
+				annotation.SidecarStatus.Name: fmt.Sprintf(`{"revision":%q}`, revision),
+			},
+			OwnerReferences: owners,
+		},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
@@ -20,16 +20,22 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/apimachinery/pkg/runtime/serializer/versioning"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	//  to avoid 'No Auth Provider found for name "gcp"'
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -72,8 +78,17 @@ const (
 	remoteSecretPrefix = "istio-remote-secret-"
 	configSecretName   = "istio-kubeconfig"
 	configSecretKey    = "config"
+	// rootCAConfigMapName is the ConfigMap with the CA bundle of the API server, published in each namespace.
+	rootCAConfigMapName = "kube-root-ca.crt"
+	// tokenRequestRoleSuffix is appended to the service account name for the Role allowing to request its tokens.
+	tokenRequestRoleSuffix = "-token-request"
+	// tokenRequesterSuffix is appended to the service account name for the service account requesting its tokens.
+	tokenRequesterSuffix = "-token-requester"
 )
 
+// bootstrapTokenTimeout is how long to wait for the token of the bootstrap token secret to be populated.
+var bootstrapTokenTimeout = 30 * time.Second
+
 func remoteSecretNameFromClusterName(clusterName string) string {
 	return remoteSecretPrefix + clusterName
 }
@@ -82,9 +97,11 @@ func remoteSecretNameFromClusterName(clusterName string) string {
 // together in a multi-cluster mesh.
 func NewCreateRemoteSecretCommand() *cobra.Command {
 	opts := RemoteSecretOptions{
-		AuthType:         RemoteSecretAuthTypeBearerToken,
-		AuthPluginConfig: make(map[string]string),
-		Type:             SecretTypeRemote,
+		AuthType:           RemoteSecretAuthTypeBearerToken,
+		AuthPluginConfig:   make(map[string]string),
+		AuthExecAPIVersion: "client.authentication.k8s.io/v1beta1",
+		TokenDuration:      24 * time.Hour,
+		Type:               SecretTypeRemote,
 	}
 	c := &cobra.Command{
 		Use:   "create-remote-secret",
@@ -99,6 +116,15 @@ func NewCreateRemoteSecretCommand() *cobra.Command {
 
   # Create a secret access a remote cluster with an auth plugin
   istioctl --kubeconfig=c0.yaml x create-remote-secret --name c0 --auth-type=plugin --auth-plugin-name=gcp \
+    | kubectl --kubeconfig=c1.yaml apply -f -
+
+  # Create a secret to access a remote cluster with credentials from an exec plugin, which must be available in istiod
+  istioctl --kubeconfig=c0.yaml x create-remote-secret --name c0 --auth-type=exec \
+    --auth-exec-command=/usr/local/bin/aws-iam-authenticator --auth-exec-arg=token --auth-exec-arg=-i --auth-exec-arg=c0 \
+    | kubectl --kubeconfig=c1.yaml apply -f -
+
+  # Create a secret with a short-lived token, which istiod renews before it expires
+  istioctl --kubeconfig=c0.yaml x create-remote-secret --name c0 --auth-type=token-request --token-duration=1h \
     | kubectl --kubeconfig=c1.yaml apply -f -`,
 		Args: cobra.NoArgs,
 		RunE: func(c *cobra.Command, args []string) error {
@@ -130,10 +156,6 @@ func createRemoteServiceAccountSecret(kubeconfig *api.Config, clusterName, secNa
 	if err := latest.Codec.Encode(kubeconfig, &data); err != nil {
 		return nil, err
 	}
-	key := clusterName
-	if secName == configSecretName {
-		key = configSecretKey
-	}
 	out := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: secName,
@@ -145,12 +167,20 @@ func createRemoteServiceAccountSecret(kubeconfig *api.Config, clusterName, secNa
 			},
 		},
 		Data: map[string][]byte{
-			key: data.Bytes(),
+			remoteSecretKey(clusterName, secName): data.Bytes(),
 		},
 	}
 	return out, nil
 }
 
+// remoteSecretKey returns the key of the kubeconfig in the secret.
+func remoteSecretKey(clusterName, secName string) string {
+	if secName == configSecretName {
+		return configSecretKey
+	}
+	return clusterName
+}
+
 func createBaseKubeconfig(caData []byte, clusterName, server string) *api.Config {
 	return &api.Config{
 		Clusters: map[string]*api.Cluster{
@@ -186,6 +216,14 @@ func createPluginKubeconfig(caData []byte, clusterName, server string, authProvi
 	return c
 }
 
+func createExecKubeconfig(caData []byte, clusterName, server string, execConfig *api.ExecConfig) *api.Config {
+	c := createBaseKubeconfig(caData, clusterName, server)
+	c.AuthInfos[c.CurrentContext] = &api.AuthInfo{
+		Exec: execConfig,
+	}
+	return c
+}
+
 func createRemoteSecretFromPlugin(
 	tokenSecret *v1.Secret,
 	server, clusterName, secName string,
@@ -231,6 +269,154 @@ func createRemoteSecretFromTokenAndServer(tokenSecret *v1.Secret, clusterName, s
 	return createRemoteServiceAccountSecret(kubeconfig, clusterName, secName)
 }
 
+func createRemoteSecretFromExec(caData []byte, server, clusterName, secName string, execConfig *api.ExecConfig) (*v1.Secret, error) {
+	// Create a Kubeconfig to access the remote cluster using credentials from the exec plugin.
+	kubeconfig := createExecKubeconfig(caData, clusterName, server, execConfig)
+	if err := clientcmd.Validate(*kubeconfig); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+
+	// Encode the Kubeconfig in a secret that can be loaded by Istio to dynamically discover and access the remote cluster.
+	return createRemoteServiceAccountSecret(kubeconfig, clusterName, secName)
+}
+
+func createRemoteSecretFromTokenRequest(caData []byte, token, bootstrapToken, server, clusterName, secName string,
+	opt RemoteSecretOptions) (*v1.Secret, error) {
+	// Create a Kubeconfig to access the remote cluster using the short-lived service account token.
+	kubeconfig := createBearerTokenKubeconfig(caData, []byte(token), clusterName, server)
+	if err := clientcmd.Validate(*kubeconfig); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %v", err)
+	}
+
+	out, err := createRemoteServiceAccountSecret(kubeconfig, clusterName, secName)
+	if err != nil {
+		return nil, err
+	}
+	// Istiod renews the token of secrets with these annotations before it expires.
+	out.Annotations[secretcontroller.TokenRequestServiceAccountAnnotation] = opt.Namespace + "/" + opt.ServiceAccountName
+	out.Annotations[secretcontroller.TokenRequestDurationAnnotation] = opt.TokenDuration.String()
+	out.Data[remoteSecretKey(clusterName, secName)+secretcontroller.TokenRequestBootstrapTokenSuffix] = []byte(bootstrapToken)
+	return out, nil
+}
+
+// getCAData returns the CA bundle of the local kube-apiserver, without reading a service account token secret.
+func getCAData(client kube.ExtendedClient, opt RemoteSecretOptions) ([]byte, error) {
+	cm, err := client.CoreV1().ConfigMaps(opt.Namespace).Get(context.TODO(), rootCAConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not get the CA bundle of the local kube-apiserver: %v", err)
+	}
+	caData, ok := cm.Data[v1.ServiceAccountRootCAKey]
+	if !ok {
+		return nil, errMissingRootCAKey
+	}
+	return []byte(caData), nil
+}
+
+// createServiceAccountToken requests a token of the service account with the TokenRequest API. It also returns the
+// bootstrap token, the long-lived token of a separate service account that is only allowed to request tokens of the
+// service account, with which istiod renews the token. The service account cannot request its own tokens, so a leaked
+// token expires.
+func createServiceAccountToken(client kube.ExtendedClient, opt RemoteSecretOptions) (string, string, error) {
+	if opt.TokenDuration < 10*time.Minute {
+		return "", "", fmt.Errorf("--token-duration must be at least 10m, got %v", opt.TokenDuration)
+	}
+	if _, err := getOrCreateServiceAccount(client, opt); err != nil {
+		return "", "", err
+	}
+	bootstrapToken, err := getOrCreateBootstrapToken(client, opt)
+	if err != nil {
+		return "", "", err
+	}
+	if err := createTokenRequestRole(client, opt); err != nil {
+		return "", "", err
+	}
+	seconds := int64(opt.TokenDuration.Seconds())
+	tr, err := client.CoreV1().ServiceAccounts(opt.Namespace).CreateToken(context.TODO(), opt.ServiceAccountName,
+		&authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds}},
+		metav1.CreateOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("could not request a token of service account %s.%s: %v", opt.ServiceAccountName, opt.Namespace, err)
+	}
+	return tr.Status.Token, bootstrapToken, nil
+}
+
+// getOrCreateBootstrapToken creates the token requester service account and a service account token secret for it,
+// and returns its token once it is populated.
+func getOrCreateBootstrapToken(client kube.ExtendedClient, opt RemoteSecretOptions) (string, error) {
+	name := opt.ServiceAccountName + tokenRequesterSuffix
+	sa := &v1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: opt.Namespace}}
+	if _, err := client.CoreV1().ServiceAccounts(opt.Namespace).Create(context.TODO(), sa, metav1.CreateOptions{}); err != nil &&
+		!kerrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("could not create service account %s.%s: %v", name, opt.Namespace, err)
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name + "-token",
+			Namespace:   opt.Namespace,
+			Annotations: map[string]string{v1.ServiceAccountNameKey: name},
+		},
+		Type: v1.SecretTypeServiceAccountToken,
+	}
+	if _, err := client.CoreV1().Secrets(opt.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil &&
+		!kerrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("could not create secret %s.%s: %v", secret.Name, opt.Namespace, err)
+	}
+	var token []byte
+	err := wait.PollImmediate(100*time.Millisecond, bootstrapTokenTimeout, func() (bool, error) {
+		s, err := client.CoreV1().Secrets(opt.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		token = s.Data[v1.ServiceAccountTokenKey]
+		return len(token) > 0, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not get the token of secret %s.%s: %v", secret.Name, opt.Namespace, err)
+	}
+	return string(token), nil
+}
+
+// createTokenRequestRole creates the Role and RoleBinding allowing the token requester service account to request
+// tokens of the service account. A binding to other subjects, such as the service account itself, is replaced.
+func createTokenRequestRole(client kube.ExtendedClient, opt RemoteSecretOptions) error {
+	name := opt.ServiceAccountName + tokenRequestRoleSuffix
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: opt.Namespace},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups:     []string{""},
+			Resources:     []string{"serviceaccounts/token"},
+			ResourceNames: []string{opt.ServiceAccountName},
+			Verbs:         []string{"create"},
+		}},
+	}
+	if _, err := client.RbacV1().Roles(opt.Namespace).Create(context.TODO(), role, metav1.CreateOptions{}); err != nil &&
+		!kerrors.IsAlreadyExists(err) {
+		return fmt.Errorf("could not create role %s.%s: %v", name, opt.Namespace, err)
+	}
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      opt.ServiceAccountName + tokenRequesterSuffix,
+		Namespace: opt.Namespace,
+	}}
+	binding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: opt.Namespace},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name},
+		Subjects:   subjects,
+	}
+	_, err := client.RbacV1().RoleBindings(opt.Namespace).Create(context.TODO(), binding, metav1.CreateOptions{})
+	if kerrors.IsAlreadyExists(err) {
+		var existing *rbacv1.RoleBinding
+		if existing, err = client.RbacV1().RoleBindings(opt.Namespace).Get(context.TODO(), name, metav1.GetOptions{}); err == nil {
+			existing.Subjects = subjects
+			_, err = client.RbacV1().RoleBindings(opt.Namespace).Update(context.TODO(), existing, metav1.UpdateOptions{})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("could not create role binding %s.%s: %v", name, opt.Namespace, err)
+	}
+	return nil
+}
+
 func getServiceAccountSecret(client kube.ExtendedClient, opt RemoteSecretOptions) (*v1.Secret, error) {
 	// Create the service account if it doesn't exist.
 	serviceAccount, err := getOrCreateServiceAccount(client, opt)
@@ -487,6 +673,12 @@ const (
 	// Use a custom authentication plugin for the remote kubernetes cluster.
 	RemoteSecretAuthTypePlugin RemoteSecretAuthType = "plugin"
 
+	// Use credentials from an exec plugin, such as the CLI of an external identity provider.
+	RemoteSecretAuthTypeExec RemoteSecretAuthType = "exec"
+
+	// Use a short-lived token from the TokenRequest API, which istiod renews before it expires.
+	RemoteSecretAuthTypeTokenRequest RemoteSecretAuthType = "token-request"
+
 	// Secret generated from remote cluster
 	SecretTypeRemote SecretType = "remote"
 
@@ -514,6 +706,13 @@ type RemoteSecretOptions struct {
 	// Authenticator plugin configuration
 	AuthPluginName   string
 	AuthPluginConfig map[string]string
+	// Exec credential plugin configuration
+	AuthExecCommand    string
+	AuthExecArgs       []string
+	AuthExecEnv        map[string]string
+	AuthExecAPIVersion string
+	// TokenDuration is the lifetime of the tokens of the token-request authentication type.
+	TokenDuration time.Duration
 
 	// Type of the generated secret
 	Type SecretType
@@ -547,7 +746,10 @@ func (o *RemoteSecretOptions) addFlags(flagset *pflag.FlagSet) {
 	flagset.StringVar(&o.SecretName, "secret-name", "",
 		"The name of the specific secret to use from the service-account. Needed when there are multiple secrets in the service account.")
 	var supportedAuthType []string
-	for _, at := range []RemoteSecretAuthType{RemoteSecretAuthTypeBearerToken, RemoteSecretAuthTypePlugin} {
+	for _, at := range []RemoteSecretAuthType{
+		RemoteSecretAuthTypeBearerToken, RemoteSecretAuthTypePlugin,
+		RemoteSecretAuthTypeExec, RemoteSecretAuthTypeTokenRequest,
+	} {
 		supportedAuthType = append(supportedAuthType, string(at))
 	}
 	var supportedSecretType []string
@@ -563,6 +765,21 @@ func (o *RemoteSecretOptions) addFlags(flagset *pflag.FlagSet) {
 	flagset.StringToString("auth-plugin-config", o.AuthPluginConfig,
 		fmt.Sprintf("Authenticator plug-in configuration. --auth-type=%v must be set with this option",
 			RemoteSecretAuthTypePlugin))
+	flagset.StringVar(&o.AuthExecCommand, "auth-exec-command", o.AuthExecCommand,
+		fmt.Sprintf("Command of the exec credential plugin, which must be available in the istiod image. --auth-type=%v must be set "+
+			"with this option", RemoteSecretAuthTypeExec))
+	flagset.StringArrayVar(&o.AuthExecArgs, "auth-exec-arg", o.AuthExecArgs,
+		fmt.Sprintf("Argument of the exec credential plugin, may be repeated. --auth-type=%v must be set with this option",
+			RemoteSecretAuthTypeExec))
+	flagset.StringToStringVar(&o.AuthExecEnv, "auth-exec-env", o.AuthExecEnv,
+		fmt.Sprintf("Environment variables of the exec credential plugin. --auth-type=%v must be set with this option",
+			RemoteSecretAuthTypeExec))
+	flagset.StringVar(&o.AuthExecAPIVersion, "auth-exec-api-version", o.AuthExecAPIVersion,
+		fmt.Sprintf("API version of the ExecCredential returned by the exec credential plugin. --auth-type=%v must be set "+
+			"with this option", RemoteSecretAuthTypeExec))
+	flagset.DurationVar(&o.TokenDuration, "token-duration", o.TokenDuration,
+		fmt.Sprintf("Lifetime of the service account token, at least 10m. Istiod requests a new token before it expires. "+
+			"--auth-type=%v must be set with this option", RemoteSecretAuthTypeTokenRequest))
 	flagset.Var(&o.Type, "type",
 		fmt.Sprintf("Type of the generated secret. supported values = %v", supportedSecretType))
 	flagset.StringVarP(&o.ManifestsPath, "manifests", "d", "", mesh.ManifestsFlagHelpStr)
@@ -606,9 +823,13 @@ func createRemoteSecret(opt RemoteSecretOptions, client kube.ExtendedClient, env
 	default:
 		return nil, nil, fmt.Errorf("unsupported type: %v", opt.Type)
 	}
-	tokenSecret, err := getServiceAccountSecret(client, opt)
-	if err != nil {
-		return nil, nil, fmt.Errorf("could not get access token to read resources from local kube-apiserver: %v", err)
+	var tokenSecret *v1.Secret
+	var err error
+	if opt.AuthType == RemoteSecretAuthTypeBearerToken || opt.AuthType == RemoteSecretAuthTypePlugin {
+		tokenSecret, err = getServiceAccountSecret(client, opt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not get access token to read resources from local kube-apiserver: %v", err)
+		}
 	}
 
 	var server string
@@ -633,6 +854,33 @@ func createRemoteSecret(opt RemoteSecretOptions, client kube.ExtendedClient, env
 		}
 		remoteSecret, err = createRemoteSecretFromPlugin(tokenSecret, server, opt.ClusterName, secretName,
 			authProviderConfig)
+	case RemoteSecretAuthTypeExec:
+		var caData []byte
+		if caData, err = getCAData(client, opt); err != nil {
+			break
+		}
+		execConfig := &api.ExecConfig{
+			Command:    opt.AuthExecCommand,
+			Args:       opt.AuthExecArgs,
+			APIVersion: opt.AuthExecAPIVersion,
+			// istiod has no terminal to interact with
+			InteractiveMode: api.NeverExecInteractiveMode,
+		}
+		for name, value := range opt.AuthExecEnv {
+			execConfig.Env = append(execConfig.Env, api.ExecEnvVar{Name: name, Value: value})
+		}
+		sort.Slice(execConfig.Env, func(i, j int) bool { return execConfig.Env[i].Name < execConfig.Env[j].Name })
+		remoteSecret, err = createRemoteSecretFromExec(caData, server, opt.ClusterName, secretName, execConfig)
+	case RemoteSecretAuthTypeTokenRequest:
+		var caData []byte
+		if caData, err = getCAData(client, opt); err != nil {
+			break
+		}
+		var token, bootstrapToken string
+		if token, bootstrapToken, err = createServiceAccountToken(client, opt); err != nil {
+			break
+		}
+		remoteSecret, err = createRemoteSecretFromTokenRequest(caData, token, bootstrapToken, server, opt.ClusterName, secretName, opt)
 	default:
 		err = fmt.Errorf("unsupported authentication type: %v", opt.AuthType)
 	}
@@ -16,19 +16,25 @@ package multicluster
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/gomega"
 	"github.com/spf13/pflag"
+	authenticationv1 "k8s.io/api/authentication/v1"
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/clientcmd/api"
 
 	"istio.io/istio/operator/pkg/object"
@@ -839,6 +845,161 @@ users:
 	}
 }
 
+func TestCreateRemoteSecretFromExec(t *testing.T) {
+	fakeClusterName := "fake-clusterName-0"
+	kubeconfig := strings.ReplaceAll(`apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Y2FEYXRh
+    server: https://1.2.3.4
+  name: {cluster}
+contexts:
+- context:
+    cluster: {cluster}
+    user: {cluster}
+  name: {cluster}
+current-context: {cluster}
+kind: Config
+preferences: {}
+users:
+- name: {cluster}
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      args:
+      - token
+      command: authenticator
+      env:
+      - name: CLUSTER
+        value: c0
+      interactiveMode: Never
+      provideClusterInfo: false
+`, "{cluster}", fakeClusterName)
+
+	got, err := createRemoteSecretFromExec([]byte("caData"), "https://1.2.3.4", fakeClusterName,
+		remoteSecretNameFromClusterName(fakeClusterName), &api.ExecConfig{
+			Command:         "authenticator",
+			Args:            []string{"token"},
+			Env:             []api.ExecEnvVar{{Name: "CLUSTER", Value: "c0"}},
+			APIVersion:      "client.authentication.k8s.io/v1beta1",
+			InteractiveMode: api.NeverExecInteractiveMode,
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(got.Data[fakeClusterName]), kubeconfig); diff != "" {
+		t.Fatalf("got %v\nwant %v\ndiff %v", string(got.Data[fakeClusterName]), kubeconfig, diff)
+	}
+
+	if _, err := createRemoteSecretFromExec([]byte("caData"), "https://1.2.3.4", fakeClusterName,
+		remoteSecretNameFromClusterName(fakeClusterName), &api.ExecConfig{}); err == nil {
+		t.Fatal("expected error for exec plugin without command")
+	}
+}
+
+func TestCreateServiceAccountToken(t *testing.T) {
+	opts := RemoteSecretOptions{
+		ServiceAccountName: testServiceAccountName,
+		KubeOptions: KubeOptions{
+			Namespace: testNamespace,
+		},
+		TokenDuration: time.Hour,
+	}
+	// the token controller populated the token of the bootstrap token secret
+	bootstrapSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: testServiceAccountName + tokenRequesterSuffix + "-token", Namespace: testNamespace},
+		Data:       map[string][]byte{v1.ServiceAccountTokenKey: []byte("bootstrap")},
+	}
+	// a binding created by a previous version allowing the service account to request its own tokens
+	oldBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: testServiceAccountName + tokenRequestRoleSuffix, Namespace: testNamespace},
+		Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: testServiceAccountName, Namespace: testNamespace}},
+	}
+	client := kube.NewFakeClient(makeServiceAccount(), bootstrapSecret, oldBinding)
+	var requested *authenticationv1.TokenRequest
+	client.Kube().(*fake.Clientset).PrependReactor("create", "serviceaccounts",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() != "token" {
+				return false, nil, nil
+			}
+			requested = action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+			return true, &authenticationv1.TokenRequest{Status: authenticationv1.TokenRequestStatus{Token: "short-lived"}}, nil
+		})
+
+	token, bootstrapToken, err := createServiceAccountToken(client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "short-lived" || *requested.Spec.ExpirationSeconds != 3600 {
+		t.Fatalf("got token %q with request %v", token, requested)
+	}
+	if bootstrapToken != "bootstrap" {
+		t.Fatalf("got bootstrap token %q", bootstrapToken)
+	}
+	if _, err := client.CoreV1().ServiceAccounts(testNamespace).Get(context.TODO(), testServiceAccountName+tokenRequesterSuffix,
+		metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	role, err := client.RbacV1().Roles(testNamespace).Get(context.TODO(), testServiceAccountName+tokenRequestRoleSuffix, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := role.Rules[0]; rule.Resources[0] != "serviceaccounts/token" || rule.ResourceNames[0] != testServiceAccountName {
+		t.Fatalf("unexpected role rule %v", rule)
+	}
+	// only the token requester is bound to the role, not the service account itself
+	binding, err := client.RbacV1().RoleBindings(testNamespace).Get(context.TODO(), testServiceAccountName+tokenRequestRoleSuffix,
+		metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binding.Subjects) != 1 || binding.Subjects[0].Name != testServiceAccountName+tokenRequesterSuffix {
+		t.Fatalf("unexpected role binding subjects %v", binding.Subjects)
+	}
+
+	// Requesting another token reuses the role
+	if _, _, err := createServiceAccountToken(client, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	opts.TokenDuration = time.Minute
+	if _, _, err := createServiceAccountToken(client, opts); err == nil {
+		t.Fatal("expected error for a token duration shorter than 10m")
+	}
+}
+
+func TestBootstrapTokenTimeout(t *testing.T) {
+	bootstrapTokenTimeout = 200 * time.Millisecond
+	defer func() { bootstrapTokenTimeout = 30 * time.Second }()
+
+	opts := RemoteSecretOptions{
+		ServiceAccountName: testServiceAccountName,
+		KubeOptions:        KubeOptions{Namespace: testNamespace},
+	}
+	// no token controller populates the token
+	if _, err := getOrCreateBootstrapToken(kube.NewFakeClient(), opts); err == nil {
+		t.Fatal("expected error for a token that is never populated")
+	}
+}
+
+func TestGetCAData(t *testing.T) {
+	opts := RemoteSecretOptions{KubeOptions: KubeOptions{Namespace: testNamespace}}
+	if _, err := getCAData(kube.NewFakeClient(), opts); err == nil {
+		t.Fatal("expected error without root CA config map")
+	}
+	client := kube.NewFakeClient(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: rootCAConfigMapName, Namespace: testNamespace},
+		Data:       map[string]string{v1.ServiceAccountRootCAKey: "caData"},
+	})
+	got, err := getCAData(client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "caData" {
+		t.Fatalf("got %q, want caData", got)
+	}
+}
+
 func TestRemoteSecretOptions(t *testing.T) {
 	g := NewWithT(t)
 
@@ -0,0 +1,379 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/golang/protobuf/jsonpb"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+)
+
+// dumpSection describes how to extract the resources of one xDS type from a config dump section.
+type dumpSection struct {
+	title   string
+	typeURL string
+	// list is the field of the section holding the resources
+	list string
+	// resource is the path of the resource within a list entry
+	resource []string
+	// key is the field of the resource identifying it
+	key string
+	// nameOnly compares the resources by key only
+	nameOnly bool
+}
+
+// dumpSections are the sections compared by the DumpComparator, in output order.
+var dumpSections = []dumpSection{
+	{
+		title:    "Clusters",
+		typeURL:  "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+		list:     "dynamic_active_clusters",
+		resource: []string{"cluster"},
+		key:      "name",
+	},
+	{
+		title:    "Listeners",
+		typeURL:  "type.googleapis.com/envoy.admin.v3.ListenersConfigDump",
+		list:     "dynamic_listeners",
+		resource: []string{"active_state", "listener"},
+		key:      "name",
+	},
+	{
+		title:    "Routes",
+		typeURL:  "type.googleapis.com/envoy.admin.v3.RoutesConfigDump",
+		list:     "dynamic_route_configs",
+		resource: []string{"route_config"},
+		key:      "name",
+	},
+	{
+		title:    "Endpoints",
+		typeURL:  "type.googleapis.com/envoy.admin.v3.EndpointsConfigDump",
+		list:     "dynamic_endpoint_configs",
+		resource: []string{"endpoint_config"},
+		key:      "cluster_name",
+	},
+	{
+		title:    "Secrets",
+		typeURL:  "type.googleapis.com/envoy.admin.v3.SecretsConfigDump",
+		list:     "dynamic_active_secrets",
+		key:      "name",
+		nameOnly: true,
+	},
+	{
+		title:    "Extension configs",
+		typeURL:  "type.googleapis.com/envoy.admin.v3.EcdsConfigDump",
+		list:     "ecds_filters",
+		resource: []string{"ecds_filter"},
+		key:      "name",
+	},
+}
+
+// volatileFields change with every push or update, without any change to the configuration.
+var volatileFields = map[string]bool{"version_info": true, "last_updated": true}
+
+// DumpComparator computes a semantic diff between two config dumps, which may come from Envoy or from Istiod.
+// Resources are matched by name within each xDS type and compared field by field. Secrets are only compared by
+// name, and fields which change with every update, such as version_info and last_updated, are ignored.
+type DumpComparator struct {
+	w            io.Writer
+	aName, bName string
+	a, b         map[string]map[string]interface{}
+}
+
+// NewDumpComparator is a DumpComparator constructor. aName and bName describe the source of each dump in the output.
+func NewDumpComparator(w io.Writer, aName string, a []byte, bName string, b []byte) (*DumpComparator, error) {
+	as, err := parseDumpSections(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config dump of %s: %v", aName, err)
+	}
+	bs, err := parseDumpSections(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config dump of %s: %v", bName, err)
+	}
+	return &DumpComparator{w: w, aName: aName, bName: bName, a: as, b: bs}, nil
+}
+
+// parseDumpSections returns the sections of a config dump by type URL, as JSON with the original proto field names.
+// Sections known to the Envoy API version of istioctl are normalized through their protos, so dumps written with
+// either proto or JSON field names can be compared. Unknown sections are used as is.
+func parseDumpSections(dump []byte) (map[string]map[string]interface{}, error) {
+	raw := struct {
+		Configs []map[string]interface{} `json:"configs"`
+	}{}
+	if err := json.Unmarshal(dump, &raw); err != nil {
+		return nil, err
+	}
+	wrapper := &configdump.Wrapper{}
+	if err := json.Unmarshal(dump, wrapper); err != nil {
+		return nil, err
+	}
+	jsonm := &jsonpb.Marshaler{OrigName: true}
+	sections := map[string]map[string]interface{}{}
+	for i, c := range wrapper.Configs {
+		section := raw.Configs[i]
+		if knownTypeURL(c.TypeUrl) {
+			buf := &bytes.Buffer{}
+			if err := jsonm.Marshal(buf, c); err != nil {
+				return nil, err
+			}
+			section = map[string]interface{}{}
+			if err := json.Unmarshal(buf.Bytes(), &section); err != nil {
+				return nil, err
+			}
+		}
+		sections[c.TypeUrl] = section
+	}
+	return sections, nil
+}
+
+// knownTypeURL reports whether the config dump section type is compiled into istioctl.
+func knownTypeURL(typeURL string) bool {
+	switch typeURL {
+	case "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+		"type.googleapis.com/envoy.admin.v3.ListenersConfigDump",
+		"type.googleapis.com/envoy.admin.v3.RoutesConfigDump",
+		"type.googleapis.com/envoy.admin.v3.EndpointsConfigDump",
+		"type.googleapis.com/envoy.admin.v3.SecretsConfigDump":
+		return true
+	}
+	return false
+}
+
+// Diff prints the differences of each xDS type to the writer. It returns true if the dumps differ.
+func (c *DumpComparator) Diff() (bool, error) {
+	fmt.Fprintf(c.w, "--- %s\n+++ %s\n", c.aName, c.bName)
+	differ := false
+	for _, s := range dumpSections {
+		as, aFound := c.a[s.typeURL]
+		bs, bFound := c.b[s.typeURL]
+		switch {
+		case !aFound && !bFound:
+			continue
+		case !aFound:
+			fmt.Fprintf(c.w, "%s: not present in %s, skipped\n", s.title, c.aName)
+			continue
+		case !bFound:
+			fmt.Fprintf(c.w, "%s: not present in %s, skipped\n", s.title, c.bName)
+			continue
+		}
+		ar, err := s.resources(as)
+		if err != nil {
+			return false, fmt.Errorf("%s of %s: %v", s.title, c.aName, err)
+		}
+		br, err := s.resources(bs)
+		if err != nil {
+			return false, fmt.Errorf("%s of %s: %v", s.title, c.bName, err)
+		}
+		if c.diffResources(s.title, ar, br) {
+			differ = true
+		}
+	}
+	return differ, nil
+}
+
+// diffResources prints the differences between the resources of one type, returning true if they differ.
+func (c *DumpComparator) diffResources(title string, a, b map[string]interface{}) bool {
+	names := make([]string, 0, len(a)+len(b))
+	for n := range a {
+		names = append(names, n)
+	}
+	for n := range b {
+		if _, f := a[n]; !f {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	for _, n := range names {
+		ar, aFound := a[n]
+		br, bFound := b[n]
+		switch {
+		case !bFound:
+			fmt.Fprintf(&out, "  - %s (only in %s)\n", n, c.aName)
+		case !aFound:
+			fmt.Fprintf(&out, "  + %s (only in %s)\n", n, c.bName)
+		default:
+			fields := diffValues("", ar, br)
+			if len(fields) == 0 {
+				continue
+			}
+			fmt.Fprintf(&out, "  ~ %s\n", n)
+			for _, f := range fields {
+				fmt.Fprintf(&out, "      %s\n", f)
+			}
+		}
+	}
+	if out.Len() == 0 {
+		fmt.Fprintf(c.w, "%s match\n", title)
+		return false
+	}
+	fmt.Fprintf(c.w, "%s:\n%s", title, out.String())
+	return true
+}
+
+// resources returns the resources of the section by key, without volatile fields.
+func (s dumpSection) resources(section map[string]interface{}) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	entries, _ := section[s.list].([]interface{})
+	for _, e := range entries {
+		var r interface{} = e
+		for _, p := range s.resource {
+			m, _ := r.(map[string]interface{})
+			r = m[p]
+		}
+		rm, ok := r.(map[string]interface{})
+		if !ok {
+			// For example a listener which is only warming or draining
+			continue
+		}
+		name, _ := rm[s.key].(string)
+		if name == "" {
+			return nil, fmt.Errorf("resource without %s", s.key)
+		}
+		if s.nameOnly {
+			res[name] = nil
+			continue
+		}
+		res[name] = stripVolatileFields(rm)
+	}
+	return res, nil
+}
+
+func stripVolatileFields(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			if !volatileFields[k] {
+				res[k] = stripVolatileFields(v)
+			}
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(t))
+		for i, v := range t {
+			res[i] = stripVolatileFields(v)
+		}
+		return res
+	}
+	return v
+}
+
+// diffValues returns the leaf fields that differ between a and b, sorted by path. Lists whose entries all have a
+// name are matched by name, other lists of the same length are compared by index.
+func diffValues(path string, a, b interface{}) []string {
+	am, aIsMap := a.(map[string]interface{})
+	bm, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := make([]string, 0, len(am)+len(bm))
+		for k := range am {
+			keys = append(keys, k)
+		}
+		for k := range bm {
+			if _, f := am[k]; !f {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		var res []string
+		for _, k := range keys {
+			res = append(res, diffValues(joinPath(path, k), am[k], bm[k])...)
+		}
+		return res
+	}
+	al, aIsList := a.([]interface{})
+	bl, bIsList := b.([]interface{})
+	if aIsList && bIsList {
+		an, aNamed := namedEntries(al)
+		bn, bNamed := namedEntries(bl)
+		if aNamed && bNamed {
+			names := make([]string, 0, len(an)+len(bn))
+			for n := range an {
+				names = append(names, n)
+			}
+			for n := range bn {
+				if _, f := an[n]; !f {
+					names = append(names, n)
+				}
+			}
+			sort.Strings(names)
+			var res []string
+			for _, n := range names {
+				res = append(res, diffValues(fmt.Sprintf("%s[name=%s]", path, n), an[n], bn[n])...)
+			}
+			return res
+		}
+		if len(al) == len(bl) {
+			var res []string
+			for i := range al {
+				res = append(res, diffValues(fmt.Sprintf("%s[%d]", path, i), al[i], bl[i])...)
+			}
+			return res
+		}
+	}
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %s -> %s", path, dumpValue(a), dumpValue(b))}
+}
+
+// namedEntries returns the entries of a list by name, if all entries have a unique name.
+func namedEntries(l []interface{}) (map[string]interface{}, bool) {
+	if len(l) == 0 {
+		return nil, false
+	}
+	res := make(map[string]interface{}, len(l))
+	for _, e := range l {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		n, ok := m["name"].(string)
+		if !ok || n == "" {
+			return nil, false
+		}
+		if _, dup := res[n]; dup {
+			return nil, false
+		}
+		res[n] = e
+	}
+	return res, true
+}
+
+func joinPath(path, k string) string {
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
+
+func dumpValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,176 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// istiodDump uses JSON field names, like the config dump generated by Istiod.
+const istiodDump = `{
+  "configs": [
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+      "versionInfo": "2021-08-01T00:00:00Z/1",
+      "dynamicActiveClusters": [
+        {"cluster": {"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster", "name": "a", "connectTimeout": "10s"}},
+        {"cluster": {"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster", "name": "only-istiod"}}
+      ]
+    },
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.RoutesConfigDump",
+      "dynamicRouteConfigs": [
+        {
+          "versionInfo": "1",
+          "routeConfig": {
+            "@type": "type.googleapis.com/envoy.config.route.v3.RouteConfiguration",
+            "name": "80",
+            "virtualHosts": [
+              {"name": "b.default.svc.cluster.local:80", "domains": ["b"]},
+              {"name": "c.default.svc.cluster.local:80", "domains": ["c"]}
+            ]
+          }
+        }
+      ]
+    },
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.SecretsConfigDump",
+      "dynamicActiveSecrets": [
+        {"name": "default", "versionInfo": "1", "secret": {"@type": "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret", "name": "default"}}
+      ]
+    }
+  ]
+}`
+
+// envoyDump uses proto field names, like the config dump of Envoy.
+const envoyDump = `{
+  "configs": [
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump",
+      "version_info": "2021-08-01T00:00:00Z/2",
+      "dynamic_active_clusters": [
+        {
+          "version_info": "2",
+          "last_updated": "2021-08-01T00:00:00Z",
+          "cluster": {"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster", "name": "a", "connect_timeout": "1s"}
+        },
+        {"cluster": {"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster", "name": "only-envoy"}}
+      ]
+    },
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.RoutesConfigDump",
+      "dynamic_route_configs": [
+        {
+          "version_info": "2",
+          "last_updated": "2021-08-01T00:00:00Z",
+          "route_config": {
+            "@type": "type.googleapis.com/envoy.config.route.v3.RouteConfiguration",
+            "name": "80",
+            "virtual_hosts": [
+              {"name": "c.default.svc.cluster.local:80", "domains": ["c"]},
+              {"name": "b.default.svc.cluster.local:80", "domains": ["b", "b.default"]}
+            ]
+          }
+        }
+      ]
+    },
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.SecretsConfigDump",
+      "dynamic_active_secrets": [
+        {
+          "name": "default",
+          "version_info": "2",
+          "secret": {"@type": "type.googleapis.com/envoy.extensions.transport_sockets.tls.v3.Secret", "name": "default", "tls_certificate": {}}
+        }
+      ]
+    },
+    {
+      "@type": "type.googleapis.com/envoy.admin.v3.EcdsConfigDump",
+      "ecds_filters": [
+        {"version_info": "1", "ecds_filter": {"@type": "type.googleapis.com/envoy.config.core.v3.TypedExtensionConfig", "name": "stats"}}
+      ]
+    }
+  ]
+}`
+
+func TestDumpComparator(t *testing.T) {
+	w := &bytes.Buffer{}
+	c, err := NewDumpComparator(w, "istiod", []byte(istiodDump), "envoy", []byte(envoyDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	differ, err := c.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !differ {
+		t.Error("expected dumps to differ")
+	}
+	want := `--- istiod
++++ envoy
+Clusters:
+  ~ a
+      connect_timeout: "10s" -> "1s"
+  + only-envoy (only in envoy)
+  - only-istiod (only in istiod)
+Routes:
+  ~ 80
+      virtual_hosts[name=b.default.svc.cluster.local:80].domains: ["b"] -> ["b","b.default"]
+Secrets match
+Extension configs: not present in istiod, skipped
+`
+	if got := w.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A dump does not differ from itself, regardless of volatile fields
+	w.Reset()
+	c, err = NewDumpComparator(w, "a", []byte(envoyDump), "b", []byte(envoyDump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if differ, err := c.Diff(); err != nil || differ {
+		t.Fatalf("expected no differences, got %v: %s", err, w.String())
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	a := map[string]interface{}{
+		"filters": []interface{}{
+			map[string]interface{}{"name": "x", "value": 1.0},
+			map[string]interface{}{"name": "y"},
+		},
+		"ports": []interface{}{80.0, 443.0},
+	}
+	b := map[string]interface{}{
+		"filters": []interface{}{
+			map[string]interface{}{"name": "x", "value": 2.0},
+			map[string]interface{}{"name": "z"},
+		},
+		"ports": []interface{}{80.0},
+	}
+	got := diffValues("", a, b)
+	want := []string{
+		"filters[name=x].value: 1 -> 2",
+		`filters[name=y]: {"name":"y"} -> <none>`,
+		`filters[name=z]: <none> -> {"name":"z"}`,
+		"ports: [80,443] -> [80]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -128,7 +128,7 @@ func InstallCmd(logOpts *log.Options) *cobra.Command {
 		},
 	}
 
-	addFlags(ic, rootArgs)
+	addPlanFlags(ic, rootArgs)
 	addInstallFlags(ic, iArgs)
 	return ic
 }
@@ -167,6 +167,18 @@ func runApplyCmd(cmd *cobra.Command, rootArgs *rootArgs, iArgs *installArgs, log
 	// "no running Istio pods in istio-system" for the first time
 	_ = DetectIstioVersionDiff(cmd, tag, ns, kubeClient, setFlags)
 
+	if rootArgs.plan {
+		if err := configLogs(logOpts); err != nil {
+			return fmt.Errorf("could not configure logs: %s", err)
+		}
+		plan, err := InstallPlan(iop, restConfig, client, l)
+		if err != nil {
+			return fmt.Errorf("failed to compute install plan: %v", err)
+		}
+		l.Print(plan.String())
+		return nil
+	}
+
 	// Warn users if they use `istioctl install` without any config args.
 	if !rootArgs.dryRun && !iArgs.skipConfirmation {
 		prompt := fmt.Sprintf("This will install the Istio %s %s profile with %q components into the cluster. Proceed? (y/N)", tag, profile, enabledComponents)
@@ -226,6 +238,18 @@ func runApplyCmd(cmd *cobra.Command, rootArgs *rootArgs, iArgs *installArgs, log
 	return nil
 }
 
+// InstallPlan generates manifests from the given istiooperator instance and compares them with the cluster, returning
+// the changes InstallManifests would make. Nothing is written to the cluster.
+func InstallPlan(iop *v1alpha12.IstioOperator, restConfig *rest.Config, client client.Client,
+	l clog.Logger) (*helmreconciler.Plan, error) {
+	opts := &helmreconciler.Options{DryRun: true, Log: l, ProgressLog: progress.NewLog()}
+	reconciler, err := helmreconciler.NewHelmReconciler(client, restConfig, iop, opts)
+	if err != nil {
+		return nil, err
+	}
+	return reconciler.Plan()
+}
+
 // InstallManifests generates manifests from the given istiooperator instance and applies them to the
 // cluster. See GenManifests for more description of the manifest generation process.
 //  force   validation warnings are written to logger but command is not aborted
@@ -353,6 +353,10 @@ func TestManifestGeneratePilot(t *testing.T) {
 			desc:       "pilot_override_kubernetes",
 			diffSelect: "Deployment:*:istiod, Service:*:istiod,MutatingWebhookConfiguration:*:istio-sidecar-injector,ClusterRoleBinding::istio-reader-istio-system",
 		},
+		{
+			desc:       "pilot_component_spec_patches",
+			diffSelect: "Deployment:*:istiod, Service:*:istiod, ConfigMap:*:extra",
+		},
 		// TODO https://github.com/istio/istio/issues/22347 this is broken for overriding things to default value
 		// This can be seen from REGISTRY_ONLY not applying
 		{
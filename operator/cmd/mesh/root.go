@@ -16,6 +16,8 @@ package mesh
 
 import (
 	"flag"
+	"fmt"
+	"strconv"
 
 	"github.com/spf13/cobra"
 
@@ -62,6 +64,8 @@ could be secret list separated by comma, eg. '--imagePullSecrets imagePullSecret
 type rootArgs struct {
 	// Dry run performs all steps except actually applying the manifests or creating output dirs/files.
 	dryRun bool
+	// plan is set with --dry-run=plan. It implies dryRun, and prints the changes an install would make instead.
+	plan bool
 }
 
 func addFlags(cmd *cobra.Command, rootArgs *rootArgs) {
@@ -69,6 +73,47 @@ func addFlags(cmd *cobra.Command, rootArgs *rootArgs) {
 		false, "Console/log output only, make no changes.")
 }
 
+// addPlanFlags adds the dry-run flag of the commands that implement --dry-run=plan. Other commands only accept a
+// boolean.
+func addPlanFlags(cmd *cobra.Command, rootArgs *rootArgs) {
+	f := cmd.PersistentFlags().VarPF(&dryRunValue{args: rootArgs}, "dry-run", "",
+		"Console/log output only, make no changes. With --dry-run=plan, compares the rendered manifests "+
+			"with the cluster and prints the objects that would be created, updated or pruned.")
+	f.NoOptDefVal = "true"
+}
+
+// dryRunValue is a boolean flag value that additionally accepts "plan".
+type dryRunValue struct {
+	args *rootArgs
+}
+
+func (v *dryRunValue) String() string {
+	if v.args == nil {
+		return "false"
+	}
+	if v.args.plan {
+		return "plan"
+	}
+	return strconv.FormatBool(v.args.dryRun)
+}
+
+func (v *dryRunValue) Set(s string) error {
+	if s == "plan" {
+		v.args.dryRun, v.args.plan = true, true
+		return nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid value %q, must be a boolean or \"plan\"", s)
+	}
+	v.args.dryRun, v.args.plan = b, false
+	return nil
+}
+
+func (v *dryRunValue) Type() string {
+	return "string"
+}
+
 // GetRootCmd returns the root of the cobra command-tree.
 func GetRootCmd(args []string) *cobra.Command {
 	rootCmd := &cobra.Command{
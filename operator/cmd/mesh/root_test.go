@@ -0,0 +1,61 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestDryRunPlanFlag(t *testing.T) {
+	cases := []struct {
+		args    []string
+		dryRun  bool
+		plan    bool
+		wantErr bool
+	}{
+		{args: nil},
+		{args: []string{"--dry-run"}, dryRun: true},
+		{args: []string{"--dry-run=true"}, dryRun: true},
+		{args: []string{"--dry-run=false"}},
+		{args: []string{"--dry-run=plan"}, dryRun: true, plan: true},
+		{args: []string{"--dry-run=other"}, wantErr: true},
+	}
+	for _, tt := range cases {
+		rootArgs := &rootArgs{}
+		cmd := &cobra.Command{Use: "test"}
+		addPlanFlags(cmd, rootArgs)
+		err := cmd.ParseFlags(tt.args)
+		if gotErr := err != nil; gotErr != tt.wantErr {
+			t.Fatalf("%v: got error %v, want error %v", tt.args, err, tt.wantErr)
+		}
+		if rootArgs.dryRun != tt.dryRun || rootArgs.plan != tt.plan {
+			t.Errorf("%v: got dryRun=%v plan=%v, want dryRun=%v plan=%v", tt.args, rootArgs.dryRun, rootArgs.plan, tt.dryRun, tt.plan)
+		}
+	}
+}
+
+func TestDryRunFlagRejectsPlan(t *testing.T) {
+	rootArgs := &rootArgs{}
+	cmd := &cobra.Command{Use: "test"}
+	addFlags(cmd, rootArgs)
+	if err := cmd.ParseFlags([]string{"--dry-run=plan"}); err == nil {
+		t.Fatalf("expected --dry-run=plan to be rejected")
+	}
+	if err := cmd.ParseFlags([]string{"--dry-run"}); err != nil || !rootArgs.dryRun || rootArgs.plan {
+		t.Fatalf("expected boolean dry run, got dryRun=%v plan=%v, %v", rootArgs.dryRun, rootArgs.plan, err)
+	}
+}
@@ -127,7 +127,7 @@ func UpgradeCmd() *cobra.Command {
 			return err
 		},
 	}
-	addFlags(cmd, rootArgs)
+	addPlanFlags(cmd, rootArgs)
 	addUpgradeFlags(cmd, macArgs)
 	return cmd
 }
@@ -215,6 +215,15 @@ func upgrade(rootArgs *rootArgs, args *upgradeArgs, l clog.Logger) (err error) {
 	}
 	checkUpgradeIOPS(currentProfileIOPSYaml, targetIOPYaml, overrideIOPYaml, l)
 
+	if rootArgs.plan {
+		plan, err := InstallPlan(targetIOP, restConfig, client, l)
+		if err != nil {
+			return fmt.Errorf("failed to compute upgrade plan: %v", err)
+		}
+		l.Print(plan.String())
+		return nil
+	}
+
 	waitForConfirmation(args.skipConfirmation || rootArgs.dryRun, l)
 
 	// Apply the Istio Control Plane specs reading from inFilenames to the cluster
@@ -25,6 +25,8 @@ import (
 
 	"istio.io/api/operator/v1alpha1"
 	valuesv1alpha1 "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/patch"
 	"istio.io/istio/operator/pkg/tpath"
 	"istio.io/istio/operator/pkg/util"
 )
@@ -56,9 +58,31 @@ func ValidateConfig(failOnMissingValidation bool, iopls *v1alpha1.IstioOperatorS
 	if deprecatedErrors != nil {
 		validationErrors = util.AppendErr(validationErrors, deprecatedErrors)
 	}
+	if specWarnings := checkComponentSpecFields(iopls); specWarnings != "" {
+		if warningMessage != "" {
+			warningMessage += "\n"
+		}
+		warningMessage += specWarnings
+	}
 	return validationErrors, warningMessage
 }
 
+// checkComponentSpecFields warns about fields of component specs that are not used by the kustomize-style patches.
+func checkComponentSpecFields(iop *v1alpha1.IstioOperatorSpec) string {
+	messages := []string{}
+	for _, c := range name.KustomizeComponentNames {
+		path := fmt.Sprintf("Components.%s.Spec", c)
+		spec, f, _ := tpath.GetFromStructPath(iop, path)
+		if !f || util.IsValueNil(spec) {
+			continue
+		}
+		for _, field := range patch.UnknownKustomizeFields(spec) {
+			messages = append(messages, fmt.Sprintf("! %s.%s is not a known field and is ignored", firstCharsToLower(path), field))
+		}
+	}
+	return strings.Join(messages, "\n")
+}
+
 // Converts from struct paths to helm paths
 // Global.Proxy.AccessLogFormat -> global.proxy.accessLogFormat
 func firstCharsToLower(s string) string {
@@ -180,6 +180,23 @@ values:
 `,
 			errors: `port 80 is invalid: targetPort is set to 90, which requires root. Set targetPort to be greater than 1024 or configure values.gateways.istio-ingressgateway.runAsRoot=true`,
 		},
+		{
+			name: "unknown component spec fields",
+			values: `
+components:
+  pilot:
+    spec:
+      replicas: 2
+      patchesJson6902:
+      - target:
+          kind: Deployment
+          name: istiod
+          labels: {}
+        patch: []
+`,
+			warnings: `! components.pilot.spec.replicas is not a known field and is ignored
+! components.pilot.spec.patchesJson6902[0].target.labels is not a known field and is ignored`,
+		},
 		{
 			name: "legacy values ports valid",
 			values: `
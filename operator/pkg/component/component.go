@@ -31,6 +31,7 @@ import (
 	"istio.io/istio/operator/pkg/patch"
 	"istio.io/istio/operator/pkg/tpath"
 	"istio.io/istio/operator/pkg/translate"
+	"istio.io/istio/operator/pkg/util"
 	"istio.io/pkg/log"
 )
 
@@ -446,25 +447,46 @@ func renderManifest(c IstioComponent, cf *CommonComponentFields) (string, error)
 	if err != nil {
 		return "", err
 	}
-	if !found {
-		scope.Debugf("Manifest after resources: \n%s\n", my)
+	if found {
+		kyo, err := yaml.Marshal(overlays)
+		if err != nil {
+			return "", err
+		}
+		scope.Infof("Applying Kubernetes overlay: \n%s\n", kyo)
+		my, err = patch.YAMLManifestPatch(my, cf.Namespace, overlays)
+		if err != nil {
+			metrics.CountManifestRenderError(c.ComponentName(), metrics.K8SManifestPatchError)
+			return "", err
+		}
+		scope.Debugf("Manifest after resources and overlay: \n%s\n", my)
+	}
+
+	// Add the resources and patches from the component spec. Other components do not have a spec field, and their
+	// spec would not be validated.
+	if !cf.ComponentName.SupportsKustomize() {
 		metrics.CountManifestRender(cf.ComponentName)
 		return my, nil
 	}
-	kyo, err := yaml.Marshal(overlays)
+	spec, found, err := tpath.GetFromStructPath(cf.InstallSpec, fmt.Sprintf("Components.%s.Spec", cf.ComponentName))
 	if err != nil {
 		return "", err
 	}
-	scope.Infof("Applying Kubernetes overlay: \n%s\n", kyo)
-	ret, err := patch.YAMLManifestPatch(my, cf.Namespace, overlays)
-	if err != nil {
-		metrics.CountManifestRenderError(c.ComponentName(), metrics.K8SManifestPatchError)
-		return "", err
+	if found && !util.IsValueNil(spec) {
+		ks, err := patch.ParseKustomizeSpec(spec)
+		if err != nil {
+			metrics.CountManifestRenderError(c.ComponentName(), metrics.K8SManifestPatchError)
+			return "", err
+		}
+		my, err = patch.KustomizeManifest(my, cf.Namespace, ks)
+		if err != nil {
+			metrics.CountManifestRenderError(c.ComponentName(), metrics.K8SManifestPatchError)
+			return "", err
+		}
+		scope.Debugf("Manifest after component spec patches: \n%s\n", my)
 	}
 
-	scope.Debugf("Manifest after resources and overlay: \n%s\n", ret)
 	metrics.CountManifestRender(cf.ComponentName)
-	return ret, nil
+	return my, nil
 }
 
 // createHelmRenderer creates a helm renderer for the component defined by c and returns a ptr to it.
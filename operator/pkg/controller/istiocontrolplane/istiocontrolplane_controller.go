@@ -19,8 +19,11 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -31,6 +34,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -64,6 +68,8 @@ const (
 	finalizerMaxRetries = 1
 	// IgnoreReconcileAnnotation is annotation of IstioOperator CR so it would be ignored during Reconcile loop.
 	IgnoreReconcileAnnotation = "install.istio.io/ignoreReconcile"
+	// eventRecorderName is the component name of events recorded by the controller.
+	eventRecorderName = "istio-operator"
 )
 
 var (
@@ -174,13 +180,24 @@ func NewReconcileIstioOperator(client client.Client, config *rest.Config, scheme
 	}
 }
 
+// DriftDetectionOptions configures the periodic detection of drift between the rendered manifest and the live
+// objects in the cluster.
+type DriftDetectionOptions struct {
+	// Interval between two drift checks of an IstioOperator CR. Drift detection is disabled if zero.
+	Interval time.Duration
+	// ReportOnly reports drifted objects in the IstioOperator status, instead of reverting them.
+	ReportOnly bool
+}
+
 // ReconcileIstioOperator reconciles a IstioOperator object
 type ReconcileIstioOperator struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
-	config *rest.Config
-	scheme *runtime.Scheme
+	client   client.Client
+	config   *rest.Config
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	drift    DriftDetectionOptions
 }
 
 // Reconcile reads that state of the cluster for a IstioOperator object and makes changes based on the state read
@@ -341,15 +358,85 @@ func (r *ReconcileIstioOperator) Reconcile(_ context.Context, request reconcile.
 	if err := reconciler.SetStatusBegin(); err != nil {
 		return reconcile.Result{}, err
 	}
+	var drifted []helmreconciler.DriftedObject
+	if r.drift.Interval > 0 && !r.drift.ReportOnly {
+		// Drifted objects are removed from the object cache, so that Reconcile applies them again.
+		drifted = r.detectDrift(reconciler)
+		reconciler.RevertDrift(drifted)
+	}
 	status, err := reconciler.Reconcile()
 	if err != nil {
 		scope.Errorf("Error during reconcile: %s", err)
 	}
+	if r.drift.Interval > 0 && r.drift.ReportOnly {
+		drifted = r.detectDrift(reconciler)
+	}
+	helmreconciler.SetDriftStatus(status, drifted, !r.drift.ReportOnly)
+	r.recordDriftEvents(iop, drifted)
 	if err := reconciler.SetStatusComplete(status); err != nil {
 		return reconcile.Result{}, err
 	}
 
-	return reconcile.Result{}, err
+	return reconcile.Result{RequeueAfter: r.drift.Interval}, err
+}
+
+// detectDrift returns the objects that differ from the rendered manifest. Errors are logged, as drift detection
+// must not block reconciling.
+func (r *ReconcileIstioOperator) detectDrift(reconciler *helmreconciler.HelmReconciler) []helmreconciler.DriftedObject {
+	drifted, err := reconciler.DetectDrift()
+	if err != nil {
+		scope.Warnf("Error during drift detection: %s", err)
+	}
+	for _, d := range drifted {
+		if d.Missing {
+			scope.Infof("Object %s of component %s is missing from the cluster", d.Object, d.Component)
+		} else {
+			scope.Infof("Object %s of component %s differs from the rendered manifest:\n%s", d.Object, d.Component, d.Diff)
+		}
+	}
+	return drifted
+}
+
+// recordDriftEvents records an event on the IstioOperator CR for each component with drifted objects.
+func (r *ReconcileIstioOperator) recordDriftEvents(iop *iopv1alpha1.IstioOperator, drifted []helmreconciler.DriftedObject) {
+	if r.recorder == nil || len(drifted) == 0 {
+		return
+	}
+	byComponent := map[string][]string{}
+	for _, d := range drifted {
+		byComponent[d.Component] = append(byComponent[d.Component], d.Object)
+	}
+	for c, objs := range byComponent {
+		if r.drift.ReportOnly {
+			r.recorder.Eventf(iop, corev1.EventTypeWarning, "DriftDetected",
+				"Objects of component %s differ from the rendered manifest: %s", c, strings.Join(objs, ", "))
+		} else {
+			r.recorder.Eventf(iop, corev1.EventTypeNormal, "DriftReverted",
+				"Reverted objects of component %s that differed from the rendered manifest: %s", c, strings.Join(objs, ", "))
+		}
+	}
+}
+
+// driftDetectionOptionsFromEnv reads the drift detection options from the DRIFT_DETECTION_INTERVAL and
+// DRIFT_DETECTION_REPORT_ONLY environment variables.
+func driftDetectionOptionsFromEnv() DriftDetectionOptions {
+	var opts DriftDetectionOptions
+	if s, found := os.LookupEnv("DRIFT_DETECTION_INTERVAL"); found && s != "" {
+		interval, err := time.ParseDuration(s)
+		if err != nil {
+			scope.Warnf("Invalid DRIFT_DETECTION_INTERVAL %q, drift detection is disabled: %v", s, err)
+		} else {
+			opts.Interval = interval
+		}
+	}
+	if s, found := os.LookupEnv("DRIFT_DETECTION_REPORT_ONLY"); found && s != "" {
+		reportOnly, err := strconv.ParseBool(s)
+		if err != nil {
+			scope.Warnf("Invalid DRIFT_DETECTION_REPORT_ONLY %q: %v", s, err)
+		}
+		opts.ReportOnly = reportOnly
+	}
+	return opts
 }
 
 // mergeIOPSWithProfile overlays the values in iop on top of the defaults for the profile given by iop.profile and
@@ -415,7 +502,13 @@ func mergeIOPSWithProfile(iop *iopv1alpha1.IstioOperator) (*v1alpha1.IstioOperat
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
 	restConfig = mgr.GetConfig()
-	return add(mgr, &ReconcileIstioOperator{client: mgr.GetClient(), scheme: mgr.GetScheme(), config: mgr.GetConfig()})
+	return add(mgr, &ReconcileIstioOperator{
+		client:   mgr.GetClient(),
+		scheme:   mgr.GetScheme(),
+		config:   mgr.GetConfig(),
+		recorder: mgr.GetEventRecorderFor(eventRecorderName),
+		drift:    driftDetectionOptionsFromEnv(),
+	})
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -0,0 +1,264 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helmreconciler
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/api/operator/v1alpha1"
+	"istio.io/istio/operator/pkg/compare"
+	"istio.io/istio/operator/pkg/metrics"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/istio/operator/pkg/util"
+)
+
+// DriftedObject is a rendered object whose live counterpart in the cluster differs from the manifest.
+type DriftedObject struct {
+	Component string
+	// Object is the object hash, in the form Kind:Namespace:Name.
+	Object string
+	// Missing is true if the object does not exist in the cluster.
+	Missing bool
+	// Diff is the tree based diff from the live object to the rendered object.
+	Diff string
+}
+
+// DetectDrift renders the manifests and compares each object with the live object in the cluster. Only the fields
+// set in the manifest are compared, so fields defaulted by the API server or set by other controllers are not
+// reported as drift. Values the API server canonicalizes, such as quantities, are compared semantically. The number
+// of drifted objects per component is recorded as a metric.
+func (h *HelmReconciler) DetectDrift() ([]DriftedObject, error) {
+	manifestMap, err := h.RenderCharts()
+	if err != nil {
+		return nil, err
+	}
+	var drifted []DriftedObject
+	var errs util.Errors
+	for cname, manifest := range manifestMap.Consolidated() {
+		objects, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+		if err != nil {
+			return nil, err
+		}
+		count := 0
+		for _, obj := range objects {
+			obju := obj.UnstructuredObject()
+			if err := h.applyLabelsAndAnnotations(obju, cname); err != nil {
+				return nil, err
+			}
+			d, err := h.driftObject(obju)
+			if err != nil {
+				errs = util.AppendErr(errs, err)
+				continue
+			}
+			if d == nil {
+				continue
+			}
+			d.Component = cname
+			d.Object = obj.Hash()
+			drifted = append(drifted, *d)
+			count++
+		}
+		metrics.RecordDriftedResources(cname, count)
+	}
+	sort.SliceStable(drifted, func(i, j int) bool {
+		if drifted[i].Component != drifted[j].Component {
+			return drifted[i].Component < drifted[j].Component
+		}
+		return drifted[i].Object < drifted[j].Object
+	})
+	return drifted, errs.ToError()
+}
+
+// driftObject compares a single rendered object with the live object, returning nil if they do not differ.
+func (h *HelmReconciler) driftObject(obj *unstructured.Unstructured) (*DriftedObject, error) {
+	objectStr := fmt.Sprintf("%s/%s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	err := h.client.Get(context.TODO(), client.ObjectKeyFromObject(obj), live)
+	if errors2.IsNotFound(err) {
+		return &DriftedObject{Missing: true}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %v", objectStr, err)
+	}
+
+	desired := normalizeForPlan(obj).Object
+	ly, err := yaml.Marshal(projectFields(normalizeForPlan(live).Object, desired))
+	if err != nil {
+		return nil, err
+	}
+	dy, err := yaml.Marshal(desired)
+	if err != nil {
+		return nil, err
+	}
+	if diff := compare.YAMLCmp(string(ly), string(dy)); diff != "" {
+		return &DriftedObject{Diff: diff}, nil
+	}
+	return nil, nil
+}
+
+// projectFields returns the parts of live which are set in desired. Lists are projected element by element if both
+// have the same length, otherwise the live list is returned as is. Live values semantically equal to the desired
+// value are replaced by the desired value, and empty desired values the API server omits are kept, so that neither
+// shows up when comparing the result with desired.
+func projectFields(live, desired interface{}) interface{} {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		lm, ok := live.(map[string]interface{})
+		if !ok {
+			return live
+		}
+		res := make(map[string]interface{}, len(d))
+		for k, dv := range d {
+			if lv, f := lm[k]; f {
+				res[k] = projectFields(lv, dv)
+			} else if isZeroValue(dv) {
+				res[k] = dv
+			}
+		}
+		return res
+	case []interface{}:
+		ll, ok := live.([]interface{})
+		if !ok || len(ll) != len(d) {
+			return live
+		}
+		res := make([]interface{}, len(d))
+		for i := range d {
+			res[i] = projectFields(ll[i], d[i])
+		}
+		return res
+	}
+	if semanticallyEqual(live, desired) {
+		return desired
+	}
+	return live
+}
+
+// semanticallyEqual compares two scalar values, considering numbers of different types and quantities in their
+// canonical form, as returned by the API server, equal. For example, 2048Mi is returned as 2Gi.
+func semanticallyEqual(live, desired interface{}) bool {
+	if reflect.DeepEqual(live, desired) {
+		return true
+	}
+	if lf, ok := toFloat(live); ok {
+		if df, ok := toFloat(desired); ok {
+			return lf == df
+		}
+	}
+	ls, lok := live.(string)
+	if !lok {
+		return false
+	}
+	var q resource.Quantity
+	var err error
+	switch d := desired.(type) {
+	case string:
+		q, err = resource.ParseQuantity(d)
+	case int64, float64:
+		q, err = resource.ParseQuantity(fmt.Sprint(d))
+	default:
+		return false
+	}
+	return err == nil && q.String() == ls
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// isZeroValue checks whether a value is empty or a zero scalar, in which case the API server may omit it.
+func isZeroValue(v interface{}) bool {
+	switch t := v.(type) {
+	case string:
+		return t == ""
+	case bool:
+		return !t
+	}
+	if f, ok := toFloat(v); ok {
+		return f == 0
+	}
+	return isEmptyValue(v)
+}
+
+// RevertDrift removes the drifted objects from the object cache, so they are applied again by the next Reconcile.
+func (h *HelmReconciler) RevertDrift(drifted []DriftedObject) {
+	for _, d := range drifted {
+		h.removeFromObjectCache(d.Component, d.Object)
+		metrics.CountDriftRevert(strings.SplitN(d.Object, ":", 2)[0])
+	}
+}
+
+// SetDriftStatus reports the drifted objects in status, listing them in the component error. If the drift was not
+// reverted, components with drifted objects are marked as ACTION_REQUIRED. Otherwise their status is left as is.
+func SetDriftStatus(status *v1alpha1.InstallStatus, drifted []DriftedObject, reverted bool) {
+	if status == nil || len(drifted) == 0 {
+		return
+	}
+	if status.ComponentStatus == nil {
+		status.ComponentStatus = map[string]*v1alpha1.InstallStatus_VersionStatus{}
+	}
+	byComponent := map[string][]string{}
+	var components []string
+	for _, d := range drifted {
+		if _, f := byComponent[d.Component]; !f {
+			components = append(components, d.Component)
+		}
+		byComponent[d.Component] = append(byComponent[d.Component], d.Object)
+	}
+	sort.Strings(components)
+	for _, c := range components {
+		cs := status.ComponentStatus[c]
+		if cs != nil && cs.Status == v1alpha1.InstallStatus_ERROR {
+			// Errors take precedence, the component could not be applied
+			continue
+		}
+		objects := strings.Join(byComponent[c], ", ")
+		if !reverted {
+			setStatus(status.ComponentStatus, name.ComponentName(c), v1alpha1.InstallStatus_ACTION_REQUIRED,
+				fmt.Errorf("objects differ from the rendered manifest: %s", objects))
+			continue
+		}
+		if cs == nil {
+			cs = &v1alpha1.InstallStatus_VersionStatus{Status: v1alpha1.InstallStatus_HEALTHY}
+			status.ComponentStatus[c] = cs
+		}
+		cs.Error = fmt.Sprintf("reverted objects that differed from the rendered manifest: %s", objects)
+	}
+	status.Status = overallStatus(status.ComponentStatus)
+	if reverted {
+		status.Message = fmt.Sprintf("%d objects differing from the rendered manifest were reverted", len(drifted))
+	} else {
+		status.Message = fmt.Sprintf("%d objects differ from the rendered manifest", len(drifted))
+	}
+}
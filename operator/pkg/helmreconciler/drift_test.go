@@ -0,0 +1,257 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helmreconciler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/api/operator/v1alpha1"
+	iopv1alpha1 "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/operator/pkg/util"
+	"istio.io/istio/operator/pkg/util/clog"
+	"istio.io/istio/operator/pkg/util/progress"
+	"istio.io/istio/pkg/test/env"
+)
+
+func TestDetectDrift(t *testing.T) {
+	iopStr, err := os.ReadFile(filepath.Join(env.IstioSrc, "manifests/profiles/default.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iop := &iopv1alpha1.IstioOperator{}
+	if err := util.UnmarshalWithJSONPB(string(iopStr), iop, false); err != nil {
+		t.Fatal(err)
+	}
+	iop.Spec.InstallPackagePath = filepath.Join(env.IstioSrc, "manifests")
+	h := &HelmReconciler{
+		client: fake.NewClientBuilder().Build(),
+		opts: &Options{
+			ProgressLog: progress.NewLog(),
+			Log:         clog.NewDefaultLogger(),
+		},
+		iop:           iop,
+		countLock:     &sync.Mutex{},
+		prunedKindSet: map[schema.GroupKind]struct{}{},
+	}
+	manifestMap, err := h.RenderCharts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyResourcesIntoCluster(t, h, manifestMap)
+
+	drifted, err := h.DetectDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 0 {
+		t.Fatalf("expected no drift, got %+v", drifted)
+	}
+
+	// Fields not in the manifest are not drift
+	dep := &unstructured.Unstructured{}
+	dep.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	key := client.ObjectKey{Namespace: "istio-system", Name: "istiod"}
+	if err := h.client.Get(context.TODO(), key, dep); err != nil {
+		t.Fatal(err)
+	}
+	if err := unstructured.SetNestedField(dep.Object, "bar", "metadata", "labels", "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.client.Update(context.TODO(), dep); err != nil {
+		t.Fatal(err)
+	}
+	drifted, err = h.DetectDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 0 {
+		t.Fatalf("expected no drift, got %+v", drifted)
+	}
+
+	// Changed and deleted objects are drift
+	if err := unstructured.SetNestedField(dep.Object, "other", "metadata", "labels", "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.client.Update(context.TODO(), dep); err != nil {
+		t.Fatal(err)
+	}
+	sa := &unstructured.Unstructured{}
+	sa.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"})
+	sa.SetNamespace("istio-system")
+	sa.SetName("istiod")
+	if err := h.client.Delete(context.TODO(), sa); err != nil {
+		t.Fatal(err)
+	}
+	drifted, err = h.DetectDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DriftedObject{
+		{
+			Component: "Pilot",
+			Object:    "Deployment:istio-system:istiod",
+			Diff:      "metadata:\n  labels:\n    app: other -> istiod\n",
+		},
+		{
+			Component: "Pilot",
+			Object:    "ServiceAccount:istio-system:istiod",
+			Missing:   true,
+		},
+	}
+	if !reflect.DeepEqual(drifted, want) {
+		t.Fatalf("got drift %+v, want %+v", drifted, want)
+	}
+
+	status := &v1alpha1.InstallStatus{
+		Status: v1alpha1.InstallStatus_HEALTHY,
+		ComponentStatus: map[string]*v1alpha1.InstallStatus_VersionStatus{
+			"Base":  {Status: v1alpha1.InstallStatus_HEALTHY},
+			"Pilot": {Status: v1alpha1.InstallStatus_HEALTHY},
+		},
+	}
+	SetDriftStatus(status, drifted, false)
+	if status.Status != v1alpha1.InstallStatus_ACTION_REQUIRED {
+		t.Errorf("got overall status %v, want ACTION_REQUIRED", status.Status)
+	}
+	if got := status.ComponentStatus["Base"].Status; got != v1alpha1.InstallStatus_HEALTHY {
+		t.Errorf("got Base status %v, want HEALTHY", got)
+	}
+	pilot := status.ComponentStatus["Pilot"]
+	wantErr := "objects differ from the rendered manifest: Deployment:istio-system:istiod, ServiceAccount:istio-system:istiod"
+	if pilot.Status != v1alpha1.InstallStatus_ACTION_REQUIRED || pilot.Error != wantErr {
+		t.Errorf("got Pilot status %v, want ACTION_REQUIRED with error %q", pilot, wantErr)
+	}
+
+	// Reverted drift is reported without changing the component status
+	status = &v1alpha1.InstallStatus{
+		Status: v1alpha1.InstallStatus_HEALTHY,
+		ComponentStatus: map[string]*v1alpha1.InstallStatus_VersionStatus{
+			"Pilot": {Status: v1alpha1.InstallStatus_HEALTHY},
+		},
+	}
+	SetDriftStatus(status, drifted, true)
+	pilot = status.ComponentStatus["Pilot"]
+	wantErr = "reverted objects that differed from the rendered manifest: Deployment:istio-system:istiod, ServiceAccount:istio-system:istiod"
+	if status.Status != v1alpha1.InstallStatus_HEALTHY || pilot.Status != v1alpha1.InstallStatus_HEALTHY || pilot.Error != wantErr {
+		t.Errorf("got status %v, want HEALTHY with Pilot error %q", status, wantErr)
+	}
+}
+
+// TestDetectDriftCanonicalized ensures values the API server canonicalizes or omits are not drift.
+func TestDetectDriftCanonicalized(t *testing.T) {
+	iopStr, err := os.ReadFile(filepath.Join(env.IstioSrc, "manifests/profiles/default.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iop := &iopv1alpha1.IstioOperator{}
+	if err := util.UnmarshalWithJSONPB(string(iopStr), iop, false); err != nil {
+		t.Fatal(err)
+	}
+	iop.Spec.InstallPackagePath = filepath.Join(env.IstioSrc, "manifests")
+	h := &HelmReconciler{
+		client: fake.NewClientBuilder().Build(),
+		opts: &Options{
+			ProgressLog: progress.NewLog(),
+			Log:         clog.NewDefaultLogger(),
+		},
+		iop:           iop,
+		countLock:     &sync.Mutex{},
+		prunedKindSet: map[schema.GroupKind]struct{}{},
+	}
+	manifestMap, err := h.RenderCharts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyResourcesIntoCluster(t, h, manifestMap)
+
+	dep := &unstructured.Unstructured{}
+	dep.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	key := client.ObjectKey{Namespace: "istio-system", Name: "istiod"}
+	if err := h.client.Get(context.TODO(), key, dep); err != nil {
+		t.Fatal(err)
+	}
+	containers, _, _ := unstructured.NestedSlice(dep.Object, "spec", "template", "spec", "containers")
+	c := containers[0].(map[string]interface{})
+	if mem, _, _ := unstructured.NestedString(c, "resources", "requests", "memory"); mem != "2048Mi" {
+		t.Fatalf("expected istiod to request 2048Mi, got %q", mem)
+	}
+	if err := unstructured.SetNestedField(c, "2Gi", "resources", "requests", "memory"); err != nil {
+		t.Fatal(err)
+	}
+	if err := unstructured.SetNestedSlice(dep.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.client.Update(context.TODO(), dep); err != nil {
+		t.Fatal(err)
+	}
+	drifted, err := h.DetectDrift()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 0 {
+		t.Fatalf("expected no drift, got %+v", drifted)
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	live := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas":        int64(2),
+			"defaulted":       "x",
+			"memory":          "2Gi",
+			"cpu":             "1",
+			"port":            float64(80),
+			"containers":      []interface{}{map[string]interface{}{"name": "a", "imagePullPolicy": "Always"}},
+			"differentLength": []interface{}{"a", "b"},
+		},
+	}
+	desired := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas":        int64(1),
+			"containers":      []interface{}{map[string]interface{}{"name": "a"}},
+			"differentLength": []interface{}{"a"},
+			"missing":         "y",
+			"omitted":         map[string]interface{}{},
+			"memory":          "2048Mi",
+			"cpu":             "1000m",
+			"port":            int64(80),
+		},
+	}
+	got := projectFields(live, desired)
+	want := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas":        int64(2),
+			"containers":      []interface{}{map[string]interface{}{"name": "a"}},
+			"differentLength": []interface{}{"a", "b"},
+			"omitted":         map[string]interface{}{},
+			"memory":          "2048Mi",
+			"cpu":             "1000m",
+			"port":            int64(80),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
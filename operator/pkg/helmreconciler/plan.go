@@ -0,0 +1,263 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helmreconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	errors2 "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/istio/operator/pkg/util"
+)
+
+// PlanAction is the action an install would take for a single object.
+type PlanAction string
+
+const (
+	PlanCreate PlanAction = "create"
+	PlanUpdate PlanAction = "update"
+	PlanPrune  PlanAction = "prune"
+	PlanNoop   PlanAction = "no-op"
+)
+
+// PlanEntry is the planned action for a single object.
+type PlanEntry struct {
+	Component string
+	// Object is the object hash, in the form Kind:Namespace:Name.
+	Object string
+	Action PlanAction
+	// Fields lists the changed fields of an updated object, in the form path: old -> new.
+	Fields []string
+}
+
+// Plan describes the changes an install would make to the cluster.
+type Plan struct {
+	Entries []PlanEntry
+}
+
+// Count returns the number of entries with the given action.
+func (p *Plan) Count(action PlanAction) int {
+	n := 0
+	for _, e := range p.Entries {
+		if e.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// String returns the plan grouped by action, followed by a summary.
+func (p *Plan) String() string {
+	var sb strings.Builder
+	for _, action := range []PlanAction{PlanCreate, PlanUpdate, PlanPrune, PlanNoop} {
+		if p.Count(action) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("Objects to %s:\n", action))
+		for _, e := range p.Entries {
+			if e.Action != action {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("  %s (%s)\n", e.Object, e.Component))
+			for _, f := range e.Fields {
+				sb.WriteString("      " + f + "\n")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fmt.Sprintf("Plan: %d to create, %d to update, %d to prune, %d unchanged.\n",
+		p.Count(PlanCreate), p.Count(PlanUpdate), p.Count(PlanPrune), p.Count(PlanNoop)))
+	return sb.String()
+}
+
+// Plan renders the manifests and compares each object with the live object in the cluster, without making any
+// changes. If server-side apply is available, updates are evaluated with a server-side dry run, so defaulting
+// and admission are taken into account. Objects that Prune would delete are included in the plan.
+func (h *HelmReconciler) Plan() (*Plan, error) {
+	manifestMap, err := h.RenderCharts()
+	if err != nil {
+		return nil, err
+	}
+	serverSideApply := h.CheckSSAEnabled()
+	plan := &Plan{}
+	var errs util.Errors
+	for cname, manifest := range manifestMap.Consolidated() {
+		objects, err := object.ParseK8sObjectsFromYAMLManifest(manifest)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			obju := obj.UnstructuredObject()
+			if err := h.applyLabelsAndAnnotations(obju, cname); err != nil {
+				return nil, err
+			}
+			entry, err := h.planObject(obju, serverSideApply)
+			if err != nil {
+				errs = util.AppendErr(errs, err)
+				continue
+			}
+			entry.Component = cname
+			entry.Object = obj.Hash()
+			plan.Entries = append(plan.Entries, entry)
+		}
+	}
+
+	err = h.runForAllTypes(func(labels map[string]string, objects *unstructured.UnstructuredList) error {
+		for cname, manifest := range manifestMap.Consolidated() {
+			for _, o := range h.prunableObjects(object.AllObjectHashes(manifest), labels, cname, objects, false) {
+				o := o
+				plan.Entries = append(plan.Entries, PlanEntry{
+					Component: cname,
+					Object:    object.NewK8sObject(&o, nil, nil).Hash(),
+					Action:    PlanPrune,
+				})
+			}
+		}
+		return nil
+	})
+	errs = util.AppendErr(errs, err)
+
+	sort.SliceStable(plan.Entries, func(i, j int) bool {
+		if plan.Entries[i].Component != plan.Entries[j].Component {
+			return plan.Entries[i].Component < plan.Entries[j].Component
+		}
+		return plan.Entries[i].Object < plan.Entries[j].Object
+	})
+	return plan, errs.ToError()
+}
+
+// planObject determines the action for a single rendered object.
+func (h *HelmReconciler) planObject(obj *unstructured.Unstructured, serverSideApply bool) (PlanEntry, error) {
+	objectStr := fmt.Sprintf("%s/%s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	err := h.client.Get(context.TODO(), client.ObjectKeyFromObject(obj), live)
+	// On a fresh cluster, the CRDs of custom resources are only created by the install itself.
+	if errors2.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return PlanEntry{Action: PlanCreate}, nil
+	}
+	if err != nil {
+		return PlanEntry{}, fmt.Errorf("failed to get %s: %v", objectStr, err)
+	}
+
+	desired := obj.DeepCopy()
+	if serverSideApply {
+		opts := []client.PatchOption{client.ForceOwnership, client.FieldOwner(fieldOwnerOperator), client.DryRunAll}
+		if err := h.client.Patch(context.TODO(), desired, client.Apply, opts...); err != nil {
+			return PlanEntry{}, fmt.Errorf("failed to dry run server-side apply for %s: %v", objectStr, err)
+		}
+	} else {
+		desired = live.DeepCopy()
+		if err := applyOverlay(desired, obj); err != nil {
+			return PlanEntry{}, err
+		}
+	}
+
+	fields := diffFields("", normalizeForPlan(live).Object, normalizeForPlan(desired).Object)
+	if len(fields) == 0 {
+		return PlanEntry{Action: PlanNoop}, nil
+	}
+	return PlanEntry{Action: PlanUpdate, Fields: fields}, nil
+}
+
+// normalizeForPlan removes server managed fields, which are not meaningful when comparing objects.
+func normalizeForPlan(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	obj = obj.DeepCopy()
+	for _, f := range []string{"managedFields", "resourceVersion", "uid", "creationTimestamp", "generation", "selfLink"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", f)
+	}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+	unstructured.RemoveNestedField(obj.Object, "status")
+	return obj
+}
+
+// diffFields returns the leaf fields that differ between a and b, sorted by path.
+func diffFields(path string, a, b interface{}) []string {
+	am, aIsMap := a.(map[string]interface{})
+	bm, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := map[string]struct{}{}
+		for k := range am {
+			keys[k] = struct{}{}
+		}
+		for k := range bm {
+			keys[k] = struct{}{}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		var res []string
+		for _, k := range sorted {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			res = append(res, diffFields(p, am[k], bm[k])...)
+		}
+		return res
+	}
+	al, aIsList := a.([]interface{})
+	bl, bIsList := b.([]interface{})
+	if aIsList && bIsList && len(al) == len(bl) {
+		var res []string
+		for i := range al {
+			res = append(res, diffFields(fmt.Sprintf("%s[%d]", path, i), al[i], bl[i])...)
+		}
+		return res
+	}
+	if reflect.DeepEqual(a, b) || (isEmptyValue(a) && isEmptyValue(b)) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %s -> %s", path, planValue(a), planValue(b))}
+}
+
+// isEmptyValue returns true for unset fields and empty maps or lists, which are equivalent in the API.
+func isEmptyValue(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	}
+	return false
+}
+
+func planValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,195 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helmreconciler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/operator/pkg/util"
+	"istio.io/istio/operator/pkg/util/clog"
+	"istio.io/istio/operator/pkg/util/progress"
+	"istio.io/istio/pkg/test/env"
+)
+
+func TestPlan(t *testing.T) {
+	iopStr, err := os.ReadFile(filepath.Join(env.IstioSrc, "manifests/profiles/default.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iop := &v1alpha1.IstioOperator{}
+	if err := util.UnmarshalWithJSONPB(string(iopStr), iop, false); err != nil {
+		t.Fatal(err)
+	}
+	iop.Spec.InstallPackagePath = filepath.Join(env.IstioSrc, "manifests")
+	h := &HelmReconciler{
+		client: fake.NewClientBuilder().Build(),
+		opts: &Options{
+			ProgressLog: progress.NewLog(),
+			Log:         clog.NewDefaultLogger(),
+		},
+		iop:           iop,
+		countLock:     &sync.Mutex{},
+		prunedKindSet: map[schema.GroupKind]struct{}{},
+	}
+
+	// Nothing is installed yet, so everything is created
+	plan, err := h.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Entries) == 0 || plan.Count(PlanCreate) != len(plan.Entries) {
+		t.Fatalf("expected all objects to be created, got %v", plan)
+	}
+
+	manifestMap, err := h.RenderCharts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyResourcesIntoCluster(t, h, manifestMap)
+
+	// Once installed, there is nothing to do
+	plan, err = h.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Count(PlanNoop) != len(plan.Entries) {
+		t.Fatalf("expected no changes, got %v", plan)
+	}
+
+	// Changes made in the cluster are reverted by the install
+	dep := &unstructured.Unstructured{}
+	dep.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	key := client.ObjectKey{Namespace: "istio-system", Name: "istiod"}
+	if err := h.client.Get(context.TODO(), key, dep); err != nil {
+		t.Fatal(err)
+	}
+	if err := unstructured.SetNestedField(dep.Object, "other", "metadata", "labels", "app"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.client.Update(context.TODO(), dep); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = h.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updates []PlanEntry
+	for _, e := range plan.Entries {
+		if e.Action == PlanUpdate {
+			updates = append(updates, e)
+		}
+	}
+	want := []PlanEntry{{
+		Component: "Pilot",
+		Object:    "Deployment:istio-system:istiod",
+		Action:    PlanUpdate,
+		Fields:    []string{"metadata.labels.app: other -> istiod"},
+	}}
+	if !reflect.DeepEqual(updates, want) {
+		t.Fatalf("got updates %+v, want %+v", updates, want)
+	}
+}
+
+// noCRDClient behaves like a cluster without the Istio CRDs installed.
+type noCRDClient struct {
+	client.Client
+}
+
+func (c noCRDClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if gvk := obj.GetObjectKind().GroupVersionKind(); gvk.Group == "networking.istio.io" {
+		return &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+	}
+	return c.Client.Get(ctx, key, obj)
+}
+
+func TestPlanWithoutCRDs(t *testing.T) {
+	iopStr, err := os.ReadFile(filepath.Join(env.IstioSrc, "manifests/profiles/default.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iop := &v1alpha1.IstioOperator{}
+	if err := util.UnmarshalWithJSONPB(string(iopStr), iop, false); err != nil {
+		t.Fatal(err)
+	}
+	iop.Spec.InstallPackagePath = filepath.Join(env.IstioSrc, "manifests")
+	h := &HelmReconciler{
+		client: noCRDClient{fake.NewClientBuilder().Build()},
+		opts: &Options{
+			ProgressLog: progress.NewLog(),
+			Log:         clog.NewDefaultLogger(),
+		},
+		iop:           iop,
+		countLock:     &sync.Mutex{},
+		prunedKindSet: map[schema.GroupKind]struct{}{},
+	}
+
+	// Custom resources of kinds unknown to the cluster are created along with their CRDs
+	plan, err := h.Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, e := range plan.Entries {
+		if e.Action != PlanCreate {
+			t.Fatalf("expected all objects to be created, got %+v", e)
+		}
+		if strings.HasPrefix(e.Object, "EnvoyFilter:") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected EnvoyFilters in the plan, got %v", plan)
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	a := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 1,
+			"ports":    []interface{}{map[string]interface{}{"port": 80}},
+			"removed":  "x",
+		},
+	}
+	b := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 2,
+			"ports":    []interface{}{map[string]interface{}{"port": 8080}},
+			"added":    map[string]interface{}{"a": "b"},
+		},
+	}
+	got := diffFields("", a, b)
+	want := []string{
+		`spec.added: <none> -> {"a":"b"}`,
+		"spec.ports[0].port: 80 -> 8080",
+		"spec.removed: x -> <none>",
+		"spec.replicas: 1 -> 2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -358,21 +358,10 @@ func (h *HelmReconciler) runForAllTypes(callback func(labels map[string]string,
 func (h *HelmReconciler) deleteResources(excluded map[string]bool, coreLabels map[string]string,
 	componentName string, objects *unstructured.UnstructuredList, all bool) error {
 	var errs util.Errors
-	labels := h.addComponentLabels(coreLabels, componentName)
-	selector := klabels.Set(labels).AsSelectorPreValidated()
-	for _, o := range objects.Items {
+	for _, o := range h.prunableObjects(excluded, coreLabels, componentName, objects, all) {
+		o := o
 		obj := object.NewK8sObject(&o, nil, nil)
 		oh := obj.Hash()
-		if !all {
-			// Label mismatch. Provided objects don't select against the component, so this likely means the object
-			// is for another component.
-			if !selector.Matches(klabels.Set(o.GetLabels())) {
-				continue
-			}
-			if excluded[oh] {
-				continue
-			}
-		}
 		if h.opts.DryRun {
 			h.opts.Log.LogAndPrintf("Not pruning object %s because of dry run.", oh)
 			continue
@@ -406,6 +395,30 @@ func (h *HelmReconciler) deleteResources(excluded map[string]bool, coreLabels ma
 	return errs.ToError()
 }
 
+// prunableObjects returns the objects from the given component that are not in the excluded map. Resource labels are
+// used to identify the resources belonging to the component. If all is set, all objects are returned.
+func (h *HelmReconciler) prunableObjects(excluded map[string]bool, coreLabels map[string]string,
+	componentName string, objects *unstructured.UnstructuredList, all bool) []unstructured.Unstructured {
+	if all {
+		return objects.Items
+	}
+	var res []unstructured.Unstructured
+	labels := h.addComponentLabels(coreLabels, componentName)
+	selector := klabels.Set(labels).AsSelectorPreValidated()
+	for _, o := range objects.Items {
+		// Label mismatch. Provided objects don't select against the component, so this likely means the object
+		// is for another component.
+		if !selector.Matches(klabels.Set(o.GetLabels())) {
+			continue
+		}
+		if excluded[object.NewK8sObject(&o, nil, nil).Hash()] {
+			continue
+		}
+		res = append(res, o)
+	}
+	return res
+}
+
 // RemoveObject removes object with objHash in componentName from the object cache.
 func (h *HelmReconciler) removeFromObjectCache(componentName, objHash string) {
 	crHash, err := h.getCRHash(componentName)
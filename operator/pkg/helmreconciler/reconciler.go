@@ -376,6 +376,7 @@ func setStatus(s map[string]*v1alpha1.InstallStatus_VersionStatus, componentName
 // - If one or more components are UPDATING and others are HEALTHY, overall status is UPDATING.
 // - If components are a mix of RECONCILING, UPDATING and HEALTHY, overall status is UPDATING.
 // - If any component is in ERROR state, overall status is ERROR.
+// - If one or more components are ACTION_REQUIRED and others are HEALTHY, overall status is ACTION_REQUIRED.
 func overallStatus(componentStatus map[string]*v1alpha1.InstallStatus_VersionStatus) v1alpha1.InstallStatus_Status {
 	ret := v1alpha1.InstallStatus_HEALTHY
 	for _, cs := range componentStatus {
@@ -388,6 +389,8 @@ func overallStatus(componentStatus map[string]*v1alpha1.InstallStatus_VersionSta
 		} else if cs.Status == v1alpha1.InstallStatus_RECONCILING {
 			ret = v1alpha1.InstallStatus_RECONCILING
 			break
+		} else if cs.Status == v1alpha1.InstallStatus_ACTION_REQUIRED {
+			ret = v1alpha1.InstallStatus_ACTION_REQUIRED
 		}
 	}
 	return ret
@@ -203,6 +203,21 @@ var (
 		"cache_flush_total",
 		"number of times operator cache was flushed",
 	)
+
+	// DriftedResourceTotal indicates the number of resources of a component
+	// that differed from the rendered manifest in the last drift check.
+	DriftedResourceTotal = monitoring.NewGauge(
+		"drifted_resource_total",
+		"Number of resources that differ from the rendered manifest",
+		monitoring.WithLabels(ComponentNameLabel),
+	)
+
+	// DriftRevertTotal counts the drifted resources reverted by the operator.
+	DriftRevertTotal = monitoring.NewSum(
+		"drift_revert_total",
+		"Number of drifted resources reverted by the operator",
+		monitoring.WithLabels(ResourceKindLabel),
+	)
 )
 
 func init() {
@@ -225,6 +240,9 @@ func init() {
 		ManifestRenderErrorTotal,
 		LegacyPathTranslationTotal,
 		CacheFlushTotal,
+
+		DriftedResourceTotal,
+		DriftRevertTotal,
 	)
 
 	initOperatorCrdResourceMetrics()
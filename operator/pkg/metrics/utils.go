@@ -56,3 +56,19 @@ func CountManifestRender(name name.ComponentName) {
 		With(ComponentNameLabel.Value(string(name))).
 		Increment()
 }
+
+// RecordDriftedResources records the number of drifted
+// resources found for a component in the last drift check.
+func RecordDriftedResources(cn string, count int) {
+	DriftedResourceTotal.
+		With(ComponentNameLabel.Value(cn)).
+		Record(float64(count))
+}
+
+// CountDriftRevert increments the count of reverted
+// drifted resources by kind.
+func CountDriftRevert(kind string) {
+	DriftRevertTotal.
+		With(ResourceKindLabel.Value(kind)).
+		Increment()
+}
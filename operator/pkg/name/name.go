@@ -125,6 +125,14 @@ var (
 		IstiodRemoteComponentName,
 	}
 
+	// KustomizeComponentNames are the components whose spec may contain kustomize-style resources and patches.
+	// The spec of these components is validated, and patches are not applied to any other component.
+	KustomizeComponentNames = []ComponentName{
+		PilotComponentName,
+		CNIComponentName,
+		IstiodRemoteComponentName,
+	}
+
 	// AllComponentNames is a list of all Istio components.
 	AllComponentNames = append(AllCoreComponentNames, IngressComponentName, EgressComponentName,
 		IstioOperatorComponentName, IstioOperatorCustomResourceName)
@@ -202,6 +210,16 @@ func (cn ComponentName) IsGateway() bool {
 	return cn == IngressComponentName || cn == EgressComponentName
 }
 
+// SupportsKustomize reports whether the spec of the component may contain kustomize-style resources and patches.
+func (cn ComponentName) SupportsKustomize() bool {
+	for _, c := range KustomizeComponentNames {
+		if cn == c {
+			return true
+		}
+	}
+	return false
+}
+
 // Namespace returns the namespace for the component. It follows these rules:
 // 1. If DefaultNamespace is unset, log and error and return the empty string.
 // 2. If the feature and component namespaces are unset, return DefaultNamespace.
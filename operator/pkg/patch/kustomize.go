@@ -0,0 +1,335 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"istio.io/istio/operator/pkg/metrics"
+	"istio.io/istio/operator/pkg/object"
+	"istio.io/istio/operator/pkg/util"
+)
+
+// KustomizeSpec is a kustomize-style customization of a component manifest. It is set in the spec field of a
+// component, for example:
+//
+// components:
+//   pilot:
+//     spec:
+//       resources:
+//       - apiVersion: v1
+//         kind: ConfigMap
+//         metadata:
+//           name: extra
+//           namespace: istio-system
+//       patchesStrategicMerge:
+//       - apiVersion: apps/v1
+//         kind: Deployment
+//         metadata:
+//           name: istiod
+//         spec:
+//           template:
+//             spec:
+//               containers:
+//               - name: discovery
+//                 imagePullPolicy: Always
+//       patchesJson6902:
+//       - target:
+//           kind: Deployment
+//           name: istiod
+//         patch:
+//         - op: add
+//           path: /spec/template/metadata/labels/foo
+//           value: bar
+//
+// Resources are added to the manifest first, so they can be patched as well. Strategic merge patches are applied
+// before JSON patches. Only the components in name.KustomizeComponentNames are patched; the spec of gateway
+// components is not used.
+type KustomizeSpec struct {
+	// Resources are added to the component manifest as given.
+	Resources []map[string]interface{} `json:"resources,omitempty"`
+	// PatchesStrategicMerge are strategic merge patches. The patched object is identified by the apiVersion, kind,
+	// name and namespace of the patch. If the namespace is not set, the component namespace is used.
+	PatchesStrategicMerge []map[string]interface{} `json:"patchesStrategicMerge,omitempty"`
+	// PatchesJSON6902 are JSON patches as defined in RFC 6902.
+	PatchesJSON6902 []*JSON6902Patch `json:"patchesJson6902,omitempty"`
+}
+
+// JSON6902Patch is a list of RFC 6902 operations applied to the target object.
+type JSON6902Patch struct {
+	Target *PatchTarget             `json:"target,omitempty"`
+	Patch  []map[string]interface{} `json:"patch,omitempty"`
+}
+
+// PatchTarget selects the object a JSON patch is applied to. If the namespace is not set, the component namespace
+// is used.
+type PatchTarget struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+var json6902Ops = map[string]bool{"add": true, "remove": true, "replace": true, "move": true, "copy": true, "test": true}
+
+// ParseKustomizeSpec parses the spec field of a component. It returns nil if spec is not set. Fields that are not part
+// of KustomizeSpec are ignored, see UnknownKustomizeFields.
+func ParseKustomizeSpec(spec interface{}) (*KustomizeSpec, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	js, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	ks := &KustomizeSpec{}
+	if err := json.Unmarshal(js, ks); err != nil {
+		return nil, fmt.Errorf("invalid component spec: %v", err)
+	}
+	return ks, nil
+}
+
+var (
+	kustomizeFields = map[string]bool{"resources": true, "patchesStrategicMerge": true, "patchesJson6902": true}
+	json6902Fields  = map[string]bool{"target": true, "patch": true}
+	targetFields    = map[string]bool{"group": true, "version": true, "kind": true, "name": true, "namespace": true}
+)
+
+// UnknownKustomizeFields returns the paths of the fields of a component spec that are not part of KustomizeSpec.
+// The spec field could hold arbitrary values before it was used for patches, so these fields are reported as
+// warnings rather than rejected.
+func UnknownKustomizeFields(spec interface{}) []string {
+	js, err := json.Marshal(spec)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(js, &m); err != nil {
+		// Not an object, rejected by ParseKustomizeSpec
+		return nil
+	}
+	out := unknownFields(m, kustomizeFields, "")
+	patches, _ := m["patchesJson6902"].([]interface{})
+	for i, p := range patches {
+		pm, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		prefix := fmt.Sprintf("patchesJson6902[%d].", i)
+		out = append(out, unknownFields(pm, json6902Fields, prefix)...)
+		if target, ok := pm["target"].(map[string]interface{}); ok {
+			out = append(out, unknownFields(target, targetFields, prefix+"target.")...)
+		}
+	}
+	return out
+}
+
+func unknownFields(m map[string]interface{}, known map[string]bool, prefix string) []string {
+	var out []string
+	for k := range m {
+		if !known[k] {
+			out = append(out, prefix+k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Validate checks that every resource and patch identifies an object, and that JSON patches are well formed.
+func (ks *KustomizeSpec) Validate() (errs util.Errors) {
+	if ks == nil {
+		return nil
+	}
+	for i, r := range ks.Resources {
+		u := &unstructured.Unstructured{Object: r}
+		if u.GetAPIVersion() == "" || u.GetKind() == "" || u.GetName() == "" {
+			errs = util.AppendErr(errs, fmt.Errorf("resources[%d]: apiVersion, kind and metadata.name must be set", i))
+		}
+	}
+	for i, p := range ks.PatchesStrategicMerge {
+		u := &unstructured.Unstructured{Object: p}
+		if u.GetKind() == "" || u.GetName() == "" {
+			errs = util.AppendErr(errs, fmt.Errorf("patchesStrategicMerge[%d]: kind and metadata.name must be set", i))
+		}
+	}
+	for i, p := range ks.PatchesJSON6902 {
+		if p.Target == nil || p.Target.Kind == "" || p.Target.Name == "" {
+			errs = util.AppendErr(errs, fmt.Errorf("patchesJson6902[%d]: target kind and name must be set", i))
+		}
+		if len(p.Patch) == 0 {
+			errs = util.AppendErr(errs, fmt.Errorf("patchesJson6902[%d]: patch must not be empty", i))
+		}
+		for j, op := range p.Patch {
+			o, _ := op["op"].(string)
+			if !json6902Ops[o] {
+				errs = util.AppendErr(errs, fmt.Errorf("patchesJson6902[%d].patch[%d]: invalid op %q", i, j, o))
+			}
+			if path, _ := op["path"].(string); !strings.HasPrefix(path, "/") {
+				errs = util.AppendErr(errs, fmt.Errorf("patchesJson6902[%d].patch[%d]: path must be a JSON pointer", i, j))
+			}
+		}
+	}
+	return errs
+}
+
+// KustomizeManifest adds the resources of ks to the manifest and applies its patches. Each patch must match exactly
+// one object. It returns the resulting manifest YAML.
+func KustomizeManifest(baseYAML string, defaultNamespace string, ks *KustomizeSpec) (string, error) {
+	if ks == nil {
+		return baseYAML, nil
+	}
+	if errs := ks.Validate(); len(errs) != 0 {
+		return "", errs.ToError()
+	}
+	objs, err := object.ParseK8sObjectsFromYAMLManifest(baseYAML)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range ks.Resources {
+		objs = append(objs, object.NewK8sObject(&unstructured.Unstructured{Object: r}, nil, nil))
+	}
+
+	var errs util.Errors
+	for i, p := range ks.PatchesStrategicMerge {
+		u := &unstructured.Unstructured{Object: p}
+		gvk := u.GroupVersionKind()
+		idx, err := matchObject(objs, gvk.Group, gvk.Version, gvk.Kind, u.GetName(), u.GetNamespace(), defaultNamespace)
+		if err != nil {
+			errs = util.AppendErr(errs, fmt.Errorf("patchesStrategicMerge[%d]: %v", i, err))
+			continue
+		}
+		patched, err := strategicMergeObject(objs[idx], p)
+		if err != nil {
+			metrics.ManifestPatchErrorTotal.Increment()
+			errs = util.AppendErr(errs, fmt.Errorf("patchesStrategicMerge[%d]: %v", i, err))
+			continue
+		}
+		objs[idx] = patched
+	}
+	for i, p := range ks.PatchesJSON6902 {
+		t := p.Target
+		idx, err := matchObject(objs, t.Group, t.Version, t.Kind, t.Name, t.Namespace, defaultNamespace)
+		if err != nil {
+			errs = util.AppendErr(errs, fmt.Errorf("patchesJson6902[%d]: %v", i, err))
+			continue
+		}
+		patched, err := jsonPatchObject(objs[idx], p.Patch)
+		if err != nil {
+			metrics.ManifestPatchErrorTotal.Increment()
+			errs = util.AppendErr(errs, fmt.Errorf("patchesJson6902[%d]: %v", i, err))
+			continue
+		}
+		objs[idx] = patched
+	}
+	if len(errs) != 0 {
+		return "", errs.ToError()
+	}
+	return objs.YAMLManifest()
+}
+
+// matchObject returns the index of the single object matching the given fields. Empty group and version match any
+// group and version. An empty namespace matches objects in the default namespace and cluster scoped objects.
+func matchObject(objs object.K8sObjects, group, version, kind, name, namespace, defaultNamespace string) (int, error) {
+	match := -1
+	for i, o := range objs {
+		gvk := o.GroupVersionKind()
+		if o.Kind != kind || o.Name != name || (group != "" && gvk.Group != group) || (version != "" && gvk.Version != version) {
+			continue
+		}
+		if namespace != "" && o.Namespace != namespace {
+			continue
+		}
+		if namespace == "" && o.Namespace != defaultNamespace && o.Namespace != "" {
+			continue
+		}
+		if match != -1 {
+			return 0, fmt.Errorf("%s matches multiple objects in output manifest", object.Hash(kind, namespace, name))
+		}
+		match = i
+	}
+	if match == -1 {
+		return 0, fmt.Errorf("%s does not match any object in output manifest. Available objects are:\n%s",
+			object.Hash(kind, namespace, name), strings.Join(objs.Keys(), "\n"))
+	}
+	return match, nil
+}
+
+// strategicMergeObject applies a strategic merge patch to obj. Types unknown to the Kubernetes scheme, such as
+// custom resources, are patched with a JSON merge patch.
+func strategicMergeObject(obj *object.K8sObject, patch map[string]interface{}) (*object.K8sObject, error) {
+	base, err := obj.JSON()
+	if err != nil {
+		return nil, err
+	}
+	pj, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	var merged []byte
+	if versionedObject, err := scheme.Scheme.New(obj.GroupVersionKind()); err == nil {
+		merged, err = strategicpatch.StrategicMergePatch(base, pj, versionedObject)
+		if err != nil {
+			return nil, fmt.Errorf("strategic merge patch of %s: %v", obj.Hash(), err)
+		}
+	} else {
+		merged, err = jsonpatch.MergePatch(base, pj)
+		if err != nil {
+			return nil, fmt.Errorf("merge patch of %s: %v", obj.Hash(), err)
+		}
+	}
+	return parsePatchedObject(obj, merged)
+}
+
+// jsonPatchObject applies RFC 6902 operations to obj.
+func jsonPatchObject(obj *object.K8sObject, ops []map[string]interface{}) (*object.K8sObject, error) {
+	base, err := obj.JSON()
+	if err != nil {
+		return nil, err
+	}
+	pj, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+	jp, err := jsonpatch.DecodePatch(pj)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := jp.Apply(base)
+	if err != nil {
+		return nil, fmt.Errorf("json patch of %s: %v", obj.Hash(), err)
+	}
+	return parsePatchedObject(obj, patched)
+}
+
+// parsePatchedObject parses a patched object, which must keep the identity of the original object.
+func parsePatchedObject(orig *object.K8sObject, patched []byte) (*object.K8sObject, error) {
+	o, err := object.ParseJSONToK8sObject(patched)
+	if err != nil {
+		return nil, err
+	}
+	if o.Hash() != orig.Hash() || o.Group != orig.Group {
+		return nil, fmt.Errorf("patch must not change the kind, name or namespace of %s", orig.Hash())
+	}
+	return o, nil
+}
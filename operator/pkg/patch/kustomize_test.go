@@ -0,0 +1,210 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/operator/pkg/compare"
+)
+
+func TestKustomizeManifest(t *testing.T) {
+	base := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: pilot
+      - name: other
+        image: other
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: istiod
+  namespace: istio-system
+`
+	tests := []struct {
+		desc    string
+		spec    string
+		want    string
+		wantErr string
+	}{
+		{
+			desc: "strategic merge patches merge lists by key",
+			spec: `
+patchesStrategicMerge:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: istiod
+  spec:
+    template:
+      spec:
+        containers:
+        - name: discovery
+          imagePullPolicy: Always
+`,
+			want: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: pilot
+        imagePullPolicy: Always
+      - name: other
+        image: other
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: istiod
+  namespace: istio-system
+`,
+		},
+		{
+			desc: "json patches and added resources",
+			spec: `
+resources:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: extra
+    namespace: istio-system
+  data:
+    a: b
+patchesJson6902:
+- target:
+    kind: Deployment
+    name: istiod
+  patch:
+  - op: remove
+    path: /spec/replicas
+  - op: replace
+    path: /spec/template/spec/containers/1/image
+    value: replaced
+- target:
+    version: v1
+    kind: ConfigMap
+    name: extra
+  patch:
+  - op: add
+    path: /data/c
+    value: d
+`,
+			want: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+spec:
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: pilot
+      - name: other
+        image: replaced
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: istiod
+  namespace: istio-system
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: extra
+  namespace: istio-system
+data:
+  a: b
+  c: d
+`,
+		},
+		{
+			desc: "patch without match",
+			spec: `
+patchesStrategicMerge:
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: missing
+`,
+			wantErr: "patchesStrategicMerge[0]: Deployment::missing does not match any object in output manifest",
+		},
+		{
+			desc: "patch changing the object name",
+			spec: `
+patchesJson6902:
+- target:
+    kind: Service
+    name: istiod
+  patch:
+  - op: replace
+    path: /metadata/name
+    value: renamed
+`,
+			wantErr: "patchesJson6902[0]: patch must not change the kind, name or namespace of Service:istio-system:istiod",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var spec interface{}
+			if err := yaml.Unmarshal([]byte(tt.spec), &spec); err != nil {
+				t.Fatal(err)
+			}
+			ks, err := ParseKustomizeSpec(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := KustomizeManifest(base, "istio-system", ks)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff, err := compare.ManifestDiff(got, tt.want, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff != "" {
+				t.Errorf("got:\n%s\nwant:\n%s\ndiff:\n%s", got, tt.want, diff)
+			}
+		})
+	}
+}
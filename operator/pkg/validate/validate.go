@@ -23,6 +23,8 @@ import (
 	"istio.io/api/operator/v1alpha1"
 	operator_v1alpha1 "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
 	"istio.io/istio/operator/pkg/metrics"
+	"istio.io/istio/operator/pkg/name"
+	"istio.io/istio/operator/pkg/patch"
 	"istio.io/istio/operator/pkg/util"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/mesh"
@@ -214,3 +216,21 @@ func validateGatewayName(path util.Path, val interface{}) util.Errors {
 	}
 	return validateWithRegex(path, val, ObjectNameRegexp)
 }
+
+func init() {
+	for _, c := range name.KustomizeComponentNames {
+		DefaultValidations[fmt.Sprintf("Components.%s.Spec", c)] = validateComponentSpec
+	}
+}
+
+// validateComponentSpec validates the kustomize-style resources and patches in the spec of a component.
+func validateComponentSpec(path util.Path, val interface{}) (errs util.Errors) {
+	ks, err := patch.ParseKustomizeSpec(val)
+	if err != nil {
+		return util.NewErrs(fmt.Errorf("%s: %v", path, err))
+	}
+	for _, err := range ks.Validate() {
+		errs = util.AppendErr(errs, fmt.Errorf("%s.%v", path, err))
+	}
+	return errs
+}
@@ -15,9 +15,11 @@
 package validate
 
 import (
+	"fmt"
 	"testing"
 
 	"istio.io/api/operator/v1alpha1"
+	"istio.io/istio/operator/pkg/name"
 	"istio.io/istio/operator/pkg/util"
 )
 
@@ -94,6 +96,67 @@ components:
 `,
 			wantErrs: makeErrors([]string{`invalid value Components.IngressGateways[0].Name: istio@ingress-1`}),
 		},
+		{
+			desc: "GoodComponentSpec",
+			yamlStr: `
+components:
+  pilot:
+    spec:
+      patchesStrategicMerge:
+      - apiVersion: apps/v1
+        kind: Deployment
+        metadata:
+          name: istiod
+      patchesJson6902:
+      - target:
+          kind: Deployment
+          name: istiod
+        patch:
+        - op: remove
+          path: /spec/replicas
+`,
+		},
+		{
+			desc: "BadComponentSpec",
+			yamlStr: `
+components:
+  pilot:
+    spec:
+      resources:
+      - kind: ConfigMap
+      patchesJson6902:
+      - target:
+          kind: Deployment
+          name: istiod
+        patch:
+        - op: delete
+          path: spec.replicas
+`,
+			wantErrs: makeErrors([]string{
+				`Components.Pilot.Spec.resources[0]: apiVersion, kind and metadata.name must be set`,
+				`Components.Pilot.Spec.patchesJson6902[0].patch[0]: invalid op "delete"`,
+				`Components.Pilot.Spec.patchesJson6902[0].patch[0]: path must be a JSON pointer`,
+			}),
+		},
+		{
+			desc: "UnknownComponentSpecField",
+			yamlStr: `
+components:
+  cni:
+    spec:
+      patches: []
+`,
+		},
+		{
+			desc: "InvalidComponentSpecField",
+			yamlStr: `
+components:
+  cni:
+    spec:
+      resources: {}
+`,
+			wantErrs: makeErrors([]string{`Components.Cni.Spec: invalid component spec: json: cannot unmarshal object into Go struct field KustomizeSpec.resources of type []map[string]interface {}`}),
+		},
 		{
 			desc: "BadValuesIP",
 			yamlStr: `
@@ -180,3 +243,16 @@ meshConfig:
 		})
 	}
 }
+
+// TestComponentSpecValidations ensures patches are only applied to components whose spec is validated.
+func TestComponentSpecValidations(t *testing.T) {
+	for _, c := range name.AllComponentNames {
+		_, validated := DefaultValidations[fmt.Sprintf("Components.%s.Spec", c)]
+		if validated != c.SupportsKustomize() {
+			t.Errorf("component %s: spec validated %v, but supports patches %v", c, validated, c.SupportsKustomize())
+		}
+	}
+	if name.IstioBaseComponentName.SupportsKustomize() {
+		t.Errorf("expected Base not to support patches, as its spec cannot be validated")
+	}
+}
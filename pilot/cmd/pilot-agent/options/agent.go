@@ -51,6 +51,9 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		DNSAddr:                   DNSCaptureAddr.Get(),
 		ProxyNamespace:            PodNamespaceVar.Get(),
 		ProxyDomain:               proxy.DNSDomain,
+		WaitForApplicationExit:    waitForApplicationExitEnv,
+		ApplicationExitTimeout:    applicationExitTimeoutEnv,
+		ExitWithApplication:       exitWithApplicationEnv,
 	}
 	extractXDSHeadersFromEnv(o)
 	return o
@@ -112,6 +112,19 @@ var (
 	disableEnvoyEnv = env.RegisterBoolVar("DISABLE_ENVOY", false,
 		"Disables all Envoy agent features.").Get()
 
+	waitForApplicationExitEnv = env.RegisterBoolVar("WAIT_FOR_APPLICATION_EXIT", false,
+		"If set to true, the agent keeps the proxy serving on termination until all other containers of the pod "+
+			"have exited, and only drains it afterwards. Requires shareProcessNamespace to be enabled for the pod.").Get()
+
+	// The default leaves time for the default 5s termination drain within the default 30s termination grace period.
+	applicationExitTimeoutEnv = env.RegisterDurationVar("APPLICATION_EXIT_TIMEOUT", 20*time.Second,
+		"The maximum time to wait for the application to exit on termination, if WAIT_FOR_APPLICATION_EXIT is set. "+
+			"Together with the termination drain duration, it should be below the termination grace period of the pod.").Get()
+
+	exitWithApplicationEnv = env.RegisterBoolVar("EXIT_ON_APPLICATION_EXIT", false,
+		"If set to true along with WAIT_FOR_APPLICATION_EXIT, the agent drains and terminates the proxy once all other "+
+			"containers of the pod have exited, without waiting for a termination signal. Intended for Jobs.").Get()
+
 	// certSigner is cert signer for workload cert
 	certSigner = env.RegisterStringVar("ISTIO_META_CERT_SIGNER", "",
 		"The cert signer info for workload cert")
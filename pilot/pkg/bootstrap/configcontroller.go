@@ -174,6 +174,22 @@ func (s *Server) initK8SConfigStore(args *PilotArgs) error {
 				Run(stop)
 			return nil
 		})
+		if features.EnableGatewayAPIDeploymentController {
+			s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+				leaderelection.
+					NewLeaderElection(args.Namespace, args.PodName, leaderelection.GatewayDeploymentController, s.kubeClient.Kube()).
+					AddRunFunction(func(leaderStop <-chan struct{}) {
+						log.Infof("Starting gateway deployment controller")
+						dc := gateway.NewDeploymentController(s.kubeClient, args.Revision)
+						// Start informers again, as they are created only after acquiring the leader lock.
+						// Note: stop here should be the overall pilot stop, NOT the leader election stop.
+						s.kubeClient.RunAndWait(stop)
+						dc.Run(leaderStop)
+					}).
+					Run(stop)
+				return nil
+			})
+		}
 	}
 	if features.EnableAnalysis {
 		if err := s.initInprocessAnalysisController(args); err != nil {
@@ -185,6 +201,9 @@ func (s *Server) initK8SConfigStore(args *PilotArgs) error {
 		return err
 	}
 	s.XDSServer.WorkloadEntryController = workloadentry.NewController(configController, args.PodName, args.KeepaliveOptions.MaxServerConnectionAge)
+	if s.membership != nil {
+		s.XDSServer.WorkloadEntryController.SetOwnership(s.membership.Owns)
+	}
 	return nil
 }
 
@@ -315,7 +334,18 @@ func (s *Server) initStatusController(args *PilotArgs, writeStatus bool) {
 		return nil
 	})
 	s.XDSServer.StatusReporter = s.statusReporter
-	if writeStatus {
+	if writeStatus && s.membership != nil {
+		// Every replica writes the status of the config it owns.
+		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+			controller := status.NewController(s.kubeClient.RESTConfig(), args.Namespace, s.RWConfigStore)
+			controller.Owns = func(config status.Resource) bool {
+				return s.membership.Owns(config.Resource + "/" + config.Namespace + "/" + config.Name)
+			}
+			s.statusReporter.SetController(controller)
+			controller.Start(stop)
+			return nil
+		})
+	} else if writeStatus {
 		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
 			leaderelection.
 				NewLeaderElection(args.Namespace, args.PodName, leaderelection.StatusController, s.kubeClient).
@@ -44,6 +44,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
 	"istio.io/istio/pilot/pkg/keycertbundle"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/plugin"
 	kubesecrets "istio.io/istio/pilot/pkg/secrets/kube"
@@ -117,6 +118,7 @@ type Server struct {
 	kubeClient kubelib.Client
 
 	multicluster      *kubecontroller.Multicluster
+	membership        *leaderelection.Membership
 	secretsController *kubesecrets.Multicluster
 
 	configController  model.ConfigStoreCache
@@ -260,6 +262,8 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 		return nil, err
 	}
 
+	s.initMembership(args)
+
 	if err := s.initControllers(args); err != nil {
 		return nil, err
 	}
@@ -543,6 +547,19 @@ func (s *Server) initSDSServer(args *PilotArgs) {
 	}
 }
 
+// initMembership registers this istiod with the replicas sharding background work, if enabled.
+func (s *Server) initMembership(args *PilotArgs) {
+	if !features.EnableReplicaSharding || s.kubeClient == nil {
+		return
+	}
+	s.membership = leaderelection.NewMembership(args.Namespace, args.PodName, leaderelection.ShardingGroup, s.kubeClient)
+	s.XDSServer.Membership = s.membership
+	s.addStartFunc(func(stop <-chan struct{}) error {
+		go s.membership.Run(stop)
+		return nil
+	})
+}
+
 // initKubeClient creates the k8s client if running in an k8s environment.
 // This is determined by the presence of a kube registry, which
 // uses in-context k8s, or a config source of type k8s.
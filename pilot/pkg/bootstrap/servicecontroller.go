@@ -81,6 +81,7 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 	args.RegistryOptions.KubeOptions.NetworksWatcher = s.environment.NetworksWatcher
 	args.RegistryOptions.KubeOptions.MeshWatcher = s.environment.Watcher
 	args.RegistryOptions.KubeOptions.SystemNamespace = args.Namespace
+	args.RegistryOptions.KubeOptions.Membership = s.membership
 
 	mc := kubecontroller.NewMulticluster(args.PodName,
 		s.kubeClient,
@@ -115,7 +116,8 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 
 	// start remote cluster controllers
 	s.addStartFunc(func(stop <-chan struct{}) error {
-		s.XDSServer.ListRemoteClusters = mc.InitSecretController(stop).ListRemoteClusters
+		mc.InitSecretController(stop)
+		s.XDSServer.ListRemoteClusters = mc.ListRemoteClusters
 		return nil
 	})
 
@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pkg/kube/inject"
 	"istio.io/istio/pkg/webhooks"
 	"istio.io/pkg/env"
@@ -107,6 +108,28 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 		wh.Run(stop)
 		return nil
 	})
+	if features.EnableStaleSidecarDetection && s.kubeClient != nil {
+		electionID := leaderelection.StaleSidecarController
+		if args.Revision != "" && args.Revision != "default" {
+			electionID += "-" + args.Revision
+		}
+		s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+			leaderelection.
+				NewLeaderElection(args.Namespace, args.PodName, electionID, s.kubeClient.Kube()).
+				AddRunFunction(func(leaderStop <-chan struct{}) {
+					log.Infof("Starting stale sidecar reconciler")
+					r := inject.NewStaleSidecarReconciler(wh, s.kubeClient, inject.StaleSidecarOptions{
+						Interval:          features.StaleSidecarCheckInterval,
+						RestartsPerMinute: features.StaleSidecarRestartsPerMinute,
+					})
+					// Start informers again, as they are created only after acquiring the leader lock.
+					s.kubeClient.RunAndWait(stop)
+					r.Run(leaderStop)
+				}).
+				Run(stop)
+			return nil
+		})
+	}
 	return wh, nil
 }
 
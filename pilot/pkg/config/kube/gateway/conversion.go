@@ -26,6 +26,7 @@ import (
 	k8s "sigs.k8s.io/gateway-api/apis/v1alpha1"
 
 	istio "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/model/credentials"
 	"istio.io/istio/pilot/pkg/model/kstatus"
@@ -994,10 +995,15 @@ func convertGateways(r *KubernetesResources) ([]config.Config, map[RouteKey][]ga
 			gatewayServices = append(gatewayServices, fqdn)
 		}
 		if len(kgw.Addresses) == 0 {
-			// If nothing is defined, setup a default
-			// TODO: set default in GatewayClass instead.
-			// Maybe we only have a default when obj.Namespace == SystemNamespace
-			gatewayServices = []string{fmt.Sprintf("istio-ingressgateway.%s.svc.%s", obj.Namespace, r.Domain)}
+			if features.EnableGatewayAPIDeploymentController {
+				// The deployment controller provisions a Service with the same name as the Gateway
+				gatewayServices = []string{fmt.Sprintf("%s.%s.svc.%s", obj.Name, obj.Namespace, r.Domain)}
+			} else {
+				// If nothing is defined, setup a default
+				// TODO: set default in GatewayClass instead.
+				// Maybe we only have a default when obj.Namespace == SystemNamespace
+				gatewayServices = []string{fmt.Sprintf("istio-ingressgateway.%s.svc.%s", obj.Namespace, r.Domain)}
+			}
 		}
 		for i, l := range kgw.Listeners {
 			server, ok := buildListener(obj, l, i)
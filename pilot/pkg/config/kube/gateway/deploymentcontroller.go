@@ -0,0 +1,548 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	// allow embedding the default deployment template
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	k8s "sigs.k8s.io/gateway-api/apis/v1alpha1"
+	gatewaylister "sigs.k8s.io/gateway-api/pkg/client/listers/apis/v1alpha1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/queue"
+)
+
+const (
+	// GatewayNameLabel is set on the pods of a provisioned gateway, and selected by the Service.
+	GatewayNameLabel = "istio.io/gateway-name"
+	// ManagedGatewayLabel marks resources that are provisioned and owned by the deployment controller.
+	ManagedGatewayLabel = "gateway.istio.io/managed"
+	// ManagedGatewayController is the value of ManagedGatewayLabel for resources we manage.
+	ManagedGatewayController = "istio.io-gateway-controller"
+	// ServiceTypeAnnotation allows overriding the type of the provisioned Service.
+	ServiceTypeAnnotation = "networking.istio.io/service-type"
+	// injectTemplatesAnnotation selects the injection templates applied to the gateway pods.
+	injectTemplatesAnnotation = "inject.istio.io/templates"
+)
+
+// podAnnotationPrefixes are the prefixes of the Gateway annotations that are copied to the gateway pods.
+// Other annotations, such as kubectl.kubernetes.io/last-applied-configuration, are meant for the Gateway only.
+var podAnnotationPrefixes = []string{
+	"inject.istio.io/",
+	"proxy.istio.io/",
+	"sidecar.istio.io/",
+	"traffic.sidecar.istio.io/",
+	"prometheus.io/",
+}
+
+//go:embed templates/deployment.yaml
+var deploymentTemplate string
+
+// DeploymentController provisions a Deployment, Service and ServiceAccount for each Gateway
+// that is handled by Istio but does not specify any address to bind to. The generated resources
+// are owned by the Gateway, so Kubernetes garbage collection removes them when the Gateway is deleted.
+//
+// The resources are rendered from a template in the same format as the injection templates,
+// with the Gateway exposed as the input values.
+type DeploymentController struct {
+	client          kube.Client
+	queue           queue.Instance
+	template        *template.Template
+	revision        string
+	gatewayLister   gatewaylister.GatewayLister
+	gatewayInformer cache.SharedIndexInformer
+	classLister     gatewaylister.GatewayClassLister
+	classInformer   cache.SharedIndexInformer
+	informers       []cache.SharedIndexInformer
+}
+
+// deploymentInput is the data passed to the deployment template.
+type deploymentInput struct {
+	Name             string
+	Namespace        string
+	UID              types.UID
+	OwnerAPIVersion  string
+	ServiceAccount   string
+	Revision         string
+	ServiceType      corev1.ServiceType
+	GatewayNameLabel string
+	Labels           map[string]string
+	PodLabels        map[string]string
+	Annotations      map[string]string
+	Ports            []corev1.ServicePort
+}
+
+// NewDeploymentController constructs a DeploymentController using the default deployment template.
+func NewDeploymentController(client kube.Client, revision string) *DeploymentController {
+	tmpl, err := parseDeploymentTemplate(deploymentTemplate)
+	if err != nil {
+		// The template is compiled into the binary, so this can only happen on a programming error.
+		panic(fmt.Sprintf("failed to parse gateway deployment template: %v", err))
+	}
+	return newDeploymentController(client, revision, tmpl)
+}
+
+func newDeploymentController(client kube.Client, revision string, tmpl *template.Template) *DeploymentController {
+	gateways := client.GatewayAPIInformer().Networking().V1alpha1().Gateways()
+	classes := client.GatewayAPIInformer().Networking().V1alpha1().GatewayClasses()
+	dc := &DeploymentController{
+		client:          client,
+		queue:           queue.NewQueue(time.Second),
+		template:        tmpl,
+		revision:        revision,
+		gatewayLister:   gateways.Lister(),
+		gatewayInformer: gateways.Informer(),
+		classLister:     classes.Lister(),
+		classInformer:   classes.Informer(),
+	}
+
+	dc.gatewayInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			dc.enqueue(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			dc.enqueue(obj)
+		},
+	})
+	dc.classInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			dc.enqueueClass(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			dc.enqueueClass(obj)
+		},
+	})
+
+	// Watch the generated resources, so manual changes or deletions are reverted.
+	ownedHandler := cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, obj interface{}) {
+			dc.enqueueOwner(obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			dc.enqueueOwner(obj)
+		},
+	}
+	deployments := client.KubeInformer().Apps().V1().Deployments().Informer()
+	deployments.AddEventHandler(ownedHandler)
+	services := client.KubeInformer().Core().V1().Services().Informer()
+	services.AddEventHandler(ownedHandler)
+	serviceAccounts := client.KubeInformer().Core().V1().ServiceAccounts().Informer()
+	serviceAccounts.AddEventHandler(ownedHandler)
+
+	dc.informers = []cache.SharedIndexInformer{dc.gatewayInformer, dc.classInformer, deployments, services, serviceAccounts}
+	return dc
+}
+
+func parseDeploymentTemplate(tmpl string) (*template.Template, error) {
+	return template.New("gateway-deployment").
+		Funcs(sprig.TxtFuncMap()).
+		Funcs(inject.CreateInjectionFuncmap()).
+		Parse(tmpl)
+}
+
+// Run starts the DeploymentController until a value is sent to stop.
+func (d *DeploymentController) Run(stop <-chan struct{}) {
+	syncs := make([]cache.InformerSynced, 0, len(d.informers))
+	for _, i := range d.informers {
+		syncs = append(syncs, i.HasSynced)
+	}
+	if !cache.WaitForCacheSync(stop, syncs...) {
+		log.Error("Failed to sync gateway deployment controller cache")
+		return
+	}
+	log.Infof("Gateway deployment controller started")
+	d.queue.Run(stop)
+}
+
+func (d *DeploymentController) enqueue(obj interface{}) {
+	gw, ok := obj.(*k8s.Gateway)
+	if !ok {
+		return
+	}
+	d.enqueueName(types.NamespacedName{Name: gw.Name, Namespace: gw.Namespace})
+}
+
+func (d *DeploymentController) enqueueName(name types.NamespacedName) {
+	d.queue.Push(func() error {
+		return d.Reconcile(name)
+	})
+}
+
+// enqueueClass requeues all gateways referencing a class, as they may have changed between
+// being managed by Istio or not.
+func (d *DeploymentController) enqueueClass(obj interface{}) {
+	gc, ok := obj.(*k8s.GatewayClass)
+	if !ok {
+		return
+	}
+	gws, err := d.gatewayLister.List(klabels.Everything())
+	if err != nil {
+		log.Errorf("failed to list gateways: %v", err)
+		return
+	}
+	for _, gw := range gws {
+		if gw.Spec.GatewayClassName == gc.Name {
+			d.enqueueName(types.NamespacedName{Name: gw.Name, Namespace: gw.Namespace})
+		}
+	}
+}
+
+// enqueueOwner requeues the Gateway owning a generated resource.
+func (d *DeploymentController) enqueueOwner(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	meta, ok := obj.(metav1.Object)
+	if !ok || meta.GetLabels()[ManagedGatewayLabel] != ManagedGatewayController {
+		return
+	}
+	for _, ref := range meta.GetOwnerReferences() {
+		if ref.Kind == gvk.ServiceApisGateway.Kind {
+			d.enqueueName(types.NamespacedName{Name: ref.Name, Namespace: meta.GetNamespace()})
+		}
+	}
+}
+
+// Reconcile ensures the generated resources for a Gateway match the rendered template.
+// Deleted gateways need no handling, as the generated resources are removed by garbage collection.
+func (d *DeploymentController) Reconcile(name types.NamespacedName) error {
+	gw, err := d.gatewayLister.Gateways(name.Namespace).Get(name.Name)
+	if kerrors.IsNotFound(err) {
+		log.Debugf("gateway %v no longer exists", name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !d.managed(gw) {
+		log.Debugf("skip gateway %v, not provisioned by istio", name)
+		return nil
+	}
+	objs, err := d.render(gw)
+	if err != nil {
+		// Retrying will not help if the template does not render; wait for the next change.
+		log.Errorf("failed to render deployment template for gateway %v: %v", name, err)
+		return nil
+	}
+	for _, obj := range objs {
+		if err := d.apply(obj); err != nil {
+			var notOwned notOwnedError
+			if errors.As(err, &notOwned) {
+				// Retrying will not help until the conflicting resource is removed, which requeues the gateway.
+				log.Warnf("skip gateway %v: %v", name, err)
+				return nil
+			}
+			return fmt.Errorf("failed to apply generated resource for gateway %v: %v", name, err)
+		}
+	}
+	log.Debugf("reconciled gateway %v", name)
+	return nil
+}
+
+// managed determines if we should provision resources for a Gateway. This is the case for gateways
+// of a class handled by Istio without explicit addresses; gateways with addresses bind to an
+// existing deployment instead.
+func (d *DeploymentController) managed(gw *k8s.Gateway) bool {
+	if len(gw.Spec.Addresses) > 0 {
+		return false
+	}
+	gc, err := d.classLister.Get(gw.Spec.GatewayClassName)
+	if err != nil {
+		return false
+	}
+	return gc.Spec.Controller == ControllerName
+}
+
+func (d *DeploymentController) render(gw *k8s.Gateway) ([]runtime.Object, error) {
+	input := deploymentInput{
+		Name:             gw.Name,
+		Namespace:        gw.Namespace,
+		UID:              gw.UID,
+		OwnerAPIVersion:  gvk.ServiceApisGateway.GroupVersion(),
+		ServiceAccount:   gw.Name,
+		Revision:         d.revision,
+		ServiceType:      corev1.ServiceTypeLoadBalancer,
+		GatewayNameLabel: GatewayNameLabel,
+		Labels:           map[string]string{},
+		PodLabels:        map[string]string{},
+		Annotations:      podAnnotations(gw),
+		Ports:            extractServicePorts(gw),
+	}
+	for k, v := range gw.Labels {
+		input.Labels[k] = v
+	}
+	input.Labels[ManagedGatewayLabel] = ManagedGatewayController
+	// The pod labels are rendered as a single map, so the labels we set replace those of the Gateway rather than
+	// producing duplicate keys.
+	for k, v := range input.Labels {
+		input.PodLabels[k] = v
+	}
+	input.PodLabels["sidecar.istio.io/inject"] = "true"
+	input.PodLabels[GatewayNameLabel] = gw.Name
+	if d.revision != "" {
+		input.PodLabels[label.IoIstioRev.Name] = d.revision
+	}
+	if st, f := gw.Annotations[ServiceTypeAnnotation]; f {
+		input.ServiceType = corev1.ServiceType(st)
+	}
+
+	var out bytes.Buffer
+	if err := d.template.Execute(&out, input); err != nil {
+		return nil, err
+	}
+	objs := make([]runtime.Object, 0, 3)
+	for _, chunk := range strings.Split(out.String(), "\n---\n") {
+		if strings.TrimSpace(chunk) == "" {
+			continue
+		}
+		obj, err := decodeGeneratedObject([]byte(chunk))
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// podAnnotations returns the annotations of the gateway pods: the Gateway annotations with an allowed prefix, and
+// the gateway injection template unless the Gateway selects its own.
+func podAnnotations(gw *k8s.Gateway) map[string]string {
+	out := map[string]string{injectTemplatesAnnotation: "gateway"}
+	for k, v := range gw.Annotations {
+		for _, prefix := range podAnnotationPrefixes {
+			if strings.HasPrefix(k, prefix) {
+				out[k] = v
+				break
+			}
+		}
+	}
+	return out
+}
+
+// decodeGeneratedObject decodes a rendered resource into one of the supported typed objects.
+func decodeGeneratedObject(raw []byte) (runtime.Object, error) {
+	var tm metav1.TypeMeta
+	if err := yaml.Unmarshal(raw, &tm); err != nil {
+		return nil, err
+	}
+	var obj runtime.Object
+	switch tm.Kind {
+	case "Deployment":
+		obj = &appsv1.Deployment{}
+	case "Service":
+		obj = &corev1.Service{}
+	case "ServiceAccount":
+		obj = &corev1.ServiceAccount{}
+	default:
+		return nil, fmt.Errorf("unsupported kind %q in gateway deployment template", tm.Kind)
+	}
+	if err := yaml.UnmarshalStrict(raw, obj); err != nil {
+		return nil, fmt.Errorf("failed to decode %v: %v", tm.Kind, err)
+	}
+	return obj, nil
+}
+
+// apply creates the object if it does not exist, or updates it if it has drifted from the desired state.
+func (d *DeploymentController) apply(obj runtime.Object) error {
+	ctx := context.TODO()
+	switch desired := obj.(type) {
+	case *appsv1.Deployment:
+		c := d.client.Kube().AppsV1().Deployments(desired.Namespace)
+		cur, err := c.Get(ctx, desired.Name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			_, err = c.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkOwner("Deployment", desired.ObjectMeta, cur.ObjectMeta); err != nil {
+			return err
+		}
+		if metaUpToDate(desired.ObjectMeta, cur.ObjectMeta) && equality.Semantic.DeepDerivative(desired.Spec, cur.Spec) {
+			return nil
+		}
+		desired.ResourceVersion = cur.ResourceVersion
+		_, err = c.Update(ctx, desired, metav1.UpdateOptions{})
+		return err
+	case *corev1.Service:
+		c := d.client.Kube().CoreV1().Services(desired.Namespace)
+		cur, err := c.Get(ctx, desired.Name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			_, err = c.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkOwner("Service", desired.ObjectMeta, cur.ObjectMeta); err != nil {
+			return err
+		}
+		if metaUpToDate(desired.ObjectMeta, cur.ObjectMeta) && equality.Semantic.DeepDerivative(desired.Spec, cur.Spec) {
+			return nil
+		}
+		// Fields assigned by the API server are immutable, carry them over.
+		desired.ResourceVersion = cur.ResourceVersion
+		desired.Spec.ClusterIP = cur.Spec.ClusterIP
+		desired.Spec.ClusterIPs = cur.Spec.ClusterIPs
+		carryOverNodePorts(desired, cur)
+		_, err = c.Update(ctx, desired, metav1.UpdateOptions{})
+		return err
+	case *corev1.ServiceAccount:
+		c := d.client.Kube().CoreV1().ServiceAccounts(desired.Namespace)
+		cur, err := c.Get(ctx, desired.Name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			_, err = c.Create(ctx, desired, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if err := checkOwner("ServiceAccount", desired.ObjectMeta, cur.ObjectMeta); err != nil {
+			return err
+		}
+		if metaUpToDate(desired.ObjectMeta, cur.ObjectMeta) {
+			return nil
+		}
+		// Keep the token secrets populated by the API server.
+		desired.ResourceVersion = cur.ResourceVersion
+		desired.Secrets = cur.Secrets
+		desired.ImagePullSecrets = cur.ImagePullSecrets
+		_, err = c.Update(ctx, desired, metav1.UpdateOptions{})
+		return err
+	default:
+		return fmt.Errorf("unsupported object type %T", obj)
+	}
+}
+
+// notOwnedError is returned when a resource with the name of a generated resource exists, but was not generated
+// for the Gateway. Such resources, for example an existing istio-ingressgateway Deployment, are never taken over.
+type notOwnedError struct {
+	kind string
+	name types.NamespacedName
+}
+
+func (e notOwnedError) Error() string {
+	return fmt.Sprintf("%s %v already exists and is not managed by the gateway", e.kind, e.name)
+}
+
+// checkOwner checks that the current object carries the managed label and is owned by the Gateway of the desired
+// object.
+func checkOwner(kind string, desired, cur metav1.ObjectMeta) error {
+	if cur.Labels[ManagedGatewayLabel] == ManagedGatewayController {
+		for _, ref := range cur.OwnerReferences {
+			for _, want := range desired.OwnerReferences {
+				if ref.UID == want.UID {
+					return nil
+				}
+			}
+		}
+	}
+	return notOwnedError{kind: kind, name: types.NamespacedName{Name: cur.Name, Namespace: cur.Namespace}}
+}
+
+// carryOverNodePorts keeps the node ports allocated to the current Service, so that updates do not reallocate them
+// and break external load balancers. Ports are matched by name, or by port number if they were renamed.
+func carryOverNodePorts(desired, cur *corev1.Service) {
+	if desired.Spec.Type != corev1.ServiceTypeNodePort && desired.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return
+	}
+	for i := range desired.Spec.Ports {
+		port := &desired.Spec.Ports[i]
+		if port.NodePort != 0 {
+			continue
+		}
+		for _, cp := range cur.Spec.Ports {
+			if cp.Name == port.Name {
+				port.NodePort = cp.NodePort
+				break
+			}
+		}
+		if port.NodePort != 0 {
+			continue
+		}
+		for _, cp := range cur.Spec.Ports {
+			if cp.Port == port.Port && cp.Protocol == port.Protocol {
+				port.NodePort = cp.NodePort
+				break
+			}
+		}
+	}
+	if desired.Spec.HealthCheckNodePort == 0 {
+		desired.Spec.HealthCheckNodePort = cur.Spec.HealthCheckNodePort
+	}
+}
+
+// metaUpToDate checks whether the labels, annotations and owners we set are present on the current object.
+func metaUpToDate(desired, cur metav1.ObjectMeta) bool {
+	return equality.Semantic.DeepDerivative(desired.Labels, cur.Labels) &&
+		equality.Semantic.DeepDerivative(desired.Annotations, cur.Annotations) &&
+		equality.Semantic.DeepDerivative(desired.OwnerReferences, cur.OwnerReferences)
+}
+
+// extractServicePorts builds the Service ports for a Gateway. The status port is always exposed, so
+// the Service is valid even before any listeners are defined.
+func extractServicePorts(gw *k8s.Gateway) []corev1.ServicePort {
+	ports := []corev1.ServicePort{{
+		Name:     "status-port",
+		Port:     15021,
+		Protocol: corev1.ProtocolTCP,
+	}}
+	seen := map[int32]struct{}{15021: {}}
+	for _, l := range gw.Spec.Listeners {
+		port := int32(l.Port)
+		if _, f := seen[port]; f {
+			// Multiple listeners may share a port, for example with different hostnames
+			continue
+		}
+		seen[port] = struct{}{}
+		protocol := corev1.ProtocolTCP
+		if l.Protocol == k8s.UDPProtocolType {
+			protocol = corev1.ProtocolUDP
+		}
+		ports = append(ports, corev1.ServicePort{
+			Name:     fmt.Sprintf("%s-%d", strings.ToLower(string(l.Protocol)), port),
+			Port:     port,
+			Protocol: protocol,
+		})
+	}
+	sort.SliceStable(ports[1:], func(i, j int) bool {
+		return ports[i+1].Port < ports[j+1].Port
+	})
+	return ports
+}
@@ -0,0 +1,352 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8s "sigs.k8s.io/gateway-api/apis/v1alpha1"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestDeploymentController(t *testing.T) {
+	namedAddress := k8s.NamedAddressType
+	cases := []struct {
+		name    string
+		gw      *k8s.Gateway
+		managed bool
+		ports   []int32
+	}{
+		{
+			name: "managed",
+			gw: &k8s.Gateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "default", Labels: map[string]string{"team": "a"}},
+				Spec: k8s.GatewaySpec{
+					GatewayClassName: "istio",
+					Listeners: []k8s.Listener{
+						{Port: 443, Protocol: k8s.HTTPSProtocolType},
+						{Port: 80, Protocol: k8s.HTTPProtocolType},
+						{Port: 80, Protocol: k8s.HTTPProtocolType},
+					},
+				},
+			},
+			managed: true,
+			ports:   []int32{15021, 80, 443},
+		},
+		{
+			name: "manual addresses",
+			gw: &k8s.Gateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "default"},
+				Spec: k8s.GatewaySpec{
+					GatewayClassName: "istio",
+					Addresses:        []k8s.GatewayAddress{{Type: &namedAddress, Value: "istio-ingressgateway"}},
+				},
+			},
+			managed: false,
+		},
+		{
+			name: "other controller",
+			gw: &k8s.Gateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "default"},
+				Spec:       k8s.GatewaySpec{GatewayClassName: "other"},
+			},
+			managed: false,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			client := kube.NewFakeClient()
+			dc := NewDeploymentController(client, "canary")
+			stop := make(chan struct{})
+			defer close(stop)
+			client.RunAndWait(stop)
+
+			createObject(t, client, &k8s.GatewayClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "istio"},
+				Spec:       k8s.GatewayClassSpec{Controller: ControllerName},
+			})
+			createObject(t, client, &k8s.GatewayClass{
+				ObjectMeta: metav1.ObjectMeta{Name: "other"},
+				Spec:       k8s.GatewayClassSpec{Controller: "example.com/other"},
+			})
+			createObject(t, client, tt.gw)
+			name := types.NamespacedName{Name: tt.gw.Name, Namespace: tt.gw.Namespace}
+			retry.UntilSuccessOrFail(t, func() error {
+				if _, err := dc.gatewayLister.Gateways(name.Namespace).Get(name.Name); err != nil {
+					return err
+				}
+				if len(dc.classInformer.GetStore().List()) != 2 {
+					return fmt.Errorf("gateway classes not synced")
+				}
+				return nil
+			})
+
+			if err := dc.Reconcile(name); err != nil {
+				t.Fatal(err)
+			}
+			dep, err := client.Kube().AppsV1().Deployments(name.Namespace).Get(context.TODO(), name.Name, metav1.GetOptions{})
+			if !tt.managed {
+				if err == nil {
+					t.Fatalf("expected no deployment, got %v", dep.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dep.Spec.Template.Labels["istio.io/rev"]; got != "canary" {
+				t.Errorf("expected revision label canary, got %q", got)
+			}
+			if got := dep.Spec.Template.Labels["team"]; got != "a" {
+				t.Errorf("expected gateway labels to be propagated, got %v", dep.Spec.Template.Labels)
+			}
+			if got := dep.Labels[ManagedGatewayLabel]; got != ManagedGatewayController {
+				t.Errorf("expected managed label, got %v", dep.Labels)
+			}
+			if len(dep.OwnerReferences) != 1 || dep.OwnerReferences[0].Name != name.Name {
+				t.Errorf("expected owner reference to gateway, got %v", dep.OwnerReferences)
+			}
+			svc, err := client.Kube().CoreV1().Services(name.Namespace).Get(context.TODO(), name.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+				t.Errorf("expected LoadBalancer service, got %v", svc.Spec.Type)
+			}
+			gotPorts := []int32{}
+			for _, p := range svc.Spec.Ports {
+				gotPorts = append(gotPorts, p.Port)
+			}
+			if fmt.Sprint(gotPorts) != fmt.Sprint(tt.ports) {
+				t.Errorf("expected ports %v, got %v", tt.ports, gotPorts)
+			}
+			if _, err := client.Kube().CoreV1().ServiceAccounts(name.Namespace).Get(context.TODO(), name.Name, metav1.GetOptions{}); err != nil {
+				t.Fatal(err)
+			}
+
+			// Manual changes should be reverted on the next reconcile
+			dep.Spec.Template.Labels["team"] = "b"
+			if _, err := client.Kube().AppsV1().Deployments(name.Namespace).Update(context.TODO(), dep, metav1.UpdateOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			if err := dc.Reconcile(name); err != nil {
+				t.Fatal(err)
+			}
+			dep, err = client.Kube().AppsV1().Deployments(name.Namespace).Get(context.TODO(), name.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := dep.Spec.Template.Labels["team"]; got != "a" {
+				t.Errorf("expected drift to be reverted, got %v", dep.Spec.Template.Labels)
+			}
+		})
+	}
+}
+
+func createObject(t *testing.T, client kube.Client, obj interface{}) {
+	t.Helper()
+	var err error
+	switch o := obj.(type) {
+	case *k8s.GatewayClass:
+		_, err = client.GatewayAPI().NetworkingV1alpha1().GatewayClasses().Create(context.TODO(), o, metav1.CreateOptions{})
+	case *k8s.Gateway:
+		_, err = client.GatewayAPI().NetworkingV1alpha1().Gateways(o.Namespace).Create(context.TODO(), o, metav1.CreateOptions{})
+	default:
+		t.Fatalf("unsupported type %T", obj)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeploymentPodAnnotations(t *testing.T) {
+	tmpl, err := parseDeploymentTemplate(deploymentTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := &DeploymentController{template: tmpl}
+	objs, err := dc.render(&k8s.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "default", Annotations: map[string]string{
+			"inject.istio.io/templates":                        "gateway,custom",
+			"proxy.istio.io/config":                            "concurrency: 2",
+			"kubectl.kubernetes.io/last-applied-configuration": "{}",
+		}},
+		Spec: k8s.GatewaySpec{GatewayClassName: "istio"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dep *appsv1.Deployment
+	for _, o := range objs {
+		if d, ok := o.(*appsv1.Deployment); ok {
+			dep = d
+		}
+	}
+	if dep == nil {
+		t.Fatalf("expected deployment, got %v", objs)
+	}
+	want := map[string]string{
+		"inject.istio.io/templates": "gateway,custom",
+		"proxy.istio.io/config":     "concurrency: 2",
+	}
+	if got := dep.Spec.Template.Annotations; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got pod annotations %v, want %v", got, want)
+	}
+}
+
+func TestDeploymentControllerUnmanagedResources(t *testing.T) {
+	client := kube.NewFakeClient()
+	dc := NewDeploymentController(client, "")
+	stop := make(chan struct{})
+	defer close(stop)
+	client.RunAndWait(stop)
+
+	existing := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "default", Labels: map[string]string{"app": "istio-ingressgateway"}},
+	}
+	if _, err := client.Kube().AppsV1().Deployments("default").Create(context.TODO(), existing, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	createObject(t, client, &k8s.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio"},
+		Spec:       k8s.GatewayClassSpec{Controller: ControllerName},
+	})
+	createObject(t, client, &k8s.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "default", UID: "gw-uid"},
+		Spec:       k8s.GatewaySpec{GatewayClassName: "istio"},
+	})
+	name := types.NamespacedName{Name: "gw", Namespace: "default"}
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, err := dc.gatewayLister.Gateways(name.Namespace).Get(name.Name); err != nil {
+			return err
+		}
+		if len(dc.classInformer.GetStore().List()) != 1 {
+			return fmt.Errorf("gateway classes not synced")
+		}
+		return nil
+	})
+
+	if err := dc.Reconcile(name); err != nil {
+		t.Fatal(err)
+	}
+	dep, err := client.Kube().AppsV1().Deployments(name.Namespace).Get(context.TODO(), name.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dep.Labels, existing.Labels) || len(dep.OwnerReferences) != 0 {
+		t.Fatalf("expected unmanaged deployment to be left unchanged, got labels %v owners %v", dep.Labels, dep.OwnerReferences)
+	}
+}
+
+func TestApplyServiceKeepsNodePorts(t *testing.T) {
+	client := kube.NewFakeClient()
+	dc := &DeploymentController{client: client}
+	owner := []metav1.OwnerReference{{APIVersion: "networking.x-k8s.io/v1alpha1", Kind: "Gateway", Name: "gw", UID: "gw-uid"}}
+	cur := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "gw",
+			Namespace:       "default",
+			Labels:          map[string]string{ManagedGatewayLabel: ManagedGatewayController},
+			OwnerReferences: owner,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Name: "status-port", Port: 15021, Protocol: corev1.ProtocolTCP, NodePort: 30021},
+				{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP, NodePort: 30080},
+			},
+			HealthCheckNodePort: 32000,
+		},
+	}
+	if _, err := client.Kube().CoreV1().Services("default").Create(context.TODO(), cur, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	desired := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "gw",
+			Namespace:       "default",
+			Labels:          map[string]string{ManagedGatewayLabel: ManagedGatewayController},
+			OwnerReferences: owner,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{
+				{Name: "status-port", Port: 15021, Protocol: corev1.ProtocolTCP},
+				// Renamed port, matched by port number
+				{Name: "http-80", Port: 80, Protocol: corev1.ProtocolTCP},
+				{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+	if err := dc.apply(desired); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := client.Kube().CoreV1().Services("default").Get(context.TODO(), "gw", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int32{}
+	for _, p := range svc.Spec.Ports {
+		got[p.Name] = p.NodePort
+	}
+	want := map[string]int32{"status-port": 30021, "http-80": 30080, "https": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got node ports %v, want %v", got, want)
+	}
+	if svc.Spec.HealthCheckNodePort != 32000 {
+		t.Errorf("expected health check node port to be kept, got %v", svc.Spec.HealthCheckNodePort)
+	}
+}
+
+func TestDeploymentPodLabels(t *testing.T) {
+	tmpl, err := parseDeploymentTemplate(deploymentTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := &DeploymentController{template: tmpl, revision: "canary"}
+	objs, err := dc.render(&k8s.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw", Namespace: "default", Labels: map[string]string{
+			"sidecar.istio.io/inject": "false",
+			"istio.io/rev":            "stable",
+			"team":                    "a",
+		}},
+		Spec: k8s.GatewaySpec{GatewayClassName: "istio"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dep *appsv1.Deployment
+	for _, o := range objs {
+		if d, ok := o.(*appsv1.Deployment); ok {
+			dep = d
+		}
+	}
+	if dep == nil {
+		t.Fatalf("expected deployment, got %v", objs)
+	}
+	labels := dep.Spec.Template.Labels
+	if labels["sidecar.istio.io/inject"] != "true" || labels["istio.io/rev"] != "canary" || labels["team"] != "a" {
+		t.Fatalf("unexpected pod labels %v", labels)
+	}
+}
@@ -152,6 +152,10 @@ type Controller struct {
 
 	// healthCondition is a fifo queue used for updating health check status
 	healthCondition cache.Queue
+
+	// owns, if set, restricts the periodic cleanup to the WorkloadEntries this instance is responsible for,
+	// keyed by namespace/name, so that istiod replicas do not all process every entry.
+	owns func(key string) bool
 }
 
 type HealthStatus = v1alpha1.IstioCondition
@@ -178,6 +182,14 @@ func NewController(store model.ConfigStoreCache, instanceID string, maxConnAge t
 	return nil
 }
 
+// SetOwnership shards the periodic cleanup of auto registered WorkloadEntries between istiod replicas.
+func (c *Controller) SetOwnership(owns func(key string) bool) {
+	if c == nil {
+		return
+	}
+	c.owns = owns
+}
+
 func (c *Controller) Run(stop <-chan struct{}) {
 	if c == nil {
 		return
@@ -457,6 +469,11 @@ func (c *Controller) updateWorkloadEntryHealth(obj interface{}) error {
 }
 
 // periodicWorkloadEntryCleanup checks lists all WorkloadEntry
+// ownsEntry checks if this instance is responsible for the periodic cleanup of the WorkloadEntry.
+func (c *Controller) ownsEntry(wle config.Config) bool {
+	return c.owns == nil || c.owns(wle.Namespace+"/"+wle.Name)
+}
+
 func (c *Controller) periodicWorkloadEntryCleanup(stopCh <-chan struct{}) {
 	if !features.WorkloadEntryAutoRegistration {
 		return
@@ -473,7 +490,7 @@ func (c *Controller) periodicWorkloadEntryCleanup(stopCh <-chan struct{}) {
 			}
 			for _, wle := range wles {
 				wle := wle
-				if c.shouldCleanupEntry(wle) {
+				if c.ownsEntry(wle) && c.shouldCleanupEntry(wle) {
 					c.cleanupQueue.Push(func() error {
 						c.cleanupEntry(wle)
 						return nil
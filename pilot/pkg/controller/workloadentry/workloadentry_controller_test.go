@@ -239,6 +239,24 @@ func TestUpdateHealthCondition(t *testing.T) {
 	})
 }
 
+func TestOwnsEntry(t *testing.T) {
+	c := &Controller{}
+	wle := config.Config{Meta: config.Meta{Namespace: "default", Name: "wle"}}
+	if !c.ownsEntry(wle) {
+		t.Fatal("expected entries to be owned without sharding")
+	}
+	c.SetOwnership(func(key string) bool {
+		return key == "default/other"
+	})
+	if c.ownsEntry(wle) {
+		t.Fatal("expected entry to be owned by another instance")
+	}
+	wle.Name = "other"
+	if !c.ownsEntry(wle) {
+		t.Fatal("expected entry to be owned")
+	}
+}
+
 func TestWorkloadEntryFromGroup(t *testing.T) {
 	group := config.Config{
 		Meta: config.Meta{
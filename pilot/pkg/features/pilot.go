@@ -91,6 +91,20 @@ var (
 			"for this time, we'll trigger a push.",
 	).Get()
 
+	EnableAdaptiveDebounce = env.RegisterBoolVar(
+		"PILOT_ENABLE_ADAPTIVE_DEBOUNCE",
+		false,
+		"If enabled, the PILOT_DEBOUNCE_AFTER delay is adjusted to the push load. It is doubled, up to "+
+			"PILOT_ADAPTIVE_DEBOUNCE_AFTER_MAX, while pushes are slow or proxies are still waiting for a push, "+
+			"and shortened back to PILOT_DEBOUNCE_AFTER once pushes keep up or Pilot is idle.",
+	).Get()
+
+	AdaptiveDebounceAfterMax = env.RegisterDurationVar(
+		"PILOT_ADAPTIVE_DEBOUNCE_AFTER_MAX",
+		time.Second,
+		"The upper bound of the debounce delay when PILOT_ENABLE_ADAPTIVE_DEBOUNCE is enabled.",
+	).Get()
+
 	EnableEDSDebounce = env.RegisterBoolVar(
 		"PILOT_ENABLE_EDS_DEBOUNCE",
 		true,
@@ -257,6 +271,14 @@ var (
 		"If enabled, pilot will update the CRD Status field of all istio resources with reconciliation status.",
 	).Get()
 
+	EnableReplicaSharding = env.RegisterBoolVar(
+		"PILOT_ENABLE_REPLICA_SHARDING",
+		false,
+		"If enabled, istiod replicas register themselves with a Lease and shard background work, such as "+
+			"writing status, cleaning up auto registered WorkloadEntries and creating ServiceExports, between "+
+			"each other instead of running it on a single leader.",
+	).Get()
+
 	StatusUpdateInterval = env.RegisterDurationVar(
 		"PILOT_STATUS_UPDATE_INTERVAL",
 		500*time.Millisecond,
@@ -307,6 +329,10 @@ var (
 	EnableGatewayAPIStatus = env.RegisterBoolVar("PILOT_ENABLE_GATEWAY_API_STATUS", true,
 		"If this is set to true, gateway-api resources will have status written to them").Get()
 
+	EnableGatewayAPIDeploymentController = env.RegisterBoolVar("PILOT_ENABLE_GATEWAY_API_DEPLOYMENT_CONTROLLER", false,
+		"If this is set to true, gateway-api Gateways without addresses will have a Deployment, Service and "+
+			"ServiceAccount provisioned automatically. Otherwise, they bind to the default ingress gateway.").Get()
+
 	EnableVirtualServiceDelegate = env.RegisterBoolVar(
 		"PILOT_ENABLE_VIRTUAL_SERVICE_DELEGATE",
 		true,
@@ -347,6 +373,17 @@ var (
 	InjectionWebhookConfigName = env.RegisterStringVar("INJECTION_WEBHOOK_CONFIG_NAME", "istio-sidecar-injector",
 		"Name of the mutatingwebhookconfiguration to patch, if istioctl is not used.").Get()
 
+	EnableStaleSidecarDetection = env.RegisterBoolVar("PILOT_ENABLE_STALE_SIDECAR_DETECTION", false,
+		"If enabled, istiod periodically compares injected pods of its revision with the current injection "+
+			"configuration and reports pods that need a restart in the sidecar_injection_stale_pods metric.").Get()
+
+	StaleSidecarCheckInterval = env.RegisterDurationVar("PILOT_STALE_SIDECAR_CHECK_INTERVAL", 5*time.Minute,
+		"The interval between two checks for stale sidecars.").Get()
+
+	StaleSidecarRestartsPerMinute = env.RegisterIntVar("PILOT_STALE_SIDECAR_RESTARTS_PER_MINUTE", 0,
+		"The maximum number of Deployments with stale sidecars restarted per minute. If 0, stale sidecars "+
+			"are only reported.").Get()
+
 	ValidationWebhookConfigName = env.RegisterStringVar("VALIDATION_WEBHOOK_CONFIG_NAME", "istio-istio-system",
 		"Name of the validatingwebhookconfiguration to patch. Empty will skip using cluster admin to patch.").Get()
 
@@ -372,6 +409,12 @@ var (
 	EnableRDSCaching = env.RegisterBoolVar("PILOT_ENABLE_RDS_CACHE", true,
 		"If true, Pilot will cache RDS responses. Note: this depends on PILOT_ENABLE_XDS_CACHE.").Get()
 
+	// EnableLDSCaching determines if LDS caching is enabled. This is explicitly split out of ENABLE_XDS_CACHE,
+	// so that in case there are issues with the LDS cache we can just disable the LDS cache.
+	EnableLDSCaching = env.RegisterBoolVar("PILOT_ENABLE_LDS_CACHE", false,
+		"If true, Pilot will cache the outbound listeners of sidecars. Inbound listeners and the listeners of "+
+			"gateways are always built for each push. Note: this depends on PILOT_ENABLE_XDS_CACHE.").Get()
+
 	EnableXDSCacheMetrics = env.RegisterBoolVar("PILOT_XDS_CACHE_STATS", false,
 		"If true, Pilot will collect metrics for XDS cache efficiency.").Get()
 
@@ -400,6 +443,13 @@ var (
 			"Setting the timeout to 0 disables this behavior.",
 	).Get()
 
+	RemoteClusterRotationTimeout = env.RegisterDurationVar(
+		"PILOT_REMOTE_CLUSTER_ROTATION_TIMEOUT",
+		30*time.Second,
+		"When the kubeconfig of a remote cluster changes, how long to wait for the registry built with the new kubeconfig "+
+			"to sync before giving up and keeping the current one.",
+	).Get()
+
 	EndpointTelemetryLabel = env.RegisterBoolVar("PILOT_ENDPOINT_TELEMETRY_LABEL", true,
 		"If true, pilot will add telemetry related metadata to Endpoint resource, which will be consumed by telemetry filter.",
 	).Get()
@@ -37,6 +37,13 @@ const (
 	GatewayController = "istio-gateway-leader"
 	StatusController  = "istio-status-leader"
 	AnalyzeController = "istio-analyze-leader"
+
+	// GatewayDeploymentController provisions deployments for gateway-api Gateways.
+	GatewayDeploymentController = "istio-gateway-deployment-leader"
+
+	// StaleSidecarController reports and restarts pods with stale sidecars. It is suffixed with the revision,
+	// as each revision checks the pods it injected.
+	StaleSidecarController = "istio-stale-sidecar-leader"
 )
 
 type LeaderElection struct {
@@ -0,0 +1,252 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderelection
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/pkg/log"
+)
+
+// ShardingGroup is the membership group used by istiod replicas sharding background work.
+const ShardingGroup = "istio-shard"
+
+// MembershipGroupLabel is set on every Lease taking part in a membership group.
+const MembershipGroupLabel = "istio.io/membership-group"
+
+// expiredLeaseGCDurations is the number of lease durations after which the Lease of a replica that did not remove
+// it, for example because it crashed, is removed by the other replicas.
+const expiredLeaseGCDurations = 5
+
+// Membership tracks the live replicas of a group, each of which renews a Lease of its own, and
+// assigns keys to replicas using rendezvous hashing. Every replica computes the same assignment
+// once it observes the same members, so work can be sharded without further coordination.
+type Membership struct {
+	namespace string
+	name      string
+	group     string
+	client    kubernetes.Interface
+	ttl       time.Duration
+
+	mu       sync.RWMutex
+	members  []string
+	handlers []func()
+}
+
+func NewMembership(namespace, name, group string, client kubernetes.Interface) *Membership {
+	if name == "" {
+		name = "unknown"
+	}
+	return &Membership{
+		namespace: namespace,
+		name:      name,
+		group:     group,
+		client:    client,
+		// Default to a 30s ttl. Overridable for tests
+		ttl: time.Second * 30,
+	}
+}
+
+// Run renews the Lease of this replica and refreshes the members until stop is closed, at which point
+// the Lease is removed so that the remaining replicas take over its keys.
+func (m *Membership) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(m.ttl / 3)
+	defer ticker.Stop()
+	for {
+		m.sync()
+		select {
+		case <-stop:
+			err := m.client.CoordinationV1().Leases(m.namespace).Delete(context.TODO(), m.leaseName(), metaV1.DeleteOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				log.Warnf("failed to remove membership lease %s: %v", m.leaseName(), err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Membership) sync() {
+	if err := m.renew(); err != nil {
+		log.Warnf("failed to renew membership lease %s: %v", m.leaseName(), err)
+	}
+	leases, err := m.client.CoordinationV1().Leases(m.namespace).List(context.TODO(), metaV1.ListOptions{
+		LabelSelector: MembershipGroupLabel + "=" + m.group,
+	})
+	if err != nil {
+		log.Warnf("failed to list membership leases for %s: %v", m.group, err)
+		return
+	}
+	now := time.Now()
+	// Always consider ourselves a member, so that our keys are still handled if the API server is unreachable.
+	members := []string{m.name}
+	for _, l := range leases.Items {
+		holder := l.Spec.HolderIdentity
+		if holder == nil || *holder == m.name || l.Spec.RenewTime == nil || l.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		duration := time.Duration(*l.Spec.LeaseDurationSeconds) * time.Second
+		expiry := l.Spec.RenewTime.Add(duration)
+		if expiry.After(now) {
+			members = append(members, *holder)
+		} else if now.Sub(expiry) > expiredLeaseGCDurations*duration {
+			m.deleteExpired(l)
+		}
+	}
+	sort.Strings(members)
+	m.setMembers(members)
+}
+
+// deleteExpired removes the Lease of a replica that left without removing it. The deletion is conditional on the
+// version of the Lease, so that a replica that renewed it in the meantime keeps it.
+func (m *Membership) deleteExpired(l coordinationv1.Lease) {
+	err := m.client.CoordinationV1().Leases(m.namespace).Delete(context.TODO(), l.Name, metaV1.DeleteOptions{
+		Preconditions: &metaV1.Preconditions{UID: &l.UID, ResourceVersion: &l.ResourceVersion},
+	})
+	if err != nil && !errors.IsNotFound(err) && !errors.IsConflict(err) {
+		log.Warnf("failed to remove expired membership lease %s: %v", l.Name, err)
+		return
+	}
+	log.Infof("removed expired membership lease %s", l.Name)
+}
+
+func (m *Membership) renew() error {
+	now := metaV1.NewMicroTime(time.Now())
+	duration := int32(m.ttl / time.Second)
+	if duration == 0 {
+		duration = 1
+	}
+	leases := m.client.CoordinationV1().Leases(m.namespace)
+	lease, err := leases.Get(context.TODO(), m.leaseName(), metaV1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = leases.Create(context.TODO(), &coordinationv1.Lease{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:      m.leaseName(),
+				Namespace: m.namespace,
+				Labels:    map[string]string{MembershipGroupLabel: m.group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &m.name,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}, metaV1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(context.TODO(), lease, metaV1.UpdateOptions{})
+	return err
+}
+
+func (m *Membership) setMembers(members []string) {
+	m.mu.Lock()
+	changed := !equalMembers(m.members, members)
+	m.members = members
+	handlers := m.handlers
+	m.mu.Unlock()
+	if !changed {
+		return
+	}
+	log.Infof("membership of %s changed: %v", m.group, members)
+	for _, h := range handlers {
+		h()
+	}
+}
+
+func (m *Membership) leaseName() string {
+	return m.group + "-" + m.name
+}
+
+// AddHandler registers a function called whenever the members change, so that callers can pick up the
+// keys they were newly assigned. Handlers must not block.
+func (m *Membership) AddHandler(h func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, h)
+}
+
+// Name returns the identity of this replica.
+func (m *Membership) Name() string {
+	return m.name
+}
+
+// Members returns the sorted identities of the live replicas.
+func (m *Membership) Members() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string{}, m.members...)
+}
+
+// Owner returns the replica responsible for the key.
+func (m *Membership) Owner(key string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if len(m.members) == 0 {
+		return m.name
+	}
+	owner, best := "", uint64(0)
+	for _, member := range m.members {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(member))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(key))
+		if score := mix(h.Sum64()); owner == "" || score > best {
+			owner, best = member, score
+		}
+	}
+	return owner
+}
+
+// Owns checks if this replica is responsible for the key. Until the members are first observed,
+// this replica owns every key.
+func (m *Membership) Owns(key string) bool {
+	return m.Owner(key) == m.name
+}
+
+// mix spreads the bits of an FNV hash, whose high bits barely depend on the last bytes written.
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+func equalMembers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
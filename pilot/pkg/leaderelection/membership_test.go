@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderelection
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func createMembership(name string, client kubernetes.Interface) (*Membership, chan struct{}) {
+	m := NewMembership("ns", name, ShardingGroup, client)
+	m.ttl = time.Second
+	stop := make(chan struct{})
+	go m.Run(stop)
+	return m, stop
+}
+
+func expectMembers(t *testing.T, m *Membership, want ...string) {
+	t.Helper()
+	retry.UntilSuccessOrFail(t, func() error {
+		if got := m.Members(); !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("got members %v, want %v", got, want)
+		}
+		return nil
+	}, retry.Timeout(time.Second*15))
+}
+
+func TestMembership(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	pod1, stop1 := createMembership("pod1", client)
+	defer close(stop1)
+	expectMembers(t, pod1, "pod1")
+
+	changed := make(chan struct{}, 10)
+	pod1.AddHandler(func() { changed <- struct{}{} })
+	pod2, stop2 := createMembership("pod2", client)
+	expectMembers(t, pod1, "pod1", "pod2")
+	expectMembers(t, pod2, "pod1", "pod2")
+	select {
+	case <-changed:
+	case <-time.After(time.Second * 15):
+		t.Fatal("handler not called on membership change")
+	}
+
+	// Each key is owned by exactly one replica, and both replicas agree on the owner
+	owned := map[string]int{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("ns/name-%d", i)
+		if pod1.Owner(key) != pod2.Owner(key) {
+			t.Fatalf("replicas disagree on owner of %s", key)
+		}
+		if pod1.Owns(key) == pod2.Owns(key) {
+			t.Fatalf("expected exactly one owner of %s", key)
+		}
+		owned[pod1.Owner(key)]++
+	}
+	if owned["pod1"] == 0 || owned["pod2"] == 0 {
+		t.Fatalf("expected keys to be spread over both replicas, got %v", owned)
+	}
+
+	// Once pod2 leaves, pod1 takes over all keys
+	close(stop2)
+	expectMembers(t, pod1, "pod1")
+	for i := 0; i < 100; i++ {
+		if key := fmt.Sprintf("ns/name-%d", i); !pod1.Owns(key) {
+			t.Fatalf("expected pod1 to own %s", key)
+		}
+	}
+}
+
+func TestMembershipUncleanExit(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	pod1, stop1 := createMembership("pod1", client)
+	defer close(stop1)
+
+	// pod2 renews its lease a single time, then crashes without removing it
+	pod2 := NewMembership("ns", "pod2", ShardingGroup, client)
+	pod2.ttl = time.Second
+	pod2.sync()
+	expectMembers(t, pod1, "pod1", "pod2")
+
+	// pod2 drops out once its lease expires, and its lease is eventually removed
+	expectMembers(t, pod1, "pod1")
+	retry.UntilSuccessOrFail(t, func() error {
+		_, err := client.CoordinationV1().Leases("ns").Get(context.TODO(), pod2.leaseName(), metaV1.GetOptions{})
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("expected lease of pod2 to be removed, got %v", err)
+		}
+		return nil
+	}, retry.Timeout(time.Second*15))
+	if _, err := client.CoordinationV1().Leases("ns").Get(context.TODO(), pod1.leaseName(), metaV1.GetOptions{}); err != nil {
+		t.Fatalf("expected lease of pod1 to be kept: %v", err)
+	}
+}
+
+func TestMembershipNotStarted(t *testing.T) {
+	m := NewMembership("ns", "pod1", ShardingGroup, fake.NewSimpleClientset())
+	if !m.Owns("ns/name") {
+		t.Fatal("expected to own all keys before membership is known")
+	}
+}
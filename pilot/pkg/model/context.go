@@ -585,6 +585,11 @@ type NodeMetadata struct {
 	// Generator indicates the client wants to use a custom Generator plugin.
 	Generator string `json:"GENERATOR,omitempty"`
 
+	// PushPriority overrides the class of the proxy in the push queue. Gateways are pushed first by default,
+	// "high" gives other critical workloads the same treatment, while "low" pushes the proxy last. It takes
+	// precedence over the istio.io/push-priority label.
+	PushPriority string `json:"PUSH_PRIORITY,omitempty"`
+
 	// DNSCapture indicates whether the workload has enabled dns capture
 	DNSCapture StringBool `json:"DNS_CAPTURE,omitempty"`
 
@@ -117,6 +117,7 @@ func shallowMerge(parent, child *tpb.Telemetry) *tpb.Telemetry {
 	merged := parent.DeepCopy()
 	shallowMergeTracing(merged, child)
 	shallowMergeAccessLogs(merged, child)
+	mergeMetrics(merged, child)
 	return merged
 }
 
@@ -169,3 +170,24 @@ func shallowMergeAccessLogs(parent *tpb.Telemetry, child *tpb.Telemetry) {
 		mergedLogging.Disabled = childLogging.Disabled
 	}
 }
+
+// mergeMetrics merges the child metrics configuration into the parent. Unlike the other sections,
+// overrides are not replaced but accumulated, so the child overrides are applied after the parent ones.
+func mergeMetrics(parent *tpb.Telemetry, child *tpb.Telemetry) {
+	if len(parent.GetMetrics()) == 0 {
+		parent.Metrics = child.Metrics
+		return
+	}
+	if len(child.GetMetrics()) == 0 {
+		return
+	}
+
+	// Only use the first Metrics for now (all that is supported)
+	childMetrics := child.Metrics[0]
+	mergedMetrics := parent.Metrics[0]
+	if len(childMetrics.Providers) != 0 {
+		mergedMetrics.Providers = childMetrics.Providers
+	}
+
+	mergedMetrics.Overrides = append(mergedMetrics.Overrides, childMetrics.Overrides...)
+}
@@ -113,6 +113,47 @@ func TestTelemetries_EffectiveTelemetry(t *testing.T) {
 		},
 	}
 
+	rootMetrics := &tpb.Telemetry{
+		Metrics: []*tpb.Metrics{
+			{
+				Providers: []*tpb.ProviderRef{{Name: "prometheus"}},
+				Overrides: []*tpb.MetricsOverrides{
+					{
+						TagOverrides: map[string]*tpb.MetricsOverrides_TagOverride{
+							"request_protocol": {Operation: tpb.MetricsOverrides_TagOverride_REMOVE},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	fooMetrics := &tpb.Telemetry{
+		Selector: &v1beta1.WorkloadSelector{
+			MatchLabels: map[string]string{"service.istio.io/canonical-name": "foo"},
+		},
+		Metrics: []*tpb.Metrics{
+			{
+				Overrides: []*tpb.MetricsOverrides{
+					{
+						Match: &tpb.MetricSelector{
+							MetricMatch: &tpb.MetricSelector_Metric{Metric: tpb.MetricSelector_REQUEST_SIZE},
+						},
+						Disabled: &types.BoolValue{Value: true},
+					},
+				},
+			},
+		},
+	}
+
+	bazMetrics := &tpb.Telemetry{
+		Metrics: []*tpb.Metrics{
+			{
+				Providers: []*tpb.ProviderRef{{Name: "stackdriver"}},
+			},
+		},
+	}
+
 	cases := []struct {
 		name           string
 		ns             string
@@ -311,6 +352,39 @@ func TestTelemetries_EffectiveTelemetry(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "metrics overrides accumulate",
+			ns:   "foo",
+			configs: []config.Config{
+				newTelemetry("root", "istio-system", rootMetrics),
+				newTelemetry("foo", "foo", fooMetrics),
+			},
+			workloadLabels: map[string]string{"service.istio.io/canonical-name": "foo"},
+			want: &tpb.Telemetry{
+				Metrics: []*tpb.Metrics{
+					{
+						Providers: []*tpb.ProviderRef{{Name: "prometheus"}},
+						Overrides: append(rootMetrics.Metrics[0].Overrides, fooMetrics.Metrics[0].Overrides...),
+					},
+				},
+			},
+		},
+		{
+			name: "metrics provider",
+			ns:   "baz",
+			configs: []config.Config{
+				newTelemetry("root", "istio-system", rootMetrics),
+				newTelemetry("baz", "baz", bazMetrics),
+			},
+			want: &tpb.Telemetry{
+				Metrics: []*tpb.Metrics{
+					{
+						Providers: []*tpb.ProviderRef{{Name: "stackdriver"}},
+						Overrides: rootMetrics.Metrics[0].Overrides,
+					},
+				},
+			},
+		},
 	}
 
 	for _, v := range cases {
@@ -26,6 +26,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/util/sets"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/pkg/monitoring"
 )
@@ -40,7 +41,7 @@ var (
 	xdsCacheReads = monitoring.NewSum(
 		"xds_cache_reads",
 		"Total number of xds cache xdsCacheReads.",
-		monitoring.WithLabels(typeTag),
+		monitoring.WithLabels(typeTag, resourceTag),
 	)
 
 	xdsCacheEvictions = monitoring.NewSum(
@@ -53,19 +54,21 @@ var (
 		"Current size of xds cache",
 	)
 
+	resourceTag = monitoring.MustCreateLabel("resource")
+
 	xdsCacheHits   = xdsCacheReads.With(typeTag.Value("hit"))
 	xdsCacheMisses = xdsCacheReads.With(typeTag.Value("miss"))
 )
 
-func hit() {
+func hit(typeURL string) {
 	if features.EnableXDSCacheMetrics {
-		xdsCacheHits.Increment()
+		xdsCacheHits.With(resourceTag.Value(v3.GetMetricType(typeURL))).Increment()
 	}
 }
 
-func miss() {
+func miss(typeURL string) {
 	if features.EnableXDSCacheMetrics {
-		xdsCacheMisses.Increment()
+		xdsCacheMisses.With(resourceTag.Value(v3.GetMetricType(typeURL))).Increment()
 	}
 }
 
@@ -104,6 +107,8 @@ func indexType(typeIndex map[config.GroupVersionKind]sets.Set, k string, entry X
 type XdsCacheEntry interface {
 	// Key is the key to be used in cache.
 	Key() string
+	// TypeURL is the type of the cached resource, used to break down cache metrics and debug output.
+	TypeURL() string
 	// DependentTypes are config types that this cache key is dependant on.
 	// Whenever any configs of this type changes, we should invalidate this cache entry.
 	// Note: DependentConfigs should be preferred wherever possible.
@@ -268,15 +273,15 @@ func (l *lruCache) Get(entry XdsCacheEntry) (*discovery.Resource, bool) {
 	k := entry.Key()
 	val, ok := l.store.Get(k)
 	if !ok {
-		miss()
+		miss(entry.TypeURL())
 		return nil, false
 	}
 	cv := val.(cacheValue)
 	if cv.value == nil {
-		miss()
+		miss(entry.TypeURL())
 		return nil, false
 	}
-	hit()
+	hit(entry.TypeURL())
 	return cv.value, true
 }
 
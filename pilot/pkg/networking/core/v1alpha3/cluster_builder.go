@@ -465,6 +465,10 @@ func (t *clusterCache) DependentTypes() []config.GroupVersionKind {
 	return nil
 }
 
+func (t *clusterCache) TypeURL() string {
+	return v3.ClusterType
+}
+
 func (t clusterCache) Cacheable() bool {
 	return true
 }
@@ -240,7 +240,7 @@ func (f *ConfigGenTest) SetupProxy(p *model.Proxy) *model.Proxy {
 
 // TODO do we need lock around push context?
 func (f *ConfigGenTest) Listeners(p *model.Proxy) []*listener.Listener {
-	return f.ConfigGen.BuildListeners(p, f.PushContext())
+	return f.ConfigGen.BuildListeners(p, &model.PushRequest{Push: f.PushContext()})
 }
 
 func (f *ConfigGenTest) Clusters(p *model.Proxy) []*cluster.Cluster {
@@ -267,6 +267,9 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 	}
 
 	servicesByName := make(map[host.Name]*model.Service)
+	// The services in servicesByName only carry the attributes needed to build routes, so keep the originals
+	// around to key the cache on.
+	servicesForCache := make(map[host.Name]*model.Service)
 	hostsByNamespace := make(map[string][]host.Name)
 	for _, svc := range services {
 		if listenerPort == 0 {
@@ -285,6 +288,7 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 					ServiceRegistry: svc.Attributes.ServiceRegistry,
 				},
 			}
+			servicesForCache[svc.Hostname] = svc
 			hostsByNamespace[svc.Attributes.Namespace] = append(hostsByNamespace[svc.Attributes.Namespace], svc.Hostname)
 		}
 	}
@@ -298,9 +302,9 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 	var routeCache *istio_route.Cache
 
 	if listenerPort > 0 {
-		services = make([]*model.Service, 0, len(servicesByName))
+		services = make([]*model.Service, 0, len(servicesForCache))
 		// sort services
-		for _, svc := range servicesByName {
+		for _, svc := range servicesForCache {
 			services = append(services, svc)
 		}
 		sort.SliceStable(services, func(i, j int) bool {
@@ -308,17 +312,19 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 		})
 
 		routeCache = &istio_route.Cache{
-			RouteName:       routeName,
-			ProxyVersion:    node.Metadata.IstioVersion,
-			ClusterID:       string(node.Metadata.ClusterID),
-			DNSDomain:       node.DNSDomain,
-			DNSCapture:      bool(node.Metadata.DNSCapture),
-			DNSAutoAllocate: bool(node.Metadata.DNSAutoAllocate),
-			ListenerPort:    listenerPort,
-			Services:        services,
-			VirtualServices: virtualServices,
-			EnvoyFilterKeys: efKeys,
-			PushVersion:     push.PushVersion,
+			RouteName:               routeName,
+			ProxyVersion:            node.Metadata.IstioVersion,
+			ClusterID:               string(node.Metadata.ClusterID),
+			DNSDomain:               node.DNSDomain,
+			DNSCapture:              bool(node.Metadata.DNSCapture),
+			DNSAutoAllocate:         bool(node.Metadata.DNSAutoAllocate),
+			OutboundTrafficPolicy:   node.SidecarScope.OutboundTrafficPolicy,
+			ListenerPort:            listenerPort,
+			Services:                services,
+			VirtualServices:         virtualServices,
+			DelegateVirtualServices: push.DelegateVirtualServicesConfigKey(virtualServices),
+			EnvoyFilterKeys:         efKeys,
+			PushVersion:             push.PushVersion,
 		}
 	}
 
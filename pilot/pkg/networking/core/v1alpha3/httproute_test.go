@@ -1368,3 +1368,102 @@ func buildHTTPService(hostname string, v visibility.Instance, ip, namespace stri
 	service.Ports = Ports
 	return service
 }
+
+func TestSidecarOutboundHTTPRouteCacheDestinationRule(t *testing.T) {
+	const serviceEntry = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+`
+	// destinationRule returns a DestinationRule hashing on the given header, or without load balancer settings
+	destinationRule := func(header string) config.Config {
+		dr := &networking.DestinationRule{Host: "example.com"}
+		if header != "" {
+			dr.TrafficPolicy = &networking.TrafficPolicy{LoadBalancer: &networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+					ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+						HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: header},
+					},
+				},
+			}}
+		}
+		return config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.DestinationRule,
+				Name:             "dr",
+				Namespace:        "default",
+			},
+			Spec: dr,
+		}
+	}
+
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: serviceEntry})
+	cache := model.NewXdsCache()
+	cg.ConfigGen.Cache = cache
+	// hashHeader returns the header the route to example.com hashes on, after a push for the updated configs
+	hashHeader := func(updated ...model.ConfigKey) string {
+		t.Helper()
+		configs := map[model.ConfigKey]struct{}{}
+		for _, key := range updated {
+			configs[key] = struct{}{}
+		}
+		cache.Clear(configs)
+		push := model.NewPushContext()
+		if err := push.InitContext(cg.Env(), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		cg.Env().PushContext = push
+		proxy := cg.SetupProxy(nil)
+		resources, _ := cg.ConfigGen.BuildHTTPRoutes(proxy, &model.PushRequest{Push: push, Start: time.Now()}, []string{"80"})
+		routeConfig := &route.RouteConfiguration{}
+		if len(resources) != 1 || resources[0].Resource.UnmarshalTo(routeConfig) != nil {
+			t.Fatalf("expected route 80, got %v", resources)
+		}
+		if len(cache.Keys()) == 0 {
+			t.Fatal("expected the route to be cached")
+		}
+		for _, vh := range routeConfig.VirtualHosts {
+			if vh.Name == "example.com:80" {
+				if hashPolicy := vh.Routes[0].GetRoute().GetHashPolicy(); len(hashPolicy) > 0 {
+					return hashPolicy[0].GetHeader().GetHeaderName()
+				}
+				return ""
+			}
+		}
+		t.Fatalf("expected a virtual host for example.com, got %v", routeConfig.VirtualHosts)
+		return ""
+	}
+
+	if got := hashHeader(); got != "" {
+		t.Fatalf("expected no hash policy, got %q", got)
+	}
+	drKey := model.ConfigKey{Kind: gvk.DestinationRule, Name: "dr", Namespace: "default"}
+	if _, err := cg.Store().Create(destinationRule("")); err != nil {
+		t.Fatal(err)
+	}
+	if got := hashHeader(drKey); got != "" {
+		t.Fatalf("expected no hash policy, got %q", got)
+	}
+	if _, err := cg.Store().Update(destinationRule("x-user")); err != nil {
+		t.Fatal(err)
+	}
+	if got := hashHeader(drKey); got != "x-user" {
+		t.Fatalf("expected the route to hash on x-user after adding consistentHash to the DestinationRule, got %q", got)
+	}
+	if _, err := cg.Store().Update(destinationRule("x-session")); err != nil {
+		t.Fatal(err)
+	}
+	if got := hashHeader(drKey); got != "x-session" {
+		t.Fatalf("expected the route to hash on x-session after updating the DestinationRule, got %q", got)
+	}
+}
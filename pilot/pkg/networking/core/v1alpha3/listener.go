@@ -99,8 +99,9 @@ var (
 
 // BuildListeners produces a list of listeners and referenced clusters for all proxies
 func (configgen *ConfigGeneratorImpl) BuildListeners(node *model.Proxy,
-	push *model.PushContext) []*listener.Listener {
-	builder := NewListenerBuilder(node, push)
+	req *model.PushRequest) []*listener.Listener {
+	builder := NewListenerBuilder(node, req.Push)
+	builder.req = req
 
 	switch node.Type {
 	case model.SidecarProxy:
@@ -624,8 +625,6 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 	}
 
 	// Now validate all the listeners. Collate the tcp listeners first and then the HTTP listeners
-	// TODO: This is going to be bad for caching as the order of listeners in tcpListeners or httpListeners is not
-	// guaranteed.
 	for _, l := range listenerMap {
 		if l.servicePort.Protocol.IsTCP() {
 			tcpListeners = append(tcpListeners, l.listener)
@@ -633,6 +632,9 @@ func (configgen *ConfigGeneratorImpl) buildSidecarOutboundListeners(node *model.
 			httpListeners = append(httpListeners, l.listener)
 		}
 	}
+	// Sort the listeners, so that the output is deterministic and can be cached.
+	sort.Slice(tcpListeners, func(i, j int) bool { return tcpListeners[i].Name < tcpListeners[j].Name })
+	sort.Slice(httpListeners, func(i, j int) bool { return httpListeners[i].Name < httpListeners[j].Name })
 	tcpListeners = append(tcpListeners, httpListeners...)
 	// Build pass through filter chains now that all the non-passthrough filter chains are ready.
 	for _, listener := range tcpListeners {
@@ -1312,7 +1314,10 @@ func buildHTTPConnectionManager(listenerOpts buildListenerOpts, httpOpts *httpLi
 		filters = append(filters, xdsfilters.Alpn)
 	}
 
-	filters = append(filters, xdsfilters.Cors, xdsfilters.Fault, xdsfilters.BuildRouterFilter(routerFilterCtx))
+	filters = append(filters, xdsfilters.Cors, xdsfilters.Fault)
+	// stats filters are placed right before the router, as done by the EnvoyFilters installed with Istio
+	filters = append(filters, buildHTTPMetricsFilters(listenerOpts.push, listenerOpts.proxy, listenerOpts.class)...)
+	filters = append(filters, xdsfilters.BuildRouterFilter(routerFilterCtx))
 
 	connectionManager.HttpFilters = filters
 
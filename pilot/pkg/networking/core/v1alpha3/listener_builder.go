@@ -61,8 +61,10 @@ var blackholeFilters = []*listener.Filter{{
 // 1. Use separate inbound capture listener(:15006) and outbound capture listener(:15001)
 // 2. The above listeners use bind_to_port sub listeners or filter chains.
 type ListenerBuilder struct {
-	node              *model.Proxy
-	push              *model.PushContext
+	node *model.Proxy
+	push *model.PushContext
+	// req is the push request the listeners are built for. It is only needed to populate the cache, so may be nil.
+	req               *model.PushRequest
 	gatewayListeners  []*listener.Listener
 	inboundListeners  []*listener.Listener
 	outboundListeners []*listener.Listener
@@ -351,7 +353,19 @@ func (lb *ListenerBuilder) buildSidecarInboundListeners(configgen *ConfigGenerat
 }
 
 func (lb *ListenerBuilder) buildSidecarOutboundListeners(configgen *ConfigGeneratorImpl) *ListenerBuilder {
+	var key *outboundListenerCache
+	if features.EnableLDSCaching {
+		key = newOutboundListenerCache(lb.node, lb.push)
+		if listeners, f := getCachedListeners(configgen.Cache, key); f && !features.EnableUnsafeAssertions {
+			lb.outboundListeners = listeners
+			return lb
+		}
+	}
 	lb.outboundListeners = configgen.buildSidecarOutboundListeners(lb.node, lb.push)
+	if key != nil {
+		// The listeners are cached before EnvoyFilter patches are applied in patchListeners
+		addCachedListeners(configgen.Cache, key, lb.req, lb.outboundListeners)
+	}
 	return lb
 }
 
@@ -558,6 +572,7 @@ func buildInboundCatchAllFilterChains(configgen *ConfigGeneratorImpl,
 				Protocol: protocol.HTTP,
 			},
 			protocol: istionetworking.ListenerProtocolAuto,
+			class:    ListenerClassSidecarInbound,
 		}
 		// Call plugins to get mtls policies.
 		fcOpts := configgen.buildInboundFilterchains(in, listenerOpts, matchingIP, clusterName, true)
@@ -643,10 +658,10 @@ func (configgen *ConfigGeneratorImpl) buildInboundFilterchains(in *plugin.InputP
 		case istionetworking.ListenerProtocolHTTP:
 			fcOpt.httpOpts = configgen.buildSidecarInboundHTTPListenerOptsForPortOrUDS(in.Node, in, clusterName)
 		case istionetworking.ListenerProtocolTCP:
-			fcOpt.networkFilters = buildInboundNetworkFilters(in.Push, in.ServiceInstance, clusterName)
+			fcOpt.networkFilters = buildInboundNetworkFilters(in.Push, in.Node, in.ServiceInstance, clusterName)
 		case istionetworking.ListenerProtocolAuto:
 			fcOpt.httpOpts = configgen.buildSidecarInboundHTTPListenerOptsForPortOrUDS(in.Node, in, clusterName)
-			fcOpt.networkFilters = buildInboundNetworkFilters(in.Push, in.ServiceInstance, clusterName)
+			fcOpt.networkFilters = buildInboundNetworkFilters(in.Push, in.Node, in.ServiceInstance, clusterName)
 		}
 		fcOpt.filterChainName = model.VirtualInboundListenerName
 		if opt.fc.ListenerProtocol == istionetworking.ListenerProtocolHTTP {
@@ -0,0 +1,213 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/pkg/log"
+)
+
+// outboundListenerCache includes the variables that can influence the outbound listeners of a sidecar.
+// EnvoyFilters are not included, as they are applied to the listeners after they are read from the cache.
+// Implements XdsCacheEntry interface.
+type outboundListenerCache struct {
+	// proxy related cache fields
+	metadataHash string // hash of the proxy metadata, excluding the instance IPs
+	dnsDomain    string
+	ipv4         bool
+	ipv6         bool
+	// proxyIP is only set if the proxy sees headless services, for which we skip building a listener for
+	// the proxy itself
+	proxyIP string
+
+	// sidecar scope related cache fields
+	egressListeners       []*networking.IstioEgressListener
+	outboundTrafficPolicy *networking.OutboundTrafficPolicy
+
+	// Dependent configs
+	services        []*model.Service
+	virtualServices []config.Config
+	// delegateVirtualServices are the delegates merged into virtualServices
+	delegateVirtualServices []model.ConfigKey
+	// destinationServices are the services referenced by tcp and tls routes, which need not be part of services
+	destinationServices []*model.Service
+}
+
+func newOutboundListenerCache(node *model.Proxy, push *model.PushContext) *outboundListenerCache {
+	if node.SidecarScope == nil || node.Metadata == nil {
+		return nil
+	}
+	// Instance IPs differ for every replica of a workload, and are not used for outbound listeners.
+	meta := *node.Metadata
+	meta.InstanceIPs = nil
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return nil
+	}
+	sum := md5.Sum(b)
+
+	out := &outboundListenerCache{
+		metadataHash:          hex.EncodeToString(sum[:]),
+		dnsDomain:             node.DNSDomain,
+		ipv4:                  node.SupportsIPv4(),
+		ipv6:                  node.SupportsIPv6(),
+		outboundTrafficPolicy: node.SidecarScope.OutboundTrafficPolicy,
+	}
+	destinations := map[host.Name]struct{}{}
+	for _, egressListener := range node.SidecarScope.EgressListeners {
+		out.egressListeners = append(out.egressListeners, egressListener.IstioListener)
+		for _, svc := range egressListener.Services() {
+			if svc.Resolution == model.Passthrough && len(node.IPAddresses) > 0 {
+				out.proxyIP = node.IPAddresses[0]
+			}
+			out.services = append(out.services, svc)
+		}
+		virtualServices := egressListener.VirtualServices()
+		out.virtualServices = append(out.virtualServices, virtualServices...)
+		out.delegateVirtualServices = append(out.delegateVirtualServices, push.DelegateVirtualServicesConfigKey(virtualServices)...)
+		for _, vs := range virtualServices {
+			spec := vs.Spec.(*networking.VirtualService)
+			for _, tcp := range spec.Tcp {
+				for _, dst := range tcp.Route {
+					destinations[host.Name(dst.GetDestination().GetHost())] = struct{}{}
+				}
+			}
+			for _, tls := range spec.Tls {
+				for _, dst := range tls.Route {
+					destinations[host.Name(dst.GetDestination().GetHost())] = struct{}{}
+				}
+			}
+		}
+	}
+	for hostname := range destinations {
+		if svc := push.ServiceForHostname(node, hostname); svc != nil {
+			out.destinationServices = append(out.destinationServices, svc)
+		}
+	}
+	// sort destination services, as they are part of the key
+	sort.Slice(out.destinationServices, func(i, j int) bool {
+		return out.destinationServices[i].Hostname < out.destinationServices[j].Hostname
+	})
+	return out
+}
+
+func (l *outboundListenerCache) Cacheable() bool {
+	return l != nil
+}
+
+func (l *outboundListenerCache) DependentConfigs() []model.ConfigKey {
+	configs := make([]model.ConfigKey, 0, len(l.services)+len(l.destinationServices)+len(l.virtualServices)+
+		len(l.delegateVirtualServices))
+	for _, svc := range l.services {
+		configs = append(configs, model.ConfigKey{Kind: gvk.ServiceEntry, Name: string(svc.Hostname), Namespace: svc.Attributes.Namespace})
+	}
+	for _, svc := range l.destinationServices {
+		configs = append(configs, model.ConfigKey{Kind: gvk.ServiceEntry, Name: string(svc.Hostname), Namespace: svc.Attributes.Namespace})
+	}
+	for _, vs := range l.virtualServices {
+		configs = append(configs, model.ConfigKey{Kind: gvk.VirtualService, Name: vs.Name, Namespace: vs.Namespace})
+	}
+	return append(configs, l.delegateVirtualServices...)
+}
+
+// DependentTypes includes Telemetry, as the access logging and tracing of a proxy are derived from all the
+// Telemetry resources selecting it rather than from a single config.
+func (l *outboundListenerCache) DependentTypes() []config.GroupVersionKind {
+	return []config.GroupVersionKind{gvk.Telemetry}
+}
+
+func (l *outboundListenerCache) TypeURL() string {
+	return v3.ListenerType
+}
+
+func (l *outboundListenerCache) Key() string {
+	params := []string{
+		l.metadataHash, l.dnsDomain, strconv.FormatBool(l.ipv4), strconv.FormatBool(l.ipv6), l.proxyIP,
+		l.outboundTrafficPolicy.String(),
+	}
+	for _, el := range l.egressListeners {
+		params = append(params, el.String())
+	}
+	for _, svc := range l.services {
+		params = append(params, string(svc.Hostname)+"/"+svc.Attributes.Namespace)
+	}
+	for _, svc := range l.destinationServices {
+		params = append(params, string(svc.Hostname)+"/"+svc.Attributes.Namespace)
+	}
+	for _, vs := range l.virtualServices {
+		params = append(params, vs.Name+"/"+vs.Namespace)
+	}
+	for _, vs := range l.delegateVirtualServices {
+		params = append(params, vs.Name+"/"+vs.Namespace)
+	}
+
+	hash := md5.New()
+	for _, param := range params {
+		hash.Write([]byte(param))
+	}
+	sum := hash.Sum(nil)
+	return hex.EncodeToString(sum)
+}
+
+// getCachedListeners returns a copy of the cached outbound listeners, so that callers are free to modify them.
+func getCachedListeners(cache model.XdsCache, key *outboundListenerCache) ([]*listener.Listener, bool) {
+	resource, f := cache.Get(key)
+	if !f {
+		return nil, false
+	}
+	wrapper := &discovery.DiscoveryResponse{}
+	if err := resource.Resource.UnmarshalTo(wrapper); err != nil {
+		log.Warnf("failed to unmarshal cached listeners: %v", err)
+		return nil, false
+	}
+	listeners := make([]*listener.Listener, 0, len(wrapper.Resources))
+	for _, r := range wrapper.Resources {
+		l := &listener.Listener{}
+		if err := r.UnmarshalTo(l); err != nil {
+			log.Warnf("failed to unmarshal cached listener: %v", err)
+			return nil, false
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, true
+}
+
+// addCachedListeners stores all outbound listeners as a single entry, wrapped in a DiscoveryResponse.
+func addCachedListeners(cache model.XdsCache, key *outboundListenerCache, req *model.PushRequest, listeners []*listener.Listener) {
+	resources := make([]*anypb.Any, 0, len(listeners))
+	for _, l := range listeners {
+		resources = append(resources, util.MessageToAny(l))
+	}
+	cache.Add(key, req, &discovery.Resource{
+		Name:     "outbound",
+		Resource: util.MessageToAny(&discovery.DiscoveryResponse{TypeUrl: v3.ListenerType, Resources: resources}),
+	})
+}
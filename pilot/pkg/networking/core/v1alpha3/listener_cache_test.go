@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+const listenerCacheConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  addresses:
+  - 1.2.3.4
+  ports:
+  - number: 9000
+    name: tcp
+    protocol: TCP
+  resolution: STATIC
+  endpoints:
+  - address: 2.3.4.5
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: vs
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  tcp:
+  - route:
+    - destination:
+        host: example.com
+        subset: v1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: ef
+  namespace: default
+spec:
+  configPatches:
+  - applyTo: LISTENER
+    match:
+      context: SIDECAR_OUTBOUND
+    patch:
+      operation: ADD
+      value:
+        name: new-outbound-listener
+`
+
+func TestOutboundListenerCache(t *testing.T) {
+	defaultValue := features.EnableLDSCaching
+	features.EnableLDSCaching = true
+	defer func() { features.EnableLDSCaching = defaultValue }()
+
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: listenerCacheConfig})
+	cache := model.NewXdsCache()
+	cg.ConfigGen.Cache = cache
+	build := func(p *model.Proxy) []*listener.Listener {
+		t.Helper()
+		listeners := cg.ConfigGen.BuildListeners(cg.SetupProxy(p), &model.PushRequest{Push: cg.PushContext(), Start: time.Now()})
+		if xdstest.ExtractListener("1.2.3.4_9000", listeners) == nil {
+			t.Fatalf("expected outbound listener, got %v", xdstest.ExtractListenerNames(listeners))
+		}
+		if xdstest.ExtractListener("new-outbound-listener", listeners) == nil {
+			t.Fatalf("expected EnvoyFilter to be applied, got %v", xdstest.ExtractListenerNames(listeners))
+		}
+		return listeners
+	}
+
+	miss := build(nil)
+	if len(cache.Keys()) != 1 {
+		t.Fatalf("expected outbound listeners to be cached, got keys %v", cache.Keys())
+	}
+	// A replica of the same workload only differs by its IPs, so shares the cache entry
+	hit := build(&model.Proxy{IPAddresses: []string{"2.2.2.2"}, Metadata: &model.NodeMetadata{InstanceIPs: []string{"2.2.2.2"}}})
+	if diff := cmp.Diff(miss, hit, protocmp.Transform()); diff != "" {
+		t.Fatalf("cached listeners differ: %v", diff)
+	}
+	if len(cache.Keys()) != 1 {
+		t.Fatalf("expected cache entry to be shared, got keys %v", cache.Keys())
+	}
+	// Other workloads have their own entries
+	build(&model.Proxy{Metadata: &model.NodeMetadata{Labels: map[string]string{"app": "other"}}})
+	if len(cache.Keys()) != 2 {
+		t.Fatalf("expected cache entry per workload, got keys %v", cache.Keys())
+	}
+
+	cache.Clear(map[model.ConfigKey]struct{}{{Kind: gvk.DestinationRule, Name: "dr", Namespace: "default"}: {}})
+	if len(cache.Keys()) != 2 {
+		t.Fatalf("expected unrelated config to keep entries, got keys %v", cache.Keys())
+	}
+	cache.Clear(map[model.ConfigKey]struct{}{{Kind: gvk.VirtualService, Name: "vs", Namespace: "default"}: {}})
+	if len(cache.Keys()) != 0 {
+		t.Fatalf("expected VirtualService to clear entries, got keys %v", cache.Keys())
+	}
+	build(nil)
+	cache.Clear(map[model.ConfigKey]struct{}{{Kind: gvk.ServiceEntry, Name: "example.com", Namespace: "default"}: {}})
+	if len(cache.Keys()) != 0 {
+		t.Fatalf("expected service to clear entries, got keys %v", cache.Keys())
+	}
+}
+
+func TestOutboundListenerCacheKey(t *testing.T) {
+	base := &outboundListenerCache{
+		services: []*model.Service{{Hostname: "foo.com", Attributes: model.ServiceAttributes{Namespace: "ns"}}},
+	}
+	withDestination := &outboundListenerCache{
+		services:            base.services,
+		destinationServices: []*model.Service{{Hostname: "bar.com", Attributes: model.ServiceAttributes{Namespace: "ns"}}},
+	}
+	if base.Key() == withDestination.Key() {
+		t.Fatalf("expected destination services to change the key")
+	}
+}
+
+func BenchmarkOutboundListeners(b *testing.B) {
+	defaultValue := features.EnableLDSCaching
+	defer func() { features.EnableLDSCaching = defaultValue }()
+
+	configs := []string{listenerCacheConfig}
+	for i := 0; i < 100; i++ {
+		configs = append(configs, fmt.Sprintf(`
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se-%d
+  namespace: default
+spec:
+  hosts:
+  - svc-%d.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  - number: %d
+    name: tcp
+    protocol: TCP
+  resolution: DNS
+`, i, i, 10000+i))
+	}
+	cg := NewConfigGenTest(b, TestOptions{ConfigString: strings.Join(configs, "\n---\n")})
+	proxy := cg.SetupProxy(nil)
+	req := &model.PushRequest{Push: cg.PushContext(), Start: time.Now()}
+
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%v", cached), func(b *testing.B) {
+			features.EnableLDSCaching = cached
+			cg.ConfigGen.Cache = model.NewXdsCache()
+			// fill the cache, so that only hits are measured
+			cg.ConfigGen.BuildListeners(proxy, req)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				cg.ConfigGen.BuildListeners(proxy, req)
+			}
+		})
+	}
+}
@@ -75,8 +75,13 @@ func ApplyLocalityLBSetting(
 		// Failover needs outlier detection, otherwise Envoy will never drop down to a lower priority.
 		// Do not apply default failover when locality LB is disabled.
 	} else if enableFailover && (localityLB.Enabled == nil || localityLB.Enabled.Value) {
-		if len(localityLB.FailoverPriority) > 0 {
-			applyPriorityFailover(loadAssignment, wrappedLocalityLbEndpoints, proxyLabels, localityLB.FailoverPriority)
+		if len(localityLB.FailoverPriority) > 0 &&
+			applyPriorityFailover(loadAssignment, wrappedLocalityLbEndpoints, proxyLabels, localityLB.FailoverPriority) {
+			// If failover is explicitly configured alongside failover priority, endpoints that share the
+			// same failover priority labels are further ordered by their locality relative to the proxy.
+			if len(localityLB.Failover) > 0 {
+				applyLocalityFailover(locality, loadAssignment, localityLB.Failover)
+			}
 			return
 		}
 		applyLocalityFailover(locality, loadAssignment, localityLB.Failover)
@@ -169,6 +174,9 @@ func applyLocalityFailover(
 				}
 			}
 		}
+		// There are at most 5 locality priorities (0-4), so scale any priority already assigned by
+		// failover priority labels to keep it as the most significant part of the final priority.
+		priority += int(localityEndpoint.Priority) * 5
 		loadAssignment.Endpoints[i].Priority = uint32(priority)
 		priorityMap[priority] = append(priorityMap[priority], i)
 	}
@@ -201,14 +209,16 @@ type WrappedLocalityLbEndpoints struct {
 	LocalityLbEndpoints *endpoint.LocalityLbEndpoints
 }
 
-// set loadbalancing priority by failover priority label
+// set loadbalancing priority by failover priority label.
+// It returns false if the priorities could not be computed, e.g. the proxy has no labels
+// or the original IstioEndpoints are unknown, in which case locality failover should be used.
 func applyPriorityFailover(
 	loadAssignment *endpoint.ClusterLoadAssignment,
 	wrappedLocalityLbEndpoints []*WrappedLocalityLbEndpoints,
 	proxyLabels map[string]string,
-	failoverPriorities []string) {
+	failoverPriorities []string) bool {
 	if len(proxyLabels) == 0 || len(wrappedLocalityLbEndpoints) == 0 {
-		return
+		return false
 	}
 	priorityMap := make(map[int][]int, len(failoverPriorities))
 	localityLbEndpoints := []*endpoint.LocalityLbEndpoints{}
@@ -238,6 +248,7 @@ func applyPriorityFailover(
 		}
 	}
 	loadAssignment.Endpoints = localityLbEndpoints
+	return true
 }
 
 // set loadbalancing priority by failover priority label.
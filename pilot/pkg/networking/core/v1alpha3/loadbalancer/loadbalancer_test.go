@@ -496,6 +496,53 @@ func TestApplyLocalitySetting(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("FailoverPriority with Failover", func(t *testing.T) {
+		g := NewWithT(t)
+		lbSetting := &networking.LocalityLoadBalancerSetting{
+			FailoverPriority: []string{"topology.istio.io/network"},
+			Failover: []*networking.LocalityLoadBalancerSetting_Failover{
+				{
+					From: "region1",
+					To:   "region2",
+				},
+			},
+		}
+		proxyLabels := map[string]string{"topology.istio.io/network": "n1"}
+		cluster := buildFakeCluster()
+		ApplyLocalityLBSetting(cluster.LoadAssignment, buildWrappedLocalityLbEndpoints(), locality, proxyLabels, lbSetting, true)
+
+		got := map[string]uint32{}
+		for _, ep := range cluster.LoadAssignment.Endpoints {
+			for _, lbEp := range ep.LbEndpoints {
+				got[lbEp.GetEndpoint().GetAddress().GetSocketAddress().GetAddress()] = ep.Priority
+			}
+		}
+		// same network endpoints come first, ordered by locality, before falling back to other networks.
+		g.Expect(got).To(Equal(map[string]uint32{
+			"1.1.1.1": 0, // network n1, same locality
+			"3.3.3.3": 1, // network n1, failover region
+			"2.2.2.2": 2, // network n2, same locality
+			"4.4.4.4": 3, // network n2, failover region
+		}))
+	})
+
+	t.Run("FailoverPriority without proxy labels", func(t *testing.T) {
+		g := NewWithT(t)
+		lbSetting := &networking.LocalityLoadBalancerSetting{
+			FailoverPriority: []string{"topology.istio.io/network"},
+		}
+		cluster := buildSmallCluster()
+		ApplyLocalityLBSetting(cluster.LoadAssignment, buildWrappedLocalityLbEndpoints(), locality, nil, lbSetting, true)
+		// falls back to locality failover
+		for _, localityEndpoint := range cluster.LoadAssignment.Endpoints {
+			if localityEndpoint.Locality.Region == locality.Region {
+				g.Expect(localityEndpoint.Priority).To(Equal(uint32(0)))
+			} else {
+				g.Expect(localityEndpoint.Priority).To(Equal(uint32(1)))
+			}
+		}
+	})
 }
 
 func TestGetLocalityLbSetting(t *testing.T) {
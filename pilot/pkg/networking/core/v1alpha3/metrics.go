@@ -0,0 +1,469 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	httpwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	networkwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/wasm/v3"
+	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	networking "istio.io/api/networking/v1alpha3"
+	telemetrypb "istio.io/api/telemetry/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/pkg/log"
+)
+
+const (
+	statsFilterName       = "istio.stats"
+	stackdriverFilterName = "istio.stackdriver"
+)
+
+// statsMetricNames maps the standard Istio metrics to the names used by the stats filter.
+var statsMetricNames = map[telemetrypb.MetricSelector_IstioMetric]string{
+	telemetrypb.MetricSelector_REQUEST_COUNT:          "requests_total",
+	telemetrypb.MetricSelector_REQUEST_DURATION:       "request_duration_milliseconds",
+	telemetrypb.MetricSelector_REQUEST_SIZE:           "request_bytes",
+	telemetrypb.MetricSelector_RESPONSE_SIZE:          "response_bytes",
+	telemetrypb.MetricSelector_TCP_OPENED_CONNECTIONS: "tcp_connections_opened_total",
+	telemetrypb.MetricSelector_TCP_CLOSED_CONNECTIONS: "tcp_connections_closed_total",
+	telemetrypb.MetricSelector_TCP_SENT_BYTES:         "tcp_sent_bytes_total",
+	telemetrypb.MetricSelector_TCP_RECEIVED_BYTES:     "tcp_received_bytes_total",
+	telemetrypb.MetricSelector_GRPC_REQUEST_MESSAGES:  "request_messages_total",
+	telemetrypb.MetricSelector_GRPC_RESPONSE_MESSAGES: "response_messages_total",
+}
+
+// stackdriverMetricNames maps the standard Istio metrics to the names used by the Stackdriver filter,
+// without the client/ or server/ prefix. The gRPC message counts are not exported to Stackdriver.
+var stackdriverMetricNames = map[telemetrypb.MetricSelector_IstioMetric]string{
+	telemetrypb.MetricSelector_REQUEST_COUNT:          "request_count",
+	telemetrypb.MetricSelector_REQUEST_DURATION:       "response_latencies",
+	telemetrypb.MetricSelector_REQUEST_SIZE:           "request_bytes",
+	telemetrypb.MetricSelector_RESPONSE_SIZE:          "response_bytes",
+	telemetrypb.MetricSelector_TCP_OPENED_CONNECTIONS: "connection_open_count",
+	telemetrypb.MetricSelector_TCP_CLOSED_CONNECTIONS: "connection_close_count",
+	telemetrypb.MetricSelector_TCP_SENT_BYTES:         "sent_bytes_count",
+	telemetrypb.MetricSelector_TCP_RECEIVED_BYTES:     "received_bytes_count",
+}
+
+// metricOverride is the result of applying all the overrides selecting a single metric.
+type metricOverride struct {
+	disabled bool
+	// tags maps the tag name to its value expression, or nil if the tag is removed.
+	tags map[string]*string
+}
+
+// computeMetricOverrides applies the overrides in order, returning the resulting override for each
+// selected metric. Standard metrics are keyed by their enum name, custom metrics by their name.
+func computeMetricOverrides(overrides []*telemetrypb.MetricsOverrides, mode telemetrypb.WorkloadMode) map[string]*metricOverride {
+	out := map[string]*metricOverride{}
+	for _, o := range overrides {
+		if m := o.GetMatch().GetMode(); m != telemetrypb.WorkloadMode_CLIENT_AND_SERVER && m != mode {
+			continue
+		}
+		var keys []string
+		switch match := o.GetMatch().GetMetricMatch().(type) {
+		case *telemetrypb.MetricSelector_CustomMetric:
+			keys = []string{match.CustomMetric}
+		case *telemetrypb.MetricSelector_Metric:
+			if match.Metric != telemetrypb.MetricSelector_ALL_METRICS {
+				keys = []string{match.Metric.String()}
+				break
+			}
+			keys = allIstioMetrics()
+		default:
+			keys = allIstioMetrics()
+		}
+		for _, key := range keys {
+			mo, f := out[key]
+			if !f {
+				mo = &metricOverride{tags: map[string]*string{}}
+				out[key] = mo
+			}
+			if o.GetDisabled() != nil {
+				mo.disabled = o.GetDisabled().GetValue()
+			}
+			for tag, to := range o.GetTagOverrides() {
+				if to.GetOperation() == telemetrypb.MetricsOverrides_TagOverride_REMOVE {
+					mo.tags[tag] = nil
+					continue
+				}
+				value := to.GetValue()
+				mo.tags[tag] = &value
+			}
+		}
+	}
+	return out
+}
+
+func allIstioMetrics() []string {
+	out := make([]string, 0, len(statsMetricNames))
+	for m := range statsMetricNames {
+		out = append(out, m.String())
+	}
+	return out
+}
+
+// statsMetricName returns the stats filter name of the metric key built by computeMetricOverrides.
+func statsMetricName(key string) string {
+	if m, f := telemetrypb.MetricSelector_IstioMetric_value[key]; f {
+		return statsMetricNames[telemetrypb.MetricSelector_IstioMetric(m)]
+	}
+	return key
+}
+
+// stackdriverMetricName returns the Stackdriver name of the metric key built by computeMetricOverrides,
+// or an empty string if the metric is not exported to Stackdriver.
+func stackdriverMetricName(key string, mode telemetrypb.WorkloadMode) string {
+	m, f := telemetrypb.MetricSelector_IstioMetric_value[key]
+	if !f {
+		return key
+	}
+	metric := telemetrypb.MetricSelector_IstioMetric(m)
+	name := stackdriverMetricNames[metric]
+	if name == "" {
+		return ""
+	}
+	if mode == telemetrypb.WorkloadMode_CLIENT {
+		if metric == telemetrypb.MetricSelector_REQUEST_DURATION {
+			name = "roundtrip_latencies"
+		}
+		return "client/" + name
+	}
+	return "server/" + name
+}
+
+// statsMetricConfig is the metric configuration of the stats filter.
+type statsMetricConfig struct {
+	Name         string            `json:"name,omitempty"`
+	Dimensions   map[string]string `json:"dimensions,omitempty"`
+	TagsToRemove []string          `json:"tags_to_remove,omitempty"`
+	Drop         bool              `json:"drop,omitempty"`
+}
+
+// statsConfig is the configuration of the stats filter.
+type statsConfig struct {
+	Debug                     string               `json:"debug"`
+	StatPrefix                string               `json:"stat_prefix"`
+	DisableHostHeaderFallback bool                 `json:"disable_host_header_fallback,omitempty"`
+	Metrics                   []*statsMetricConfig `json:"metrics,omitempty"`
+}
+
+// stackdriverMetricOverride is the metric configuration of the Stackdriver filter.
+type stackdriverMetricOverride struct {
+	Drop         bool              `json:"drop,omitempty"`
+	TagOverrides map[string]string `json:"tag_overrides,omitempty"`
+}
+
+// stackdriverConfig is the configuration of the Stackdriver filter.
+type stackdriverConfig struct {
+	DisableServerAccessLogging bool                                  `json:"disable_server_access_logging,omitempty"`
+	DisableHostHeaderFallback  bool                                  `json:"disable_host_header_fallback,omitempty"`
+	MetricsOverrides           map[string]*stackdriverMetricOverride `json:"metrics_overrides,omitempty"`
+}
+
+// metricsFilterConfig is the configuration of a single metrics filter for a provider.
+type metricsFilterConfig struct {
+	name    string
+	rootID  string
+	vmID    string
+	runtime string
+	code    *core.AsyncDataSource
+	config  string
+}
+
+// buildHTTPMetricsFilters builds the HTTP stats filters for the metrics configured by the Telemetry API.
+// No filters are built if the Telemetry API does not configure metrics for the proxy.
+func buildHTTPMetricsFilters(push *model.PushContext, proxy *model.Proxy, class ListenerClass) []*hcm.HttpFilter {
+	var out []*hcm.HttpFilter
+	for _, cfg := range buildMetricsFilterConfigs(push, proxy, class, false) {
+		out = append(out, &hcm.HttpFilter{
+			Name: cfg.name,
+			ConfigType: &hcm.HttpFilter_TypedConfig{
+				TypedConfig: util.MessageToAny(&httpwasm.Wasm{Config: cfg.pluginConfig()}),
+			},
+		})
+	}
+	return out
+}
+
+// buildTCPMetricsFilters builds the TCP stats filters for the metrics configured by the Telemetry API.
+// No filters are built if the Telemetry API does not configure metrics for the proxy.
+func buildTCPMetricsFilters(push *model.PushContext, proxy *model.Proxy, class ListenerClass) []*listener.Filter {
+	var out []*listener.Filter
+	for _, cfg := range buildMetricsFilterConfigs(push, proxy, class, true) {
+		out = append(out, &listener.Filter{
+			Name: cfg.name,
+			ConfigType: &listener.Filter_TypedConfig{
+				TypedConfig: util.MessageToAny(&networkwasm.Wasm{Config: cfg.pluginConfig()}),
+			},
+		})
+	}
+	return out
+}
+
+// outboundListenerClass returns the class of the listeners handling the outbound traffic of the proxy.
+func outboundListenerClass(node *model.Proxy) ListenerClass {
+	if node.Type == model.Router {
+		return ListenerClassGateway
+	}
+	return ListenerClassSidecarOutbound
+}
+
+func buildMetricsFilterConfigs(push *model.PushContext, proxy *model.Proxy, class ListenerClass, tcp bool) []*metricsFilterConfig {
+	spec := push.Telemetry.EffectiveTelemetry(proxy)
+	if len(spec.GetMetrics()) == 0 {
+		// No Telemetry API configured, metrics are configured by the EnvoyFilters installed with Istio
+		return nil
+	}
+	// Only use the first Metrics for now (all that is supported)
+	metrics := spec.Metrics[0]
+
+	mode := telemetrypb.WorkloadMode_CLIENT
+	if class == ListenerClassSidecarInbound {
+		mode = telemetrypb.WorkloadMode_SERVER
+	}
+	overrides := computeMetricOverrides(metrics.GetOverrides(), mode)
+
+	providerNames := push.Mesh.GetDefaultProviders().GetMetrics()
+	if len(metrics.GetProviders()) > 0 {
+		providerNames = nil
+		for _, p := range metrics.GetProviders() {
+			providerNames = append(providerNames, p.GetName())
+		}
+	}
+
+	legacy := legacyMetricsFilters(push, proxy, class, tcp)
+	var out []*metricsFilterConfig
+	for _, providerName := range providerNames {
+		provider := lookupExtensionProvider(push.Mesh, providerName)
+		if provider == nil {
+			log.Debugf("metrics provider %q not found", providerName)
+			continue
+		}
+		var cfg *metricsFilterConfig
+		switch provider.Provider.(type) {
+		case *meshconfig.MeshConfig_ExtensionProvider_Prometheus:
+			cfg = buildStatsFilterConfig(overrides, class, tcp)
+		case *meshconfig.MeshConfig_ExtensionProvider_Stackdriver:
+			cfg = buildStackdriverFilterConfig(overrides, class, mode)
+		default:
+			log.Debugf("unsupported metrics provider %v: %T", providerName, provider.Provider)
+			continue
+		}
+		if legacy[cfg.name] {
+			log.Debugf("skipping %s filter of metrics provider %v for %s: already inserted by an EnvoyFilter",
+				cfg.name, providerName, proxy.ID)
+			continue
+		}
+		out = append(out, cfg)
+	}
+	return out
+}
+
+// legacyMetricsFilters returns the names of the metrics filters inserted by EnvoyFilters, such as the ones installed
+// with Istio, into the listeners of the class. The filters configured by the Telemetry API would duplicate them, so
+// they are skipped until the EnvoyFilters are removed.
+func legacyMetricsFilters(push *model.PushContext, proxy *model.Proxy, class ListenerClass, tcp bool) map[string]bool {
+	efw := push.EnvoyFilters(proxy)
+	if efw == nil {
+		return nil
+	}
+	applyTo, patchContext := networking.EnvoyFilter_HTTP_FILTER, networking.EnvoyFilter_SIDECAR_OUTBOUND
+	if tcp {
+		applyTo = networking.EnvoyFilter_NETWORK_FILTER
+	}
+	switch class {
+	case ListenerClassSidecarInbound:
+		patchContext = networking.EnvoyFilter_SIDECAR_INBOUND
+	case ListenerClassGateway:
+		patchContext = networking.EnvoyFilter_GATEWAY
+	}
+	out := map[string]bool{}
+	for _, cp := range efw.Patches[applyTo] {
+		if c := cp.Match.GetContext(); c != networking.EnvoyFilter_ANY && c != patchContext {
+			continue
+		}
+		var name string
+		switch v := cp.Value.(type) {
+		case *hcm.HttpFilter:
+			name = v.GetName()
+		case *listener.Filter:
+			name = v.GetName()
+		}
+		if name == statsFilterName || name == stackdriverFilterName {
+			out[name] = true
+		}
+	}
+	return out
+}
+
+func lookupExtensionProvider(mesh *meshconfig.MeshConfig, name string) *meshconfig.MeshConfig_ExtensionProvider {
+	for _, p := range mesh.GetExtensionProviders() {
+		if strings.EqualFold(p.Name, name) {
+			return p
+		}
+	}
+	return nil
+}
+
+// buildStatsFilterConfig builds the configuration of the stats filter used by the Prometheus provider. The
+// defaults match the configuration of the EnvoyFilters installed with Istio.
+func buildStatsFilterConfig(overrides map[string]*metricOverride, class ListenerClass, tcp bool) *metricsFilterConfig {
+	cfg := statsConfig{
+		Debug:      "false",
+		StatPrefix: "istio",
+	}
+	rootID, vmID := "stats_outbound", "stats_outbound"
+	if class == ListenerClassSidecarInbound {
+		rootID, vmID = "stats_inbound", "stats_inbound"
+		cfg.Metrics = append(cfg.Metrics, &statsMetricConfig{
+			Dimensions: map[string]string{
+				"destination_cluster": "node.metadata['CLUSTER_ID']",
+				"source_cluster":      "downstream_peer.cluster_id",
+			},
+		})
+	}
+	if !tcp && class != ListenerClassSidecarOutbound {
+		cfg.DisableHostHeaderFallback = true
+	}
+	if tcp {
+		vmID = "tcp_" + vmID
+	}
+
+	metricConfigs := make([]*statsMetricConfig, 0, len(overrides))
+	for key, o := range overrides {
+		mc := &statsMetricConfig{Name: statsMetricName(key), Drop: o.disabled}
+		for tag, value := range o.tags {
+			if value == nil {
+				mc.TagsToRemove = append(mc.TagsToRemove, tag)
+				continue
+			}
+			if mc.Dimensions == nil {
+				mc.Dimensions = map[string]string{}
+			}
+			mc.Dimensions[tag] = *value
+		}
+		sort.Strings(mc.TagsToRemove)
+		metricConfigs = append(metricConfigs, mc)
+	}
+	sort.Slice(metricConfigs, func(i, j int) bool {
+		return metricConfigs[i].Name < metricConfigs[j].Name
+	})
+	cfg.Metrics = append(cfg.Metrics, metricConfigs...)
+
+	out := &metricsFilterConfig{
+		name:   statsFilterName,
+		rootID: rootID,
+		vmID:   vmID,
+		config: marshalMetricsConfig(cfg),
+	}
+	if features.EnableWasmTelemetry {
+		out.runtime = "envoy.wasm.runtime.v8"
+		out.code = localDataSource(&core.DataSource{
+			Specifier: &core.DataSource_Filename{Filename: "/etc/istio/extensions/stats-filter.compiled.wasm"},
+		})
+	} else {
+		out.runtime = "envoy.wasm.runtime.null"
+		out.code = localDataSource(&core.DataSource{
+			Specifier: &core.DataSource_InlineString{InlineString: "envoy.wasm.stats"},
+		})
+	}
+	return out
+}
+
+// buildStackdriverFilterConfig builds the configuration of the Stackdriver filter. Stackdriver does not support
+// removing tags, so removed tags are ignored.
+func buildStackdriverFilterConfig(overrides map[string]*metricOverride, class ListenerClass,
+	mode telemetrypb.WorkloadMode) *metricsFilterConfig {
+	cfg := stackdriverConfig{}
+	rootID := "stackdriver_outbound"
+	switch class {
+	case ListenerClassSidecarInbound:
+		rootID = "stackdriver_inbound"
+		cfg.DisableServerAccessLogging = true
+		cfg.DisableHostHeaderFallback = true
+	case ListenerClassGateway:
+		cfg.DisableHostHeaderFallback = true
+	}
+
+	for key, o := range overrides {
+		name := stackdriverMetricName(key, mode)
+		if name == "" {
+			continue
+		}
+		mo := &stackdriverMetricOverride{Drop: o.disabled}
+		for tag, value := range o.tags {
+			if value == nil {
+				log.Debugf("stackdriver does not support removing tag %s from %s", tag, name)
+				continue
+			}
+			if mo.TagOverrides == nil {
+				mo.TagOverrides = map[string]string{}
+			}
+			mo.TagOverrides[tag] = *value
+		}
+		if cfg.MetricsOverrides == nil {
+			cfg.MetricsOverrides = map[string]*stackdriverMetricOverride{}
+		}
+		cfg.MetricsOverrides[name] = mo
+	}
+
+	return &metricsFilterConfig{
+		name:    stackdriverFilterName,
+		rootID:  rootID,
+		vmID:    rootID,
+		runtime: "envoy.wasm.runtime.null",
+		code: localDataSource(&core.DataSource{
+			Specifier: &core.DataSource_InlineString{InlineString: "envoy.wasm.null.stackdriver"},
+		}),
+		config: marshalMetricsConfig(cfg),
+	}
+}
+
+func localDataSource(ds *core.DataSource) *core.AsyncDataSource {
+	return &core.AsyncDataSource{Specifier: &core.AsyncDataSource_Local{Local: ds}}
+}
+
+func marshalMetricsConfig(cfg interface{}) string {
+	// the configuration only holds strings, bools and maps of strings, so it can always be marshaled
+	b, _ := json.Marshal(cfg)
+	return string(b)
+}
+
+func (c *metricsFilterConfig) pluginConfig() *wasm.PluginConfig {
+	return &wasm.PluginConfig{
+		RootId: c.rootID,
+		Vm: &wasm.PluginConfig_VmConfig{
+			VmConfig: &wasm.VmConfig{
+				VmId:             c.vmID,
+				Runtime:          c.runtime,
+				AllowPrecompiled: c.runtime == "envoy.wasm.runtime.v8",
+				Code:             c.code,
+			},
+		},
+		Configuration: util.MessageToAny(wrapperspb.String(c.config)),
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	httpwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	networkwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/wasm/v3"
+	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
+	"github.com/gogo/protobuf/types"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	tpb "istio.io/api/telemetry/v1alpha1"
+	"istio.io/api/type/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestBuildHTTPMetricsFilters(t *testing.T) {
+	rootMetrics := model.Telemetry{
+		Name:      "root",
+		Namespace: "istio-system",
+		Spec: &tpb.Telemetry{
+			Metrics: []*tpb.Metrics{{
+				Providers: []*tpb.ProviderRef{{Name: "prometheus"}},
+				Overrides: []*tpb.MetricsOverrides{{
+					TagOverrides: map[string]*tpb.MetricsOverrides_TagOverride{
+						"request_protocol": {Operation: tpb.MetricsOverrides_TagOverride_REMOVE},
+					},
+				}},
+			}},
+		},
+	}
+	fooMetrics := model.Telemetry{
+		Name:      "foo",
+		Namespace: "default",
+		Spec: &tpb.Telemetry{
+			Selector: &v1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "foo"}},
+			Metrics: []*tpb.Metrics{{
+				Overrides: []*tpb.MetricsOverrides{
+					{
+						Match: &tpb.MetricSelector{
+							MetricMatch: &tpb.MetricSelector_Metric{Metric: tpb.MetricSelector_REQUEST_COUNT},
+							Mode:        tpb.WorkloadMode_SERVER,
+						},
+						TagOverrides: map[string]*tpb.MetricsOverrides_TagOverride{
+							"request_protocol": {Value: "request.protocol"},
+							"request_method":   {Value: "request.method"},
+						},
+					},
+					{
+						Match: &tpb.MetricSelector{
+							MetricMatch: &tpb.MetricSelector_Metric{Metric: tpb.MetricSelector_REQUEST_SIZE},
+						},
+						Disabled: &types.BoolValue{Value: true},
+					},
+				},
+			}},
+		},
+	}
+	stackdriverMetrics := model.Telemetry{
+		Name:      "stackdriver",
+		Namespace: "default",
+		Spec: &tpb.Telemetry{
+			Metrics: []*tpb.Metrics{{
+				Providers: []*tpb.ProviderRef{{Name: "stackdriver"}},
+				Overrides: []*tpb.MetricsOverrides{{
+					Match: &tpb.MetricSelector{
+						MetricMatch: &tpb.MetricSelector_Metric{Metric: tpb.MetricSelector_REQUEST_DURATION},
+						Mode:        tpb.WorkloadMode_CLIENT,
+					},
+					Disabled: &types.BoolValue{Value: true},
+				}},
+			}},
+		},
+	}
+	mesh := &meshconfig.MeshConfig{
+		ExtensionProviders: []*meshconfig.MeshConfig_ExtensionProvider{
+			{
+				Name:     "prometheus",
+				Provider: &meshconfig.MeshConfig_ExtensionProvider_Prometheus{},
+			},
+			{
+				Name:     "stackdriver",
+				Provider: &meshconfig.MeshConfig_ExtensionProvider_Stackdriver{},
+			},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		telemetries []model.Telemetry
+		labels      map[string]string
+		class       ListenerClass
+		want        map[string]string
+	}{
+		{
+			name:  "no telemetry api",
+			class: ListenerClassSidecarOutbound,
+		},
+		{
+			name:        "root overrides outbound",
+			telemetries: []model.Telemetry{rootMetrics},
+			class:       ListenerClassSidecarOutbound,
+			want: map[string]string{
+				statsFilterName: `{"debug":"false","stat_prefix":"istio","metrics":[` +
+					`{"name":"request_bytes","tags_to_remove":["request_protocol"]},` +
+					`{"name":"request_duration_milliseconds","tags_to_remove":["request_protocol"]},` +
+					`{"name":"request_messages_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"requests_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"response_bytes","tags_to_remove":["request_protocol"]},` +
+					`{"name":"response_messages_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"tcp_connections_closed_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"tcp_connections_opened_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"tcp_received_bytes_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"tcp_sent_bytes_total","tags_to_remove":["request_protocol"]}]}`,
+			},
+		},
+		{
+			name: "workload overrides inbound",
+			telemetries: []model.Telemetry{{
+				Name:      "root",
+				Namespace: "istio-system",
+				Spec: &tpb.Telemetry{
+					Metrics: []*tpb.Metrics{{Providers: []*tpb.ProviderRef{{Name: "prometheus"}}}},
+				},
+			}, fooMetrics},
+			labels: map[string]string{"app": "foo"},
+			class:  ListenerClassSidecarInbound,
+			want: map[string]string{
+				statsFilterName: `{"debug":"false","stat_prefix":"istio","disable_host_header_fallback":true,"metrics":[` +
+					`{"dimensions":{"destination_cluster":"node.metadata['CLUSTER_ID']","source_cluster":"downstream_peer.cluster_id"}},` +
+					`{"name":"request_bytes","drop":true},` +
+					`{"name":"requests_total","dimensions":{"request_method":"request.method","request_protocol":"request.protocol"}}]}`,
+			},
+		},
+		{
+			name:        "workload client mode skips server overrides",
+			telemetries: []model.Telemetry{rootMetrics, fooMetrics},
+			labels:      map[string]string{"app": "foo"},
+			class:       ListenerClassGateway,
+			want: map[string]string{
+				statsFilterName: `{"debug":"false","stat_prefix":"istio","disable_host_header_fallback":true,"metrics":[` +
+					`{"name":"request_bytes","tags_to_remove":["request_protocol"],"drop":true},` +
+					`{"name":"request_duration_milliseconds","tags_to_remove":["request_protocol"]},` +
+					`{"name":"request_messages_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"requests_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"response_bytes","tags_to_remove":["request_protocol"]},` +
+					`{"name":"response_messages_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"tcp_connections_closed_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"tcp_connections_opened_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"tcp_received_bytes_total","tags_to_remove":["request_protocol"]},` +
+					`{"name":"tcp_sent_bytes_total","tags_to_remove":["request_protocol"]}]}`,
+			},
+		},
+		{
+			name:        "stackdriver provider",
+			telemetries: []model.Telemetry{stackdriverMetrics},
+			class:       ListenerClassSidecarOutbound,
+			want: map[string]string{
+				stackdriverFilterName: `{"metrics_overrides":{"client/roundtrip_latencies":{"drop":true}}}`,
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			push := &model.PushContext{
+				Mesh: mesh,
+				Telemetry: &model.Telemetries{
+					NamespaceToTelemetries: map[string][]model.Telemetry{},
+					RootNamespace:          "istio-system",
+				},
+			}
+			for _, tel := range tt.telemetries {
+				push.Telemetry.NamespaceToTelemetries[tel.Namespace] = append(push.Telemetry.NamespaceToTelemetries[tel.Namespace], tel)
+			}
+			proxy := &model.Proxy{ConfigNamespace: "default", Metadata: &model.NodeMetadata{Labels: tt.labels}}
+
+			filters := buildHTTPMetricsFilters(push, proxy, tt.class)
+			if len(filters) != len(tt.want) {
+				t.Fatalf("expected %d filters, got %d", len(tt.want), len(filters))
+			}
+			for _, f := range filters {
+				w := &httpwasm.Wasm{}
+				if err := f.GetTypedConfig().UnmarshalTo(w); err != nil {
+					t.Fatal(err)
+				}
+				if got := pluginConfiguration(t, w.Config); got != tt.want[f.Name] {
+					t.Errorf("unexpected %s configuration:\n got: %s\nwant: %s", f.Name, got, tt.want[f.Name])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildTCPMetricsFilters(t *testing.T) {
+	push := &model.PushContext{
+		Mesh: &meshconfig.MeshConfig{
+			DefaultProviders: &meshconfig.MeshConfig_DefaultProviders{Metrics: []string{"prometheus"}},
+			ExtensionProviders: []*meshconfig.MeshConfig_ExtensionProvider{{
+				Name:     "prometheus",
+				Provider: &meshconfig.MeshConfig_ExtensionProvider_Prometheus{},
+			}},
+		},
+		Telemetry: &model.Telemetries{
+			NamespaceToTelemetries: map[string][]model.Telemetry{
+				"istio-system": {{
+					Name:      "root",
+					Namespace: "istio-system",
+					Spec:      &tpb.Telemetry{Metrics: []*tpb.Metrics{{}}},
+				}},
+			},
+			RootNamespace: "istio-system",
+		},
+	}
+	proxy := &model.Proxy{ConfigNamespace: "default", Metadata: &model.NodeMetadata{}}
+
+	filters := buildTCPMetricsFilters(push, proxy, ListenerClassSidecarOutbound)
+	if len(filters) != 1 {
+		t.Fatalf("expected one filter, got %d", len(filters))
+	}
+	w := &networkwasm.Wasm{}
+	if err := filters[0].GetTypedConfig().UnmarshalTo(w); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Config.GetVmConfig().GetVmId(); got != "tcp_stats_outbound" {
+		t.Errorf("unexpected vm id %q", got)
+	}
+	want := `{"debug":"false","stat_prefix":"istio"}`
+	if got := pluginConfiguration(t, w.Config); got != want {
+		t.Errorf("unexpected configuration:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestMetricsFiltersSkipLegacyEnvoyFilters(t *testing.T) {
+	telemetry := `
+apiVersion: telemetry.istio.io/v1alpha1
+kind: Telemetry
+metadata:
+  name: root
+  namespace: istio-system
+spec:
+  metrics:
+  - providers:
+    - name: prometheus
+`
+	legacy := `
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: stats-filter
+  namespace: istio-system
+spec:
+  configPatches:
+  - applyTo: HTTP_FILTER
+    match:
+      context: SIDECAR_OUTBOUND
+      listener:
+        filterChain:
+          filter:
+            name: envoy.filters.network.http_connection_manager
+            subFilter:
+              name: envoy.filters.http.router
+    patch:
+      operation: INSERT_BEFORE
+      value:
+        name: istio.stats
+`
+	mesh := &meshconfig.MeshConfig{
+		RootNamespace: "istio-system",
+		ExtensionProviders: []*meshconfig.MeshConfig_ExtensionProvider{{
+			Name:     "prometheus",
+			Provider: &meshconfig.MeshConfig_ExtensionProvider_Prometheus{},
+		}},
+	}
+	cases := []struct {
+		name    string
+		configs string
+		class   ListenerClass
+		want    int
+	}{
+		{"telemetry api", telemetry, ListenerClassSidecarOutbound, 1},
+		{"legacy filter", telemetry + "---" + legacy, ListenerClassSidecarOutbound, 0},
+		{"legacy filter of other context", telemetry + "---" + legacy, ListenerClassSidecarInbound, 1},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := NewConfigGenTest(t, TestOptions{ConfigString: tt.configs, MeshConfig: mesh})
+			proxy := cg.SetupProxy(nil)
+			if got := buildHTTPMetricsFilters(cg.PushContext(), proxy, tt.class); len(got) != tt.want {
+				t.Fatalf("expected %d filters, got %d", tt.want, len(got))
+			}
+		})
+	}
+}
+
+func pluginConfiguration(t *testing.T, cfg *wasm.PluginConfig) string {
+	t.Helper()
+	s := &wrapperspb.StringValue{}
+	if err := cfg.GetConfiguration().UnmarshalTo(s); err != nil {
+		t.Fatal(err)
+	}
+	return s.GetValue()
+}
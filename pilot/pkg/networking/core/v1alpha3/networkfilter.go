@@ -39,7 +39,7 @@ import (
 var redisOpTimeout = 5 * time.Second
 
 // buildInboundNetworkFilters generates a TCP proxy network filter on the inbound path
-func buildInboundNetworkFilters(push *model.PushContext, instance *model.ServiceInstance, clusterName string) []*listener.Filter {
+func buildInboundNetworkFilters(push *model.PushContext, node *model.Proxy, instance *model.ServiceInstance, clusterName string) []*listener.Filter {
 	statPrefix := clusterName
 	// If stat name is configured, build the stat prefix from configured pattern.
 	if len(push.Mesh.InboundClusterStatName) != 0 {
@@ -50,7 +50,8 @@ func buildInboundNetworkFilters(push *model.PushContext, instance *model.Service
 		ClusterSpecifier: &tcp.TcpProxy_Cluster{Cluster: clusterName},
 	}
 	tcpFilter := setAccessLogAndBuildTCPFilter(push, tcpProxy)
-	return buildNetworkFiltersStack(instance.ServicePort, tcpFilter, statPrefix, clusterName)
+	metricsFilters := buildTCPMetricsFilters(push, node, ListenerClassSidecarInbound)
+	return append(metricsFilters, buildNetworkFiltersStack(instance.ServicePort, tcpFilter, statPrefix, clusterName)...)
 }
 
 // setAccessLogAndBuildTCPFilter sets the AccessLog configuration in the given
@@ -81,7 +82,8 @@ func buildOutboundNetworkFiltersWithSingleDestination(push *model.PushContext, n
 	}
 
 	tcpFilter := setAccessLogAndBuildTCPFilter(push, tcpProxy)
-	return buildNetworkFiltersStack(port, tcpFilter, statPrefix, clusterName)
+	metricsFilters := buildTCPMetricsFilters(push, node, outboundListenerClass(node))
+	return append(metricsFilters, buildNetworkFiltersStack(port, tcpFilter, statPrefix, clusterName)...)
 }
 
 // buildOutboundNetworkFiltersWithWeightedClusters takes a set of weighted
@@ -118,7 +120,8 @@ func buildOutboundNetworkFiltersWithWeightedClusters(node *model.Proxy, routes [
 	// TODO: Need to handle multiple cluster names for Redis
 	clusterName := clusterSpecifier.WeightedClusters.Clusters[0].Name
 	tcpFilter := setAccessLogAndBuildTCPFilter(push, proxyConfig)
-	return buildNetworkFiltersStack(port, tcpFilter, statPrefix, clusterName)
+	metricsFilters := buildTCPMetricsFilters(push, node, outboundListenerClass(node))
+	return append(metricsFilters, buildNetworkFiltersStack(port, tcpFilter, statPrefix, clusterName)...)
 }
 
 // buildNetworkFiltersStack builds a slice of network filters based on
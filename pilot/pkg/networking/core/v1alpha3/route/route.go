@@ -88,7 +88,8 @@ func BuildSidecarVirtualHostWrapper(routeCache *Cache, node *model.Proxy, push *
 	virtualServices []config.Config, listenPort int) []VirtualHostWrapper {
 	out := make([]VirtualHostWrapper, 0)
 
-	// dependentDestinationRules includes all the destinationrules referenced by the virtualservices, which have consistent hash policy.
+	// dependentDestinationRules includes all the destinationrules resolved for the destinations of the routes, as any of them
+	// can change the consistent hash policy of the routes.
 	dependentDestinationRules := []*config.Config{}
 	// consistent hash policies for the http route destinations
 	hashByDestination := map[*networking.HTTPRouteDestination]*networking.LoadBalancerSettings_ConsistentHashLB{}
@@ -105,6 +106,8 @@ func BuildSidecarVirtualHostWrapper(routeCache *Cache, node *model.Proxy, push *
 				hash, destinationRule := GetHashForHTTPDestination(push, node, destination, configNamespace)
 				if hash != nil {
 					hashByDestination[destination] = hash
+				}
+				if destinationRule != nil {
 					dependentDestinationRules = append(dependentDestinationRules, destinationRule)
 				}
 			}
@@ -134,6 +137,8 @@ func BuildSidecarVirtualHostWrapper(routeCache *Cache, node *model.Proxy, push *
 						hashByService[svc.Hostname] = map[int]*networking.LoadBalancerSettings_ConsistentHashLB{}
 					}
 					hashByService[svc.Hostname][port.Port] = hash
+				}
+				if destinationRule != nil {
 					dependentDestinationRules = append(dependentDestinationRules, destinationRule)
 				}
 			}
@@ -141,7 +146,7 @@ func BuildSidecarVirtualHostWrapper(routeCache *Cache, node *model.Proxy, push *
 	}
 
 	if routeCache != nil {
-		routeCache.DestinationRules = dependentDestinationRules
+		routeCache.DestinationRules = uniqueDestinationRules(dependentDestinationRules)
 	}
 
 	// append default hosts for the service missing virtual Services
@@ -1115,6 +1120,27 @@ func consistentHashToHashPolicy(consistentHash *networking.LoadBalancerSettings_
 	return nil
 }
 
+// uniqueDestinationRules returns the destination rules without duplicates, sorted by namespace and name so that they
+// can be part of a cache key.
+func uniqueDestinationRules(destinationRules []*config.Config) []*config.Config {
+	seen := map[string]bool{}
+	out := make([]*config.Config, 0, len(destinationRules))
+	for _, dr := range destinationRules {
+		key := dr.Namespace + "/" + dr.Name
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, dr)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
 func getHashForService(node *model.Proxy, push *model.PushContext,
 	svc *model.Service, port *model.Port) (*networking.LoadBalancerSettings_ConsistentHashLB, *config.Config) {
 	if push == nil {
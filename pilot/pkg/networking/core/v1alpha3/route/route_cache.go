@@ -22,6 +22,7 @@ import (
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
 )
@@ -42,12 +43,17 @@ type Cache struct {
 	// This allows resolving ServiceEntries, which is especially useful for distinguishing TCP traffic
 	// This depends on DNSCapture.
 	DNSAutoAllocate bool
+	// OutboundTrafficPolicy of the sidecar scope, which determines the catch all virtual host
+	OutboundTrafficPolicy *networking.OutboundTrafficPolicy
 
-	ListenerPort     int
-	Services         []*model.Service
-	VirtualServices  []config.Config
-	DestinationRules []*config.Config
-	EnvoyFilterKeys  []string
+	ListenerPort    int
+	Services        []*model.Service
+	VirtualServices []config.Config
+	// DelegateVirtualServices are the delegates merged into VirtualServices. Their changes are not
+	// reflected in the merged VirtualServices' names, so they are tracked separately.
+	DelegateVirtualServices []model.ConfigKey
+	DestinationRules        []*config.Config
+	EnvoyFilterKeys         []string
 	// Push version is a very broad key. Any config key will invalidate it. Its still valuable to cache,
 	// as that means we can generate a cluster once and send it to all proxies, rather than N times for N proxies.
 	// Hypothetically we could get smarter and determine the exact set of all configs we use and their versions,
@@ -79,13 +85,14 @@ func (r *Cache) Cacheable() bool {
 }
 
 func (r *Cache) DependentConfigs() []model.ConfigKey {
-	configs := make([]model.ConfigKey, len(r.Services)+len(r.VirtualServices)+len(r.DestinationRules))
+	configs := make([]model.ConfigKey, 0, len(r.Services)+len(r.VirtualServices)+len(r.DelegateVirtualServices)+len(r.DestinationRules))
 	for _, svc := range r.Services {
 		configs = append(configs, model.ConfigKey{Kind: gvk.ServiceEntry, Name: string(svc.Hostname), Namespace: svc.Attributes.Namespace})
 	}
 	for _, vs := range r.VirtualServices {
 		configs = append(configs, model.ConfigKey{Kind: gvk.VirtualService, Name: vs.Name, Namespace: vs.Namespace})
 	}
+	configs = append(configs, r.DelegateVirtualServices...)
 	for _, dr := range r.DestinationRules {
 		configs = append(configs, model.ConfigKey{Kind: gvk.DestinationRule, Name: dr.Name, Namespace: dr.Namespace})
 	}
@@ -101,11 +108,15 @@ func (r *Cache) DependentTypes() []config.GroupVersionKind {
 	return nil
 }
 
+func (r *Cache) TypeURL() string {
+	return v3.RouteType
+}
+
 func (r *Cache) Key() string {
 	params := []string{
 		r.RouteName, r.ProxyVersion, r.ClusterID, r.DNSDomain,
 		strconv.FormatBool(r.DNSCapture), strconv.FormatBool(r.DNSAutoAllocate),
-		r.PushVersion,
+		r.OutboundTrafficPolicy.String(), r.PushVersion,
 	}
 	for _, svc := range r.Services {
 		params = append(params, string(svc.Hostname)+"/"+svc.Attributes.Namespace)
@@ -113,6 +124,9 @@ func (r *Cache) Key() string {
 	for _, vs := range r.VirtualServices {
 		params = append(params, vs.Name+"/"+vs.Namespace)
 	}
+	for _, vs := range r.DelegateVirtualServices {
+		params = append(params, vs.Name+"/"+vs.Namespace)
+	}
 	for _, dr := range r.DestinationRules {
 		params = append(params, dr.Name+"/"+dr.Namespace)
 	}
@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package route
+
+import (
+	"reflect"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestCacheDependentConfigs(t *testing.T) {
+	c := &Cache{
+		RouteName:    "80",
+		ListenerPort: 80,
+		Services: []*model.Service{
+			{Hostname: "foo.com", Attributes: model.ServiceAttributes{Namespace: "ns"}},
+		},
+		VirtualServices: []config.Config{
+			{Meta: config.Meta{Name: "root", Namespace: "ns"}, Spec: &networking.VirtualService{}},
+		},
+		DelegateVirtualServices: []model.ConfigKey{{Kind: gvk.VirtualService, Name: "delegate", Namespace: "ns"}},
+		DestinationRules: []*config.Config{
+			{Meta: config.Meta{Name: "dr", Namespace: "ns"}},
+		},
+		EnvoyFilterKeys: []string{"ns/ef"},
+	}
+	want := []model.ConfigKey{
+		{Kind: gvk.ServiceEntry, Name: "foo.com", Namespace: "ns"},
+		{Kind: gvk.VirtualService, Name: "root", Namespace: "ns"},
+		{Kind: gvk.VirtualService, Name: "delegate", Namespace: "ns"},
+		{Kind: gvk.DestinationRule, Name: "dr", Namespace: "ns"},
+		{Kind: gvk.EnvoyFilter, Name: "ef", Namespace: "ns"},
+	}
+	if got := c.DependentConfigs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got dependent configs %v, want %v", got, want)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := func() *Cache {
+		return &Cache{
+			RouteName:    "80",
+			ListenerPort: 80,
+			Services: []*model.Service{
+				{Hostname: "foo.com", Attributes: model.ServiceAttributes{Namespace: "ns"}},
+			},
+		}
+	}
+	key := base().Key()
+	if got := base().Key(); got != key {
+		t.Fatalf("expected stable key, got %v and %v", key, got)
+	}
+
+	delegate := base()
+	delegate.DelegateVirtualServices = []model.ConfigKey{{Kind: gvk.VirtualService, Name: "delegate", Namespace: "ns"}}
+	if delegate.Key() == key {
+		t.Fatalf("expected delegate virtual services to change the key")
+	}
+
+	destinationRule := base()
+	destinationRule.DestinationRules = []*config.Config{{Meta: config.Meta{Name: "dr", Namespace: "ns"}}}
+	if destinationRule.Key() == key {
+		t.Fatalf("expected destination rules to change the key")
+	}
+
+	allowAny := base()
+	allowAny.OutboundTrafficPolicy = &networking.OutboundTrafficPolicy{Mode: networking.OutboundTrafficPolicy_ALLOW_ANY}
+	if allowAny.Key() == key {
+		t.Fatalf("expected outbound traffic policy to change the key")
+	}
+
+	push := base()
+	push.PushVersion = "2"
+	if push.Key() == key {
+		t.Fatalf("expected push version to change the key")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/golang/protobuf/ptypes/wrappers"
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
@@ -201,6 +202,7 @@ func (b *clusterBuilder) applyDestinationRule(defaultCluster *cluster.Cluster) (
 }
 
 // applyTrafficPolicy mutates the give cluster (if not-nil) so that the given merged traffic policy applies.
+// Fields that gRPC does not support are ignored; they are reported by UnsupportedDestinationRuleFields.
 func (b *clusterBuilder) applyTrafficPolicy(c *cluster.Cluster, trafficPolicy *networking.TrafficPolicy) {
 	// cluster can be nil if it wasn't requested
 	if c == nil {
@@ -208,18 +210,29 @@ func (b *clusterBuilder) applyTrafficPolicy(c *cluster.Cluster, trafficPolicy *n
 	}
 	b.applyTLS(c, trafficPolicy)
 	b.applyLoadBalancing(c, trafficPolicy)
-	// TODO status or log when unsupported features are included
 }
 
-func (b *clusterBuilder) applyLoadBalancing(_ *cluster.Cluster, policy *networking.TrafficPolicy) {
-	switch policy.GetLoadBalancer().GetSimple() {
-	case networking.LoadBalancerSettings_ROUND_ROBIN:
-	// ok
-	default:
-		log.Warnf("cannot apply LbPolicy %s to %s", policy.LoadBalancer.GetSimple(), b.node.ID)
+func (b *clusterBuilder) applyLoadBalancing(c *cluster.Cluster, policy *networking.TrafficPolicy) {
+	// https://github.com/grpc/proposal/blob/master/A42-xds-ring-hash-lb-policy.md
+	// The hash policy itself is set on the route, see applyGrpcRouteAction. gRPC only supports hashing on headers and
+	// picks a random endpoint for every RPC without a hash, so other hash keys keep the default ROUND_ROBIN.
+	consistentHash := policy.GetLoadBalancer().GetConsistentHash()
+	if consistentHash.GetHttpHeaderName() == "" {
+		// gRPC only supports ROUND_ROBIN (the default) for simple load balancing
+		return
+	}
+	// 1024 is the default value for gRPC and envoy
+	minRingSize := &wrappers.UInt64Value{Value: 1024}
+	if consistentHash.GetMinimumRingSize() != 0 {
+		minRingSize = &wrappers.UInt64Value{Value: consistentHash.GetMinimumRingSize()}
+	}
+	c.LbPolicy = cluster.Cluster_RING_HASH
+	c.LbConfig = &cluster.Cluster_RingHashLbConfig_{
+		RingHashLbConfig: &cluster.Cluster_RingHashLbConfig{
+			MinimumRingSize: minRingSize,
+			HashFunction:    cluster.Cluster_RingHashLbConfig_XX_HASH,
+		},
 	}
-
-	// TODO https://github.com/grpc/proposal/blob/master/A42-xds-ring-hash-lb-policy.md
 }
 
 func (b *clusterBuilder) applyTLS(c *cluster.Cluster, policy *networking.TrafficPolicy) {
@@ -228,15 +241,19 @@ func (b *clusterBuilder) applyTLS(c *cluster.Cluster, policy *networking.Traffic
 	// 2. We cannot reach servers in PERMISSIVE mode; gRPC doesn't allow us to override the alpn to one of Istio's
 	// 3. Once we support gRPC servers, we have no good way to detect if a server is implemented with xds.NewGrpcServer and will actually support our config
 	// For these reasons, support only explicit tls configuration.
-	switch policy.GetTls().GetMode() {
+	settings := policy.GetTls()
+	var tlsCtx *tls.UpstreamTlsContext
+	switch settings.GetMode() {
 	case networking.ClientTLSSettings_DISABLE:
 		// nothing to do
 	case networking.ClientTLSSettings_SIMPLE:
-		// TODO support this
+		tlsCtx = buildSimpleUpstreamTLSContext(settings)
 	case networking.ClientTLSSettings_MUTUAL:
-		// TODO support this
+		tlsCtx = buildMutualUpstreamTLSContext(settings)
 	case networking.ClientTLSSettings_ISTIO_MUTUAL:
-		tlsCtx := buildUpstreamTLSContext(b.push.ServiceAccounts[b.hostname][b.portNum])
+		tlsCtx = buildUpstreamTLSContext(b.push.ServiceAccounts[b.hostname][b.portNum])
+	}
+	if tlsCtx != nil {
 		c.TransportSocket = &core.TransportSocket{
 			Name:       transportSocketName,
 			ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: util.MessageToAny(tlsCtx)},
@@ -246,10 +263,41 @@ func (b *clusterBuilder) applyTLS(c *cluster.Cluster, policy *networking.Traffic
 
 // TransportSocket proto message has a `name` field which is expected to be set to exactly this value by the
 // management server (see grpc/xds/internal/client/xds.go securityConfigFromCluster).
-const transportSocketName = "envoy.transport_sockets.tls"
+const (
+	transportSocketName = "envoy.transport_sockets.tls"
+	// defaultCertProviderInstance is the certificate provider instance the agent generates in the gRPC bootstrap,
+	// which holds the workload certificate and the mesh root certificate.
+	defaultCertProviderInstance = "default"
+)
 
 func buildUpstreamTLSContext(sans []string) *tls.UpstreamTlsContext {
 	return &tls.UpstreamTlsContext{
 		CommonTlsContext: buildCommonTLSContext(sans),
 	}
 }
+
+// buildSimpleUpstreamTLSContext creates a TLS context that only verifies the server, against the mesh root
+// certificate. gRPC can only read certificates from the certificate provider instances in its bootstrap, and the
+// agent only generates the 'default' file_watcher instance, so the credentialName is ignored: any other instance name
+// would make the client reject the cluster.
+func buildSimpleUpstreamTLSContext(settings *networking.ClientTLSSettings) *tls.UpstreamTlsContext {
+	return &tls.UpstreamTlsContext{
+		CommonTlsContext: &tls.CommonTlsContext{
+			ValidationContextType: buildValidationContext(defaultCertProviderInstance, settings.GetSubjectAltNames()),
+		},
+	}
+}
+
+// buildMutualUpstreamTLSContext creates a TLS context that verifies the server and presents the workload certificate,
+// both from the 'default' certificate provider instance.
+func buildMutualUpstreamTLSContext(settings *networking.ClientTLSSettings) *tls.UpstreamTlsContext {
+	return &tls.UpstreamTlsContext{
+		CommonTlsContext: &tls.CommonTlsContext{
+			TlsCertificateCertificateProviderInstance: &tls.CommonTlsContext_CertificateProviderInstance{
+				InstanceName:    defaultCertProviderInstance,
+				CertificateName: "default",
+			},
+			ValidationContextType: buildValidationContext(defaultCertProviderInstance, settings.GetSubjectAltNames()),
+		},
+	}
+}
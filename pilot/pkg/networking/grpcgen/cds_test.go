@@ -0,0 +1,140 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcgen
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+func TestApplyLoadBalancing(t *testing.T) {
+	cases := []struct {
+		name    string
+		policy  *networking.TrafficPolicy
+		lb      cluster.Cluster_LbPolicy
+		minRing uint64
+	}{
+		{
+			name: "default",
+			lb:   cluster.Cluster_ROUND_ROBIN,
+		},
+		{
+			name: "consistent hash",
+			policy: &networking.TrafficPolicy{LoadBalancer: &networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+					ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+						HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: "x-user"},
+					},
+				},
+			}},
+			lb:      cluster.Cluster_RING_HASH,
+			minRing: 1024,
+		},
+		{
+			name: "consistent hash with ring size",
+			policy: &networking.TrafficPolicy{LoadBalancer: &networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+					ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+						HashKey:         &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: "x-user"},
+						MinimumRingSize: 2048,
+					},
+				},
+			}},
+			lb:      cluster.Cluster_RING_HASH,
+			minRing: 2048,
+		},
+		{
+			name: "consistent hash on source ip",
+			policy: &networking.TrafficPolicy{LoadBalancer: &networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+					ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+						HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true},
+					},
+				},
+			}},
+			lb: cluster.Cluster_ROUND_ROBIN,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := edsCluster("outbound|80||echo.default.svc.cluster.local")
+			(&clusterBuilder{node: node}).applyLoadBalancing(c, tt.policy)
+			if c.LbPolicy != tt.lb {
+				t.Fatalf("expected lb policy %v, got %v", tt.lb, c.LbPolicy)
+			}
+			if got := c.GetRingHashLbConfig().GetMinimumRingSize().GetValue(); got != tt.minRing {
+				t.Fatalf("expected minimum ring size %d, got %d", tt.minRing, got)
+			}
+		})
+	}
+}
+
+func TestApplyTLS(t *testing.T) {
+	cases := []struct {
+		name       string
+		tls        *networking.ClientTLSSettings
+		clientCert string
+		rootCert   string
+	}{
+		{
+			name: "disable",
+			tls:  &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_DISABLE},
+		},
+		{
+			name:     "simple",
+			tls:      &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_SIMPLE},
+			rootCert: "default",
+		},
+		{
+			name:     "simple with credential",
+			tls:      &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_SIMPLE, CredentialName: "echo"},
+			rootCert: "default",
+		},
+		{
+			name:       "mutual",
+			tls:        &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_MUTUAL, CredentialName: "echo"},
+			clientCert: "default",
+			rootCert:   "default",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := edsCluster("outbound|80||echo.default.svc.cluster.local")
+			(&clusterBuilder{node: node}).applyTLS(c, &networking.TrafficPolicy{Tls: tt.tls})
+			if tt.rootCert == "" {
+				if c.TransportSocket != nil {
+					t.Fatalf("expected no transport socket, got %v", c.TransportSocket)
+				}
+				return
+			}
+			tlsCtx := &tls.UpstreamTlsContext{}
+			if err := c.TransportSocket.GetTypedConfig().UnmarshalTo(tlsCtx); err != nil {
+				t.Fatal(err)
+			}
+			common := tlsCtx.GetCommonTlsContext()
+			if got := common.GetTlsCertificateCertificateProviderInstance().GetInstanceName(); got != tt.clientCert {
+				t.Errorf("expected client certificate instance %q, got %q", tt.clientCert, got)
+			}
+			validation := common.GetCombinedValidationContext().GetValidationContextCertificateProviderInstance()
+			if got := validation.GetInstanceName(); got != tt.rootCert {
+				t.Errorf("expected root certificate instance %q, got %q", tt.rootCert, got)
+			}
+		})
+	}
+}
@@ -74,15 +74,20 @@ func buildCommonTLSContext(sans []string) *tls.CommonTlsContext {
 			InstanceName:    "default",
 			CertificateName: "default",
 		},
-		ValidationContextType: &tls.CommonTlsContext_CombinedValidationContext{
-			CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
-				ValidationContextCertificateProviderInstance: &tls.CommonTlsContext_CertificateProviderInstance{
-					InstanceName:    "default",
-					CertificateName: "ROOTCA",
-				},
-				DefaultValidationContext: &tls.CertificateValidationContext{
-					MatchSubjectAltNames: util.StringToExactMatch(sans),
-				},
+		ValidationContextType: buildValidationContext("default", sans),
+	}
+}
+
+// buildValidationContext validates peers against the ROOTCA of the given certificate provider instance.
+func buildValidationContext(instance string, sans []string) *tls.CommonTlsContext_CombinedValidationContext {
+	return &tls.CommonTlsContext_CombinedValidationContext{
+		CombinedValidationContext: &tls.CommonTlsContext_CombinedCertificateValidationContext{
+			ValidationContextCertificateProviderInstance: &tls.CommonTlsContext_CertificateProviderInstance{
+				InstanceName:    instance,
+				CertificateName: "ROOTCA",
+			},
+			DefaultValidationContext: &tls.CertificateValidationContext{
+				MatchSubjectAltNames: util.StringToExactMatch(sans),
 			},
 		},
 	}
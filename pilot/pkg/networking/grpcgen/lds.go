@@ -28,11 +28,15 @@ import (
 	"github.com/golang/protobuf/ptypes/wrappers"
 
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/plugin"
 	authnplugin "istio.io/istio/pilot/pkg/networking/plugin/authn"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/security/authn"
 	"istio.io/istio/pilot/pkg/security/authn/factory"
+	"istio.io/istio/pilot/pkg/security/authz/builder"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
 	"istio.io/istio/pilot/pkg/util/sets"
+	"istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/istio-agent/grpcxds"
 )
@@ -58,6 +62,7 @@ func buildInboundListeners(node *model.Proxy, push *model.PushContext, names []s
 	}
 	var out model.Resources
 	policyApplier := factory.NewPolicyApplier(push, node.Metadata.Namespace, labels.Collection{node.Metadata.Labels})
+	rbacFilters := buildRBACFilters(node, push)
 	serviceInstancesByPort := map[uint32]*model.ServiceInstance{}
 	for _, si := range node.ServiceInstances {
 		serviceInstancesByPort[si.Endpoint.EndpointPort] = si
@@ -91,7 +96,7 @@ func buildInboundListeners(node *model.Proxy, push *model.PushContext, names []s
 					},
 				},
 			}},
-			FilterChains: buildFilterChains(node, push, si, policyApplier),
+			FilterChains: buildFilterChains(node, push, si, policyApplier, rbacFilters),
 			// the following must not be set or the client will NACK
 			ListenerFilters: nil,
 			UseOriginalDst:  nil,
@@ -104,7 +109,8 @@ func buildInboundListeners(node *model.Proxy, push *model.PushContext, names []s
 	return out
 }
 
-func buildFilterChains(node *model.Proxy, push *model.PushContext, si *model.ServiceInstance, applier authn.PolicyApplier) []*listener.FilterChain {
+func buildFilterChains(node *model.Proxy, push *model.PushContext, si *model.ServiceInstance, applier authn.PolicyApplier,
+	rbacFilters []*hcm.HttpFilter) []*listener.FilterChain {
 	mode := applier.GetMutualTLSModeForPort(si.Endpoint.EndpointPort)
 
 	var tlsContext *tls.DownstreamTlsContext
@@ -130,16 +136,16 @@ func buildFilterChains(node *model.Proxy, push *model.PushContext, si *model.Ser
 	var out []*listener.FilterChain
 	switch mode {
 	case model.MTLSDisable:
-		out = append(out, buildFilterChain("plaintext", nil))
+		out = append(out, buildFilterChain("plaintext", nil, rbacFilters))
 	case model.MTLSStrict:
-		out = append(out, buildFilterChain("mtls", tlsContext))
+		out = append(out, buildFilterChain("mtls", tlsContext, rbacFilters))
 		// TODO permissive builts both plaintext and mtls; when tlsContext is present add a match for protocol
 	}
 
 	return out
 }
 
-func buildFilterChain(nameSuffix string, tlsContext *tls.DownstreamTlsContext) *listener.FilterChain {
+func buildFilterChain(nameSuffix string, tlsContext *tls.DownstreamTlsContext, rbacFilters []*hcm.HttpFilter) *listener.FilterChain {
 	out := &listener.FilterChain{
 		Name:             "inbound-" + nameSuffix,
 		FilterChainMatch: nil,
@@ -147,7 +153,8 @@ func buildFilterChain(nameSuffix string, tlsContext *tls.DownstreamTlsContext) *
 			Name: "inbound-hcm" + nameSuffix,
 			ConfigType: &listener.Filter_TypedConfig{
 				TypedConfig: util.MessageToAny(&hcm.HttpConnectionManager{
-					// TODO gRPC doesn't support httpfilter yet; sending won't cause a NACK but they don't do anything
+					// the router must be the last filter
+					HttpFilters: append(append([]*hcm.HttpFilter{}, rbacFilters...), filters.Router),
 				}),
 			},
 		}},
@@ -161,6 +168,32 @@ func buildFilterChain(nameSuffix string, tlsContext *tls.DownstreamTlsContext) *
 	return out
 }
 
+// buildRBACFilters translates the ALLOW and DENY authorization policies for the node to the RBAC HTTP filters
+// supported by proxyless gRPC servers. Attributes gRPC can not evaluate are rejected in the same way as HTTP only
+// attributes on TCP filter chains.
+func buildRBACFilters(node *model.Proxy, push *model.PushContext) []*hcm.HttpFilter {
+	if push.AuthzPolicies == nil {
+		return nil
+	}
+	in := &plugin.InputParams{Node: node, Push: push}
+	option := builder.Option{
+		IsGrpc: true,
+		Logger: &builder.AuthzLogger{},
+	}
+	defer option.Logger.Report(in)
+
+	policies := push.AuthzPolicies.ListAuthorizationPolicies(node.ConfigNamespace, labels.Collection{node.Metadata.Labels})
+	if len(policies.Custom) > 0 {
+		option.Logger.AppendError(fmt.Errorf("ignored %d CUSTOM actions not supported by proxyless gRPC", len(policies.Custom)))
+	}
+	tdBundle := trustdomain.NewBundle(push.Mesh.TrustDomain, push.Mesh.TrustDomainAliases)
+	b := builder.New(tdBundle, in, option)
+	if b == nil {
+		return nil
+	}
+	return b.BuildHTTP()
+}
+
 func buildOutboundListeners(node *model.Proxy, filter listenerNames) model.Resources {
 	out := make(model.Resources, 0, len(filter))
 	for _, el := range node.SidecarScope.EgressListeners {
@@ -191,6 +224,8 @@ func buildOutboundListeners(node *model.Proxy, filter listenerNames) model.Resou
 						},
 						ApiListener: &listener.ApiListener{
 							ApiListener: util.MessageToAny(&hcm.HttpConnectionManager{
+								// the fault filter applies the per route fault injection config
+								HttpFilters: []*hcm.HttpFilter{filters.Fault, filters.Router},
 								RouteSpecifier: &hcm.HttpConnectionManager_Rds{
 									// TODO: for TCP listeners don't generate RDS, but some indication of cluster name.
 									Rds: &hcm.Rds{
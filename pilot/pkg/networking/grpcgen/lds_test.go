@@ -19,6 +19,8 @@ import (
 	"sort"
 	"testing"
 
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 	"github.com/google/go-cmp/cmp"
 
 	"istio.io/istio/pilot/pkg/model"
@@ -158,3 +160,20 @@ func TestTryFindFQDN(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildFilterChainHTTPFilters(t *testing.T) {
+	rbac := &hcm.HttpFilter{Name: wellknown.HTTPRoleBasedAccessControl}
+	fc := buildFilterChain("plaintext", nil, []*hcm.HttpFilter{rbac})
+	manager := &hcm.HttpConnectionManager{}
+	if err := fc.Filters[0].GetTypedConfig().UnmarshalTo(manager); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range manager.HttpFilters {
+		got = append(got, f.Name)
+	}
+	want := []string{wellknown.HTTPRoleBasedAccessControl, wellknown.Router}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
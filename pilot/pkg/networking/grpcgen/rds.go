@@ -15,6 +15,8 @@
 package grpcgen
 
 import (
+	"strings"
+
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
@@ -48,6 +50,13 @@ func buildHTTPRoute(node *model.Proxy, push *model.PushContext, routeName string
 	}
 
 	virtualHosts, _, _ := v1alpha3.BuildSidecarOutboundVirtualHosts(node, push, routeName, port, nil, &model.DisabledCache{})
+	for _, vh := range virtualHosts {
+		for _, r := range vh.Routes {
+			if action := r.GetRoute(); action != nil {
+				applyGrpcRouteAction(action)
+			}
+		}
+	}
 
 	// Only generate the required route for grpc. Will need to generate more
 	// as GRPC adds more features.
@@ -56,3 +65,61 @@ func buildHTTPRoute(node *model.Proxy, push *model.PushContext, routeName string
 		VirtualHosts: virtualHosts,
 	}
 }
+
+// grpcRetryOn are the retry_on conditions understood by gRPC clients.
+// Other conditions, such as the HTTP specific ones in the default policy, are ignored by gRPC.
+var grpcRetryOn = map[string]bool{
+	"cancelled":          true,
+	"deadline-exceeded":  true,
+	"internal":           true,
+	"resource-exhausted": true,
+	"unavailable":        true,
+}
+
+// applyGrpcRouteAction adjusts a route action built for Envoy to the subset of features gRPC supports.
+func applyGrpcRouteAction(action *route.RouteAction) {
+	// gRPC only reads the stream duration, not the HTTP timeouts. A zero duration disables the limit.
+	if action.MaxStreamDuration == nil && action.Timeout != nil {
+		action.MaxStreamDuration = &route.RouteAction_MaxStreamDuration{
+			MaxStreamDuration:    action.Timeout,
+			GrpcTimeoutHeaderMax: action.Timeout,
+		}
+	}
+	action.RetryPolicy = buildGrpcRetryPolicy(action.RetryPolicy)
+	action.HashPolicy = buildGrpcHashPolicy(action.HashPolicy)
+}
+
+// buildGrpcHashPolicy keeps the header hash policies, set from the DestinationRule consistentHash httpHeaderName.
+// gRPC ignores the other hash policies; the cluster only uses RING_HASH for httpHeaderName, see applyLoadBalancing.
+func buildGrpcHashPolicy(policies []*route.RouteAction_HashPolicy) []*route.RouteAction_HashPolicy {
+	var out []*route.RouteAction_HashPolicy
+	for _, policy := range policies {
+		if policy.GetHeader() != nil {
+			out = append(out, policy)
+		}
+	}
+	return out
+}
+
+// buildGrpcRetryPolicy keeps the retry conditions and attempts gRPC supports, dropping the policy entirely
+// if none of the conditions apply to gRPC.
+func buildGrpcRetryPolicy(policy *route.RetryPolicy) *route.RetryPolicy {
+	if policy == nil || policy.GetNumRetries().GetValue() == 0 {
+		return nil
+	}
+	var retryOn []string
+	for _, cond := range strings.Split(policy.RetryOn, ",") {
+		cond = strings.TrimSpace(cond)
+		if grpcRetryOn[cond] {
+			retryOn = append(retryOn, cond)
+		}
+	}
+	if len(retryOn) == 0 {
+		return nil
+	}
+	return &route.RetryPolicy{
+		RetryOn:      strings.Join(retryOn, ","),
+		NumRetries:   policy.NumRetries,
+		RetryBackOff: policy.RetryBackOff,
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcgen
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+func TestBuildHTTPRoute(t *testing.T) {
+	in, err := os.ReadFile("testdata/routes-in.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{ConfigString: string(in)})
+	proxy := cg.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{Generator: "grpc"}})
+
+	rc := buildHTTPRoute(proxy, cg.PushContext(), "outbound|7070||echo.default.svc.cluster.local")
+	if rc == nil {
+		t.Fatal("expected a route configuration")
+	}
+	got, err := protomarshal.ToYAML(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.RefreshGoldenFile([]byte(got), "testdata/routes-out.yaml", t)
+	util.CompareContent([]byte(got), "testdata/routes-out.yaml", t)
+}
+
+func TestBuildHTTPRouteHashPolicy(t *testing.T) {
+	cases := []struct {
+		name       string
+		hashKey    string
+		headerName string
+	}{
+		{
+			name:       "header",
+			hashKey:    "httpHeaderName: x-user",
+			headerName: "x-user",
+		},
+		{
+			name:    "source ip",
+			hashKey: "useSourceIp: true",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			cg := v1alpha3.NewConfigGenTest(t, v1alpha3.TestOptions{ConfigString: fmt.Sprintf(`
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: echo
+  namespace: default
+spec:
+  hosts:
+    - echo.default.svc.cluster.local
+  addresses:
+    - 10.10.10.10
+  ports:
+    - number: 7070
+      name: grpc
+      protocol: GRPC
+  resolution: STATIC
+  endpoints:
+    - address: 1.1.1.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: echo
+  namespace: default
+spec:
+  host: echo.default.svc.cluster.local
+  trafficPolicy:
+    loadBalancer:
+      consistentHash:
+        %s
+`, tt.hashKey)})
+			proxy := cg.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{Generator: "grpc"}})
+
+			rc := buildHTTPRoute(proxy, cg.PushContext(), "outbound|7070||echo.default.svc.cluster.local")
+			if len(rc.GetVirtualHosts()) != 1 || len(rc.GetVirtualHosts()[0].GetRoutes()) != 1 {
+				t.Fatalf("expected a single route, got %v", rc)
+			}
+			hashPolicy := rc.GetVirtualHosts()[0].GetRoutes()[0].GetRoute().GetHashPolicy()
+			if tt.headerName == "" {
+				if len(hashPolicy) != 0 {
+					t.Fatalf("expected no hash policy, got %v", hashPolicy)
+				}
+				return
+			}
+			if len(hashPolicy) != 1 || hashPolicy[0].GetHeader().GetHeaderName() != tt.headerName {
+				t.Fatalf("expected a hash policy on header %s, got %v", tt.headerName, hashPolicy)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcgen
+
+import (
+	"fmt"
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+// UnsupportedDestinationRuleFields returns the paths of the fields set in the DestinationRule that
+// are ignored when generating configuration for proxyless gRPC clients.
+func UnsupportedDestinationRuleFields(dr *networking.DestinationRule) []string {
+	var out []string
+	out = append(out, unsupportedTrafficPolicyFields("trafficPolicy", dr.GetTrafficPolicy())...)
+	for _, subset := range dr.GetSubsets() {
+		prefix := fmt.Sprintf("subsets[%s].trafficPolicy", subset.GetName())
+		out = append(out, unsupportedTrafficPolicyFields(prefix, subset.GetTrafficPolicy())...)
+	}
+	return out
+}
+
+func unsupportedTrafficPolicyFields(prefix string, policy *networking.TrafficPolicy) []string {
+	if policy == nil {
+		return nil
+	}
+	out := unsupportedPolicyFields(prefix, policy.GetLoadBalancer(), policy.GetConnectionPool(),
+		policy.GetOutlierDetection(), policy.GetTls())
+	for i, port := range policy.GetPortLevelSettings() {
+		out = append(out, unsupportedPolicyFields(fmt.Sprintf("%s.portLevelSettings[%d]", prefix, i),
+			port.GetLoadBalancer(), port.GetConnectionPool(), port.GetOutlierDetection(), port.GetTls())...)
+	}
+	return out
+}
+
+func unsupportedPolicyFields(
+	prefix string,
+	lb *networking.LoadBalancerSettings,
+	connectionPool *networking.ConnectionPoolSettings,
+	outlierDetection *networking.OutlierDetection,
+	tls *networking.ClientTLSSettings) []string {
+	var out []string
+	if connectionPool != nil {
+		out = append(out, prefix+".connectionPool")
+	}
+	if outlierDetection != nil {
+		out = append(out, prefix+".outlierDetection")
+	}
+	if _, ok := lb.GetLbPolicy().(*networking.LoadBalancerSettings_Simple); ok && lb.GetSimple() != networking.LoadBalancerSettings_ROUND_ROBIN {
+		out = append(out, prefix+".loadBalancer.simple")
+	}
+	if ch := lb.GetConsistentHash(); ch != nil && ch.GetHttpHeaderName() == "" {
+		// gRPC can only hash on headers
+		out = append(out, prefix+".loadBalancer.consistentHash")
+	}
+	if lb.GetLocalityLbSetting() != nil {
+		out = append(out, prefix+".loadBalancer.localityLbSetting")
+	}
+	if mode := tls.GetMode(); mode == networking.ClientTLSSettings_SIMPLE || mode == networking.ClientTLSSettings_MUTUAL {
+		// certificates can only be read from the certificate providers in the gRPC bootstrap
+		if tls.GetCaCertificates() != "" {
+			out = append(out, prefix+".tls.caCertificates")
+		}
+		if tls.GetClientCertificate() != "" {
+			out = append(out, prefix+".tls.clientCertificate")
+		}
+		if tls.GetPrivateKey() != "" {
+			out = append(out, prefix+".tls.privateKey")
+		}
+		if tls.GetSni() != "" {
+			out = append(out, prefix+".tls.sni")
+		}
+		if tls.GetInsecureSkipVerify() != nil {
+			out = append(out, prefix+".tls.insecureSkipVerify")
+		}
+		if name := tls.GetCredentialName(); name != "" && name != defaultCertProviderInstance {
+			// only the certificate provider instance generated by the agent exists
+			out = append(out, prefix+".tls.credentialName")
+		}
+	}
+	return out
+}
+
+// UnsupportedVirtualServiceFields returns the paths of the fields set in the VirtualService that
+// are ignored, or cause requests to fail, for proxyless gRPC clients.
+func UnsupportedVirtualServiceFields(vs *networking.VirtualService) []string {
+	var out []string
+	for i, r := range vs.GetHttp() {
+		prefix := fmt.Sprintf("http[%d]", i)
+		if r.GetName() != "" {
+			prefix = fmt.Sprintf("http[%s]", r.GetName())
+		}
+		if r.GetRedirect() != nil {
+			out = append(out, prefix+".redirect")
+		}
+		if r.GetRewrite() != nil {
+			out = append(out, prefix+".rewrite")
+		}
+		out = append(out, unsupportedRetryFields(prefix+".retries", r.GetRetries())...)
+		if r.GetMirror() != nil {
+			out = append(out, prefix+".mirror")
+		}
+		if r.GetCorsPolicy() != nil {
+			out = append(out, prefix+".corsPolicy")
+		}
+		if r.GetHeaders() != nil {
+			out = append(out, prefix+".headers")
+		}
+		if r.GetDelegate() != nil {
+			out = append(out, prefix+".delegate")
+		}
+	}
+	if len(vs.GetTcp()) > 0 {
+		out = append(out, "tcp")
+	}
+	if len(vs.GetTls()) > 0 {
+		out = append(out, "tls")
+	}
+	return out
+}
+
+func unsupportedRetryFields(prefix string, retries *networking.HTTPRetry) []string {
+	if retries == nil {
+		return nil
+	}
+	var out []string
+	if retries.GetRetryOn() != "" {
+		// at least one of the conditions must be a gRPC status for the policy to be kept
+		supported := false
+		for _, cond := range strings.Split(retries.GetRetryOn(), ",") {
+			supported = supported || grpcRetryOn[strings.TrimSpace(cond)]
+		}
+		if !supported {
+			out = append(out, prefix+".retryOn")
+		}
+	}
+	if retries.GetPerTryTimeout() != nil {
+		out = append(out, prefix+".perTryTimeout")
+	}
+	if retries.GetRetryRemoteLocalities() != nil {
+		out = append(out, prefix+".retryRemoteLocalities")
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcgen
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/google/go-cmp/cmp"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+func TestUnsupportedDestinationRuleFields(t *testing.T) {
+	cases := []struct {
+		name string
+		dr   *networking.DestinationRule
+		want []string
+	}{
+		{
+			name: "supported",
+			dr: &networking.DestinationRule{
+				TrafficPolicy: &networking.TrafficPolicy{
+					LoadBalancer: &networking.LoadBalancerSettings{
+						LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+							ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+								HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: "x-user"},
+							},
+						},
+					},
+					Tls: &networking.ClientTLSSettings{Mode: networking.ClientTLSSettings_MUTUAL, CredentialName: "default"},
+				},
+			},
+		},
+		{
+			name: "unsupported",
+			dr: &networking.DestinationRule{
+				TrafficPolicy: &networking.TrafficPolicy{
+					LoadBalancer: &networking.LoadBalancerSettings{
+						LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_LEAST_CONN},
+					},
+					OutlierDetection: &networking.OutlierDetection{},
+					PortLevelSettings: []*networking.TrafficPolicy_PortTrafficPolicy{{
+						ConnectionPool: &networking.ConnectionPoolSettings{},
+					}},
+				},
+				Subsets: []*networking.Subset{{
+					Name: "v1",
+					TrafficPolicy: &networking.TrafficPolicy{
+						LoadBalancer: &networking.LoadBalancerSettings{
+							LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+								ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+									HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true},
+								},
+							},
+						},
+						Tls: &networking.ClientTLSSettings{
+							Mode:               networking.ClientTLSSettings_SIMPLE,
+							CaCertificates:     "/etc/certs/ca.pem",
+							InsecureSkipVerify: &types.BoolValue{Value: true},
+							CredentialName:     "echo",
+						},
+					},
+				}},
+			},
+			want: []string{
+				"trafficPolicy.outlierDetection",
+				"trafficPolicy.loadBalancer.simple",
+				"trafficPolicy.portLevelSettings[0].connectionPool",
+				"subsets[v1].trafficPolicy.loadBalancer.consistentHash",
+				"subsets[v1].trafficPolicy.tls.caCertificates",
+				"subsets[v1].trafficPolicy.tls.insecureSkipVerify",
+				"subsets[v1].trafficPolicy.tls.credentialName",
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, UnsupportedDestinationRuleFields(tt.dr)); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestUnsupportedVirtualServiceFields(t *testing.T) {
+	vs := &networking.VirtualService{
+		Http: []*networking.HTTPRoute{
+			{
+				Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "echo"}}},
+			},
+			{
+				Name:    "grpc-retry",
+				Retries: &networking.HTTPRetry{Attempts: 3, RetryOn: "unavailable,5xx"},
+			},
+			{
+				Name:    "retry",
+				Retries: &networking.HTTPRetry{Attempts: 3, RetryOn: "5xx,gateway-error"},
+				Rewrite: &networking.HTTPRewrite{Uri: "/"},
+			},
+		},
+		Tcp: []*networking.TCPRoute{{}},
+	}
+	want := []string{"http[retry].rewrite", "http[retry].retries.retryOn", "tcp"}
+	if diff := cmp.Diff(want, UnsupportedVirtualServiceFields(vs)); diff != "" {
+		t.Fatal(diff)
+	}
+}
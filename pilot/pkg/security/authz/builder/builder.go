@@ -47,7 +47,9 @@ var rbacPolicyMatchNever = &rbacpb.Policy{
 // General setting to control behavior
 type Option struct {
 	IsCustomBuilder bool
-	Logger          *AuthzLogger
+	// IsGrpc builds the subset of the RBAC config that is supported by proxyless gRPC servers.
+	IsGrpc bool
+	Logger *AuthzLogger
 }
 
 // Builder builds Istio authorization policy to Envoy filters.
@@ -107,7 +109,9 @@ func (b Builder) BuildHTTP() []*httppb.HttpFilter {
 	}
 
 	var filters []*httppb.HttpFilter
-	if configs := b.build(b.auditPolicies, rbacpb.RBAC_LOG, false); configs != nil {
+	if b.option.IsGrpc && len(b.auditPolicies) > 0 {
+		b.option.Logger.AppendDebugf("ignored %d AUDIT actions not supported by proxyless gRPC", len(b.auditPolicies))
+	} else if configs := b.build(b.auditPolicies, rbacpb.RBAC_LOG, false); configs != nil {
 		b.option.Logger.AppendDebugf("built %d HTTP filters for AUDIT action", len(configs.http))
 		filters = append(filters, configs.http...)
 	}
@@ -194,6 +198,8 @@ func (b Builder) build(policies []model.AuthorizationPolicy, action rbacpb.RBAC_
 	filterType := "HTTP"
 	if forTCP {
 		filterType = "TCP"
+	} else if b.option.IsGrpc {
+		filterType = "gRPC"
 	}
 	hasEnforcePolicy, hasDryRunPolicy := false, false
 	for _, policy := range policies {
@@ -221,12 +227,15 @@ func (b Builder) build(policies []model.AuthorizationPolicy, action rbacpb.RBAC_
 				continue
 			}
 			m.MigrateTrustDomain(b.trustDomainBundle)
+			if b.option.IsGrpc {
+				m.RestrictToGrpc()
+			}
 			if len(b.trustDomainBundle.TrustDomains) > 1 {
 				b.option.Logger.AppendDebugf("patched source principal with trust domain aliases %v", b.trustDomainBundle.TrustDomains)
 			}
 			generated, err := m.Generate(forTCP, action)
 			if err != nil {
-				b.option.Logger.AppendDebugf("skipped rule %s on TCP filter chain: %v", name, err)
+				b.option.Logger.AppendDebugf("skipped rule %s on %s filter chain: %v", name, filterType, err)
 				continue
 			}
 			if generated != nil {
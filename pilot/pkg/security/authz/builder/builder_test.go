@@ -333,6 +333,38 @@ func TestGenerator_GenerateTCP(t *testing.T) {
 	}
 }
 
+func TestGenerator_GenerateGRPC(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "unsupported-fields",
+			input: "unsupported-fields-in.yaml",
+			want:  []string{"unsupported-fields-out1.yaml", "unsupported-fields-out2.yaml"},
+		},
+	}
+
+	baseDir := "grpc/"
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			option := Option{
+				IsGrpc: true,
+				Logger: &AuthzLogger{},
+			}
+			in := inputParams(t, baseDir+tc.input, nil)
+			defer option.Logger.Report(in)
+			g := New(trustdomain.Bundle{}, in, option)
+			if g == nil {
+				t.Fatalf("failed to create generator")
+			}
+			got := g.BuildHTTP()
+			verify(t, convertHTTP(got), baseDir, tc.want, false /* forTCP */)
+		})
+	}
+}
+
 func verify(t *testing.T, gots []proto.Message, baseDir string, wants []string, forTCP bool) {
 	t.Helper()
 
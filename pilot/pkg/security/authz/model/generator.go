@@ -259,3 +259,13 @@ func (methodGenerator) permission(key, value string, forTCP bool) (*rbacpb.Permi
 func (methodGenerator) principal(key, value string, forTCP bool) (*rbacpb.Principal, error) {
 	return nil, fmt.Errorf("unimplemented")
 }
+
+type grpcUnsupportedGenerator struct{}
+
+func (grpcUnsupportedGenerator) permission(key, _ string, _ bool) (*rbacpb.Permission, error) {
+	return nil, fmt.Errorf("%q is not supported by proxyless gRPC", key)
+}
+
+func (grpcUnsupportedGenerator) principal(key, _ string, _ bool) (*rbacpb.Principal, error) {
+	return nil, fmt.Errorf("%q is not supported by proxyless gRPC", key)
+}
@@ -165,6 +165,31 @@ func (m *Model) MigrateTrustDomain(tdBundle trustdomain.Bundle) {
 	}
 }
 
+// RestrictToGrpc replaces the generators of the attributes that proxyless gRPC servers can not evaluate with one
+// that always fails. These attributes are then handled like HTTP only attributes on a TCP filter chain: the rule is
+// ignored for an ALLOW policy, and only the attribute is ignored for a DENY or AUDIT policy.
+func (m *Model) RestrictToGrpc() {
+	for _, rl := range append(append([]ruleList{}, m.permissions...), m.principals...) {
+		for _, r := range rl.rules {
+			if isGrpcUnsupported(r.key) {
+				r.g = grpcUnsupportedGenerator{}
+			}
+		}
+	}
+}
+
+// isGrpcUnsupported returns true if the attribute relies on metadata or connection properties not available
+// to the RBAC filter of proxyless gRPC servers.
+func isGrpcUnsupported(key string) bool {
+	switch {
+	case key == attrRequestPrincipal, key == attrRequestAudiences, key == attrRequestPresenter, key == attrConnSNI:
+		return true
+	case strings.HasPrefix(key, attrRequestClaims), strings.HasPrefix(key, attrEnvoyFilter):
+		return true
+	}
+	return false
+}
+
 // Generate generates the Envoy RBAC config from the model.
 func (m Model) Generate(forTCP bool, action rbacpb.RBAC_Action) (*rbacpb.Policy, error) {
 	var permissions []*rbacpb.Permission
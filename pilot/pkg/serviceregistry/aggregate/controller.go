@@ -86,6 +86,19 @@ func (c *Controller) DeleteRegistry(clusterID cluster.ID, providerID provider.ID
 	log.Infof("Registry for the cluster %s has been deleted.", clusterID)
 }
 
+// UpdateRegistry replaces the registry of the same cluster and provider in the aggregated controller, or adds it if
+// there is none. Unlike DeleteRegistry followed by AddRegistry, the services of the cluster are always visible.
+func (c *Controller) UpdateRegistry(registry serviceregistry.Instance) {
+	c.storeLock.Lock()
+	defer c.storeLock.Unlock()
+
+	if index, ok := c.getRegistryIndex(registry.Cluster(), registry.Provider()); ok {
+		c.registries[index] = registry
+		return
+	}
+	c.registries = append(c.registries, registry)
+}
+
 // GetRegistries returns a copy of all registries
 func (c *Controller) GetRegistries() []serviceregistry.Instance {
 	c.storeLock.RLock()
@@ -426,6 +426,27 @@ func TestAddRegistry(t *testing.T) {
 	}
 }
 
+func TestUpdateRegistry(t *testing.T) {
+	ctrl := NewController(Options{})
+	ctrl.AddRegistry(serviceregistry.Simple{ProviderID: "registry1", ClusterID: "cluster1"})
+	ctrl.AddRegistry(serviceregistry.Simple{ProviderID: "registry2", ClusterID: "cluster2"})
+
+	discovery := mock.NewDiscovery(map[host.Name]*model.Service{}, 2)
+	replacement := serviceregistry.Simple{ProviderID: "registry1", ClusterID: "cluster1", ServiceDiscovery: discovery}
+	ctrl.UpdateRegistry(replacement)
+	if l := len(ctrl.registries); l != 2 {
+		t.Fatalf("Expected length of the registries slice should be 2, got %d", l)
+	}
+	if r := ctrl.registries[0].(serviceregistry.Simple); r.ServiceDiscovery != discovery {
+		t.Fatalf("Expected registry of cluster1 to be replaced in place, got %v", ctrl.registries)
+	}
+
+	ctrl.UpdateRegistry(serviceregistry.Simple{ProviderID: "registry3", ClusterID: "cluster3"})
+	if l := len(ctrl.registries); l != 3 {
+		t.Fatalf("Expected length of the registries slice should be 3, got %d", l)
+	}
+}
+
 func TestGetDeleteRegistry(t *testing.T) {
 	registries := []serviceregistry.Simple{
 		{
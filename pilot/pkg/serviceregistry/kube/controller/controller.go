@@ -34,6 +34,7 @@ import (
 
 	"istio.io/api/label"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
@@ -73,8 +74,9 @@ const (
 var log = istiolog.RegisterScope("kube", "kubernetes service registry controller", 0)
 
 var (
-	typeTag  = monitoring.MustCreateLabel("type")
-	eventTag = monitoring.MustCreateLabel("event")
+	typeTag    = monitoring.MustCreateLabel("type")
+	eventTag   = monitoring.MustCreateLabel("event")
+	clusterTag = monitoring.MustCreateLabel("cluster")
 
 	k8sEvents = monitoring.NewSum(
 		"pilot_k8s_reg_events",
@@ -93,12 +95,26 @@ var (
 		"pilot_k8s_endpoints_pending_pod",
 		"Number of endpoints that do not currently have any corresponding pods.",
 	)
+
+	clusterServices = monitoring.NewGauge(
+		"pilot_k8s_cluster_services",
+		"Number of services contributed by a cluster to the service registry.",
+		monitoring.WithLabels(clusterTag),
+	)
+
+	clusterEndpoints = monitoring.NewGauge(
+		"pilot_k8s_cluster_endpoints",
+		"Number of endpoint addresses contributed by a cluster to the service registry.",
+		monitoring.WithLabels(clusterTag),
+	)
 )
 
 func init() {
 	monitoring.MustRegister(k8sEvents)
 	monitoring.MustRegister(endpointsWithNoPods)
 	monitoring.MustRegister(endpointsPendingPodUpdate)
+	monitoring.MustRegister(clusterServices)
+	monitoring.MustRegister(clusterEndpoints)
 }
 
 func incrementEvent(kind, event string) {
@@ -147,6 +163,10 @@ type Options struct {
 
 	// If meshConfig.DiscoverySelectors are specified, the DiscoveryNamespacesFilter tracks the namespaces this controller watches.
 	DiscoveryNamespacesFilter filter.DiscoveryNamespacesFilter
+
+	// Membership, if set, shards background work such as ServiceExport creation between istiod replicas
+	// instead of running it on the leader only.
+	Membership *leaderelection.Membership
 }
 
 func (o Options) GetSyncInterval() time.Duration {
@@ -584,10 +604,12 @@ func (c *Controller) registerHandlers(
 	if informer, ok := informer.(cache.SharedInformer); ok {
 		_ = informer.SetWatchErrorHandler(informermetric.ErrorHandlerForCluster(c.Cluster()))
 	}
+	recordEvent := informermetric.EventRecorder(c.Cluster(), otype)
 	informer.AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: func(obj interface{}) {
 				incrementEvent(otype, "add")
+				recordEvent()
 				if !shouldEnqueue(otype, c.beginSync) {
 					return
 				}
@@ -596,6 +618,7 @@ func (c *Controller) registerHandlers(
 				})
 			},
 			UpdateFunc: func(old, cur interface{}) {
+				recordEvent()
 				if filter != nil {
 					if filter(old, cur) {
 						incrementEvent(otype, "updatesame")
@@ -613,6 +636,7 @@ func (c *Controller) registerHandlers(
 			},
 			DeleteFunc: func(obj interface{}) {
 				incrementEvent(otype, "delete")
+				recordEvent()
 				if !shouldEnqueue(otype, c.beginSync) {
 					return
 				}
@@ -42,6 +42,8 @@ type kubeEndpointsController interface {
 	// forgetEndpoint does internal bookkeeping on a deleted endpoint
 	forgetEndpoint(endpoint interface{}) []*model.IstioEndpoint
 	getServiceInfo(ep interface{}) (host.Name, string, string)
+	// addressCount returns the number of endpoint addresses in the cache
+	addressCount() int
 }
 
 // kubeEndpoints abstracts the common behavior across endpoint and endpoint slices.
@@ -164,6 +164,18 @@ func (e *endpointsController) getInformer() filter.FilteredSharedIndexInformer {
 	return e.informer
 }
 
+func (e *endpointsController) addressCount() int {
+	count := 0
+	for _, obj := range e.informer.GetIndexer().List() {
+		if ep, ok := obj.(*v1.Endpoints); ok {
+			for _, ss := range ep.Subsets {
+				count += len(ss.Addresses)
+			}
+		}
+	}
+	return count
+}
+
 func (e *endpointsController) onEvent(curr interface{}, event model.Event) error {
 	ep, ok := curr.(*v1.Endpoints)
 	if !ok {
@@ -58,6 +58,18 @@ func (esc *endpointSliceController) getInformer() filter.FilteredSharedIndexInfo
 	return esc.informer
 }
 
+func (esc *endpointSliceController) addressCount() int {
+	count := 0
+	for _, obj := range esc.informer.GetIndexer().List() {
+		if slice, ok := obj.(*discovery.EndpointSlice); ok {
+			for _, ep := range slice.Endpoints {
+				count += len(ep.Addresses)
+			}
+		}
+	}
+	return count
+}
+
 func (esc *endpointSliceController) onEvent(curr interface{}, event model.Event) error {
 	ep, ok := curr.(*discovery.EndpointSlice)
 	if !ok {
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"golang.org/x/sync/errgroup"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 
 	"istio.io/istio/pilot/pkg/config/kube/crdclient"
@@ -32,6 +33,7 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
+	"istio.io/istio/pilot/pkg/util/informermetric"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -45,6 +47,9 @@ import (
 const (
 	// Name of the webhook config in the config - no need to change it.
 	webhookName = "sidecar-injector.istio.io"
+
+	// clusterStatsInterval is how often the number of services and endpoints of each cluster is recorded.
+	clusterStatsInterval = 30 * time.Second
 )
 
 type kubeController struct {
@@ -82,6 +87,11 @@ type Multicluster struct {
 	secretNamespace  string
 	secretController *secretcontroller.Controller
 	syncInterval     time.Duration
+
+	// serviceExportControllers are the sharded service export controllers of each cluster. They are resynced by a
+	// single membership handler, registered once, when the members change. Protected by m.
+	serviceExportControllers map[cluster.ID]*ServiceExportController
+	membershipHandlerOnce    sync.Once
 }
 
 // NewMulticluster initializes data structure to store multicluster information
@@ -114,6 +124,8 @@ func NewMulticluster(
 		syncInterval:          opts.GetSyncInterval(),
 		client:                kc,
 		s:                     s,
+
+		serviceExportControllers: make(map[cluster.ID]*ServiceExportController),
 	}
 
 	return mc
@@ -152,6 +164,12 @@ func (m *Multicluster) close() (err error) {
 // when a remote cluster is added.  This function needs to set up all the handlers
 // to watch for resources being added, deleted or changed on remote clusters.
 func (m *Multicluster) AddMemberCluster(clusterID cluster.ID, rc *secretcontroller.Cluster) error {
+	return m.addMemberCluster(clusterID, rc, nil)
+}
+
+// addMemberCluster sets up the registry of a cluster. If prev is set, the new registry replaces it in place once
+// its informers have synced, so the endpoints of the cluster are never removed from EDS in between.
+func (m *Multicluster) addMemberCluster(clusterID cluster.ID, rc *secretcontroller.Cluster, prev *kubeController) error {
 	m.m.Lock()
 
 	if m.closing {
@@ -170,10 +188,13 @@ func (m *Multicluster) AddMemberCluster(clusterID cluster.ID, rc *secretcontroll
 
 	log.Infof("Initializing Kubernetes service registry %q", options.ClusterID)
 	kubeRegistry := NewController(client, options)
-	m.serviceController.AddRegistry(kubeRegistry)
-	m.remoteKubeControllers[clusterID] = &kubeController{
+	kc := &kubeController{
 		Controller: kubeRegistry,
 	}
+	if prev == nil {
+		m.serviceController.AddRegistry(kubeRegistry)
+		m.remoteKubeControllers[clusterID] = kc
+	}
 	// localCluster may also be the "config" cluster, in an external-istiod setup.
 	localCluster := m.opts.ClusterID == clusterID
 
@@ -197,13 +218,15 @@ func (m *Multicluster) AddMemberCluster(clusterID cluster.ID, rc *secretcontroll
 		} else if features.WorkloadEntryCrossCluster {
 			// TODO only do this for non-remotes, can't guarantee CRDs in remotes (depends on https://github.com/istio/istio/pull/29824)
 			if configStore, err := createConfigStore(client, m.revision, options); err == nil {
-				m.remoteKubeControllers[clusterID].workloadEntryStore = serviceentry.NewServiceDiscovery(
+				kc.workloadEntryStore = serviceentry.NewServiceDiscovery(
 					configStore, model.MakeIstioStore(configStore), options.XDSUpdater,
 					serviceentry.DisableServiceEntryProcessing(), serviceentry.WithClusterID(clusterID),
 					serviceentry.WithNetworkIDCb(kubeRegistry.Network))
-				m.serviceController.AddRegistry(m.remoteKubeControllers[clusterID].workloadEntryStore)
+				if prev == nil {
+					m.serviceController.AddRegistry(kc.workloadEntryStore)
+				}
 				// Services can select WorkloadEntry from the same cluster. We only duplicate the Service to configure kube-dns.
-				m.remoteKubeControllers[clusterID].workloadEntryStore.AppendWorkloadHandler(kubeRegistry.WorkloadInstanceHandler)
+				kc.workloadEntryStore.AppendWorkloadHandler(kubeRegistry.WorkloadInstanceHandler)
 				go configStore.Run(clusterStopCh)
 			} else {
 				return fmt.Errorf("failed creating config configStore for cluster %s: %v", clusterID, err)
@@ -211,8 +234,14 @@ func (m *Multicluster) AddMemberCluster(clusterID cluster.ID, rc *secretcontroll
 		}
 	}
 
-	// TODO make the aggregate controller keep clusters tied to their individual stop channels
-	if m.serviceController.Running() {
+	go m.recordClusterStats(kubeRegistry, clusterStopCh)
+
+	if prev != nil {
+		if err := m.replaceMemberCluster(clusterID, rc, prev, kc); err != nil {
+			return err
+		}
+	} else if m.serviceController.Running() {
+		// TODO make the aggregate controller keep clusters tied to their individual stop channels
 		// if serviceController isn't running, it will start its members when it is started
 		go kubeRegistry.Run(clusterStopCh)
 	}
@@ -263,7 +292,28 @@ func (m *Multicluster) AddMemberCluster(clusterID cluster.ID, rc *secretcontroll
 
 	// setting up the serviceexport controller if and only if it is turned on in the meshconfig.
 	// TODO(nmittler): Need a better solution. Leader election doesn't take into account locality.
-	if features.EnableMCSAutoExport {
+	if features.EnableMCSAutoExport && m.opts.Membership != nil {
+		log.Infof("starting sharded service export controller for cluster %s", clusterID)
+		membership := m.opts.Membership
+		serviceExportController := NewServiceExportController(ServiceExportOptions{
+			Client:       client,
+			ClusterID:    m.opts.ClusterID,
+			DomainSuffix: m.opts.DomainSuffix,
+			ClusterLocal: m.clusterLocal,
+			Owns:         membership.Owns,
+		})
+		m.m.Lock()
+		m.serviceExportControllers[clusterID] = serviceExportController
+		m.m.Unlock()
+		m.membershipHandlerOnce.Do(func() {
+			membership.AddHandler(m.resyncServiceExports)
+		})
+		m.s.RunComponentAsyncAndWait(func(_ <-chan struct{}) error {
+			client.RunAndWait(clusterStopCh)
+			serviceExportController.Run(clusterStopCh)
+			return nil
+		})
+	} else if features.EnableMCSAutoExport {
 		log.Infof("joining leader-election for %s in %s on cluster %s",
 			leaderelection.ServiceExportController, options.SystemNamespace, options.ClusterID)
 		// Block server exit on graceful termination of the leader controller.
@@ -293,11 +343,92 @@ func (m *Multicluster) AddMemberCluster(clusterID cluster.ID, rc *secretcontroll
 	return nil
 }
 
+// resyncServiceExports resyncs the sharded service export controllers of all clusters after the members changed, so
+// that this replica picks up the services it was newly assigned.
+func (m *Multicluster) resyncServiceExports() {
+	m.m.Lock()
+	controllers := make([]*ServiceExportController, 0, len(m.serviceExportControllers))
+	for _, c := range m.serviceExportControllers {
+		controllers = append(controllers, c)
+	}
+	m.m.Unlock()
+	for _, c := range controllers {
+		c.Resync()
+	}
+}
+
+// UpdateMemberCluster is passed to the secret controller as a callback to be called when the kubeconfig of a
+// remote cluster changes, for example when its credentials are rotated. The registry built with the new kubeconfig
+// only replaces the current one once its informers have synced, and the endpoints of services that exist in both
+// are updated in place rather than deleted and added again.
 func (m *Multicluster) UpdateMemberCluster(clusterID cluster.ID, rc *secretcontroller.Cluster) error {
-	if err := m.DeleteMemberCluster(clusterID); err != nil {
-		return err
+	m.m.Lock()
+	prev := m.remoteKubeControllers[clusterID]
+	m.m.Unlock()
+	if prev == nil {
+		return m.AddMemberCluster(clusterID, rc)
+	}
+	return m.addMemberCluster(clusterID, rc, prev)
+}
+
+// replaceMemberCluster waits for the initial sync of the new registry of a cluster and swaps it with the previous
+// one. Services which only exist in the previous registry are deleted. The wait is aborted if the new cluster is
+// stopped, for example because it was superseded by a later update.
+func (m *Multicluster) replaceMemberCluster(clusterID cluster.ID, rc *secretcontroller.Cluster, prev, kc *kubeController) error {
+	// if serviceController isn't running, it will start the new registry when it is started
+	if m.serviceController.Running() {
+		go rc.Client.RunAndWait(rc.Stop)
+		go kc.Run(rc.Stop)
+		ctx, cancel := context.WithTimeout(context.Background(), features.RemoteClusterRotationTimeout)
+		defer cancel()
+		go func() {
+			select {
+			case <-rc.Stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		synced := func() (bool, error) { return kc.initialSync.Load(), nil }
+		if err := wait.PollImmediateUntil(m.syncInterval, synced, ctx.Done()); err != nil {
+			return fmt.Errorf("registry of cluster %s did not sync with the new kubeconfig: %v", clusterID, err)
+		}
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+	if m.closing {
+		return fmt.Errorf("failed updating member cluster %s: server shutting down", clusterID)
+	}
+	if m.remoteKubeControllers[clusterID] != prev {
+		return fmt.Errorf("failed updating member cluster %s: removed or replaced while syncing", clusterID)
+	}
+	m.serviceController.UpdateRegistry(kc.Controller)
+	if kc.workloadEntryStore != nil {
+		m.serviceController.UpdateRegistry(kc.workloadEntryStore)
+	} else if prev.workloadEntryStore != nil {
+		m.serviceController.DeleteRegistry(clusterID, provider.External)
 	}
-	return m.AddMemberCluster(clusterID, rc)
+	m.remoteKubeControllers[clusterID] = kc
+	log.Infof("Replaced Kubernetes service registry %q", clusterID)
+
+	// Both registries share the same shard, so only services removed while the new registry synced are deleted
+	kc.RLock()
+	prev.RLock()
+	var removed []*model.Service
+	for hostname, svc := range prev.servicesMap {
+		if _, ok := kc.servicesMap[hostname]; !ok {
+			removed = append(removed, svc)
+		}
+	}
+	prev.RUnlock()
+	kc.RUnlock()
+	if m.XDSUpdater != nil {
+		for _, svc := range removed {
+			m.XDSUpdater.SvcUpdate(model.ShardKeyFromRegistry(kc), string(svc.Hostname), svc.Attributes.Namespace, model.EventDelete)
+		}
+		m.XDSUpdater.ConfigUpdate(&model.PushRequest{Full: true})
+	}
+	return nil
 }
 
 // DeleteMemberCluster is passed to the secret controller as a callback to be called
@@ -319,6 +450,8 @@ func (m *Multicluster) DeleteMemberCluster(clusterID cluster.ID) error {
 		m.serviceController.DeleteRegistry(clusterID, provider.External)
 	}
 	delete(m.remoteKubeControllers, clusterID)
+	delete(m.serviceExportControllers, clusterID)
+	resetClusterStats(clusterID)
 	if m.XDSUpdater != nil {
 		m.XDSUpdater.ConfigUpdate(&model.PushRequest{Full: true})
 	}
@@ -367,6 +500,59 @@ func (m *Multicluster) InitSecretController(stop <-chan struct{}) *secretcontrol
 	return m.secretController
 }
 
+// ListRemoteClusters returns the debug info of the remote clusters, including the services and endpoints
+// each contributes to the service registry and the activity of its informers.
+func (m *Multicluster) ListRemoteClusters() []cluster.DebugInfo {
+	out := m.secretController.ListRemoteClusters()
+	m.m.Lock()
+	defer m.m.Unlock()
+	for i := range out {
+		info := &out[i]
+		if kc := m.remoteKubeControllers[info.ID]; kc != nil {
+			info.Services, info.Endpoints = kc.stats()
+		}
+		info.APIServerErrors, info.LastEvents = informermetric.ClusterStats(info.ID)
+	}
+	return out
+}
+
+// stats returns the number of services and endpoint addresses in the registry of the cluster.
+func (c *Controller) stats() (int, int) {
+	c.RLock()
+	services := len(c.servicesMap)
+	c.RUnlock()
+	return services, c.endpoints.addressCount()
+}
+
+// recordClusterStats periodically records the number of services and endpoints of a cluster until it is removed.
+func (m *Multicluster) recordClusterStats(c *Controller, stop <-chan struct{}) {
+	ticker := time.NewTicker(clusterStatsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.m.Lock()
+			// The cluster may have been removed or replaced since the last tick; its metrics were reset then.
+			if kc := m.remoteKubeControllers[c.Cluster()]; kc != nil && kc.Controller == c {
+				services, endpoints := c.stats()
+				clusterServices.With(clusterTag.Value(c.Cluster().String())).Record(float64(services))
+				clusterEndpoints.With(clusterTag.Value(c.Cluster().String())).Record(float64(endpoints))
+				informermetric.RecordClusterMetrics(c.Cluster())
+			}
+			m.m.Unlock()
+		}
+	}
+}
+
+// resetClusterStats resets the metrics of a removed cluster so that no stale series are reported for it.
+func resetClusterStats(clusterID cluster.ID) {
+	clusterServices.With(clusterTag.Value(clusterID.String())).Record(0)
+	clusterEndpoints.With(clusterTag.Value(clusterID.String())).Record(0)
+	informermetric.DeleteCluster(clusterID)
+}
+
 func (m *Multicluster) HasSynced() bool {
 	return m.secretController.HasSynced()
 }
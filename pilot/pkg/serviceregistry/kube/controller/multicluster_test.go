@@ -16,6 +16,8 @@ package controller
 
 import (
 	"context"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -26,6 +28,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/keycertbundle"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/server"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	"istio.io/istio/pkg/config/mesh"
@@ -201,3 +204,84 @@ func Test_KubeSecretController_ExternalIstiod_MultipleClusters(t *testing.T) {
 	// Test - Verify that the remote controller has been removed.
 	verifyControllers(t, mc, 0, "delete remote controller")
 }
+
+// svcDeleteRecorder records the services deleted from the registry.
+type svcDeleteRecorder struct {
+	*FakeXdsUpdater
+	mu      sync.Mutex
+	deleted []string
+}
+
+func (r *svcDeleteRecorder) SvcUpdate(shard model.ShardKey, hostname string, namespace string, event model.Event) {
+	if event == model.EventDelete {
+		r.mu.Lock()
+		r.deleted = append(r.deleted, hostname)
+		r.mu.Unlock()
+	}
+	r.FakeXdsUpdater.SvcUpdate(shard, hostname, namespace, event)
+}
+
+func Test_UpdateMemberClusterInPlace(t *testing.T) {
+	stop := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+	})
+	serviceController := aggregate.NewController(aggregate.Options{})
+	go serviceController.Run(stop)
+	retry.UntilOrFail(t, serviceController.Running, retry.Delay(time.Millisecond), retry.Timeout(time.Second*5))
+
+	xds := &svcDeleteRecorder{FakeXdsUpdater: NewFakeXDS()}
+	s := server.New()
+	_ = s.Start(stop)
+	mc := NewMulticluster(
+		"pilot-abc-123",
+		kube.NewFakeClient(),
+		testSecretNameSpace,
+		Options{
+			DomainSuffix: DomainSuffix,
+			SyncInterval: time.Millisecond,
+			MeshWatcher:  mesh.NewFixedWatcher(&meshconfig.MeshConfig{}),
+			XDSUpdater:   xds,
+		}, serviceController, nil, nil, "default", nil, nil, s)
+
+	prevClient := kube.NewFakeClient()
+	makeService("a", "nsa", prevClient, t)
+	makeService("b", "nsa", prevClient, t)
+	prevStop := make(chan struct{})
+	t.Cleanup(func() {
+		close(prevStop)
+	})
+	if err := mc.AddMemberCluster("remote", &secretcontroller.Cluster{Client: prevClient, Stop: prevStop}); err != nil {
+		t.Fatal(err)
+	}
+	prevClient.RunAndWait(prevStop)
+	retry.UntilOrFail(t, func() bool {
+		svcs, _ := serviceController.Services()
+		return len(svcs) == 2
+	}, retry.Delay(time.Millisecond*10), retry.Timeout(time.Second*5))
+
+	// The rotated credentials see one of the services deleted in the meantime
+	client := kube.NewFakeClient()
+	makeService("a", "nsa", client, t)
+	newStop := make(chan struct{})
+	t.Cleanup(func() {
+		close(newStop)
+	})
+	if err := mc.UpdateMemberCluster("remote", &secretcontroller.Cluster{Client: client, Stop: newStop}); err != nil {
+		t.Fatal(err)
+	}
+
+	registries := serviceController.GetRegistries()
+	if len(registries) != 1 || registries[0] != mc.remoteKubeControllers["remote"].Controller {
+		t.Fatalf("expected the registry to be replaced in place, got %v", registries)
+	}
+	svcs, _ := serviceController.Services()
+	if len(svcs) != 1 || svcs[0].Hostname != "a.nsa.svc.fake_domain" {
+		t.Fatalf("expected only service a after the update, got %v", svcs)
+	}
+	xds.mu.Lock()
+	defer xds.mu.Unlock()
+	if want := []string{"b.nsa.svc.fake_domain"}; !reflect.DeepEqual(xds.deleted, want) {
+		t.Fatalf("got deleted services %v, want %v", xds.deleted, want)
+	}
+}
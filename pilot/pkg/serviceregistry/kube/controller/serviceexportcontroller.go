@@ -52,6 +52,9 @@ type ServiceExportOptions struct {
 	ClusterID    cluster.ID
 	DomainSuffix string
 	ClusterLocal model.ClusterLocalProvider
+	// Owns, if set, restricts the controller to the services this instance is responsible for, keyed by
+	// cluster/namespace/name.
+	Owns func(key string) bool
 }
 
 // NewServiceExportController creates a new ServiceExportController.
@@ -91,6 +94,11 @@ func (c *ServiceExportController) onServiceAdd(obj interface{}) {
 			return err
 		}
 
+		if c.Owns != nil && !c.Owns(c.ClusterID.String()+"/"+svc.Namespace+"/"+svc.Name) {
+			// Another istiod replica is responsible for this service.
+			return nil
+		}
+
 		if c.isClusterLocal(svc) {
 			// Don't create ServiceExport if the service is configured to be
 			// local to the cluster (i.e. non-exported).
@@ -101,6 +109,13 @@ func (c *ServiceExportController) onServiceAdd(obj interface{}) {
 	})
 }
 
+// Resync processes all services again, for example after the services owned by this instance changed.
+func (c *ServiceExportController) Resync() {
+	for _, obj := range c.serviceInformer.GetStore().List() {
+		c.onServiceAdd(obj)
+	}
+}
+
 func (c *ServiceExportController) Run(stopCh <-chan struct{}) {
 	if !cache.WaitForCacheSync(stopCh, c.serviceInformer.HasSynced) {
 		log.Error("Failed to sync ServiceExport controller cache")
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"go.uber.org/atomic"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -110,6 +111,39 @@ func TestServiceExportController(t *testing.T) {
 	})
 }
 
+func TestServiceExportControllerOwnership(t *testing.T) {
+	client := kube.NewFakeClient()
+	env := model.Environment{Watcher: mesh.NewFixedWatcher(&meshconfig.MeshConfig{})}
+	env.Init()
+
+	owned := atomic.NewBool(false)
+	sc := NewServiceExportController(ServiceExportOptions{
+		Client:       client,
+		ClusterID:    "cluster1",
+		DomainSuffix: env.DomainSuffix,
+		ClusterLocal: env.ClusterLocal(),
+		Owns: func(key string) bool {
+			return owned.Load() && key == "cluster1/ns/foo"
+		},
+	})
+
+	stop := make(chan struct{})
+	t.Cleanup(func() {
+		close(stop)
+	})
+	client.RunAndWait(stop)
+	sc.Run(stop)
+
+	// Another replica is responsible for the service
+	createSimpleService(t, client, "ns", "foo")
+	assertServiceExport(t, client.MCSApis(), "ns", "foo", false)
+
+	// The service is picked up once its ownership moves to this replica
+	owned.Store(true)
+	sc.Resync()
+	assertServiceExport(t, client.MCSApis(), "ns", "foo", true)
+}
+
 func createSimpleService(t *testing.T, client kubernetes.Interface, ns string, name string) {
 	t.Helper()
 	if _, err := client.CoreV1().Services(ns).Create(context.TODO(), &v1.Service{
@@ -64,6 +64,9 @@ type DistributionController struct {
 	workers         WorkerQueue
 	StaleInterval   time.Duration
 	cmInformer      cache.SharedIndexInformer
+	// Owns, if set, restricts status writes to the resources this instance is responsible for. This allows
+	// every istiod replica to run the controller, each writing the status of its own shard of the config.
+	Owns func(config Resource) bool
 }
 
 func NewController(restConfig *rest.Config, namespace string, cs model.ConfigStore) *DistributionController {
@@ -145,6 +148,9 @@ func (c *DistributionController) writeAllStatus() (staleReporters []string) {
 	defer c.mu.RUnlock()
 	c.mu.RLock()
 	for config, fractions := range c.CurrentState {
+		if c.Owns != nil && !c.Owns(config) {
+			continue
+		}
 		var distributionState Progress
 		for reporter, w := range fractions {
 			// check for stale data here
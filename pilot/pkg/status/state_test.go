@@ -15,9 +15,13 @@
 package status
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
+
+	"k8s.io/utils/clock"
 
 	"istio.io/api/meta/v1alpha1"
 	"istio.io/istio/pkg/config"
@@ -197,3 +201,39 @@ func Test_getTypedStatus(t *testing.T) {
 		})
 	}
 }
+
+type recordingQueue struct {
+	pushed []Resource
+}
+
+func (q *recordingQueue) Push(target Resource, _ ResourceStatus) {
+	q.pushed = append(q.pushed, target)
+}
+
+func (q *recordingQueue) Run(context.Context) {}
+
+func (q *recordingQueue) Delete(Resource) {}
+
+func TestWriteAllStatusOwnership(t *testing.T) {
+	owned := Resource{Namespace: "default", Name: "owned"}
+	other := Resource{Namespace: "default", Name: "other"}
+	realClock := clock.RealClock{}
+	queue := &recordingQueue{}
+	c := &DistributionController{
+		CurrentState: map[Resource]map[string]Progress{
+			owned: {"istiod-a": {AckedInstances: 1, TotalInstances: 1}},
+			other: {"istiod-a": {AckedInstances: 1, TotalInstances: 1}},
+		},
+		ObservationTime: map[string]time.Time{"istiod-a": realClock.Now()},
+		StaleInterval:   time.Minute,
+		clock:           realClock,
+		workers:         queue,
+		Owns: func(config Resource) bool {
+			return config.Name == "owned"
+		},
+	}
+	c.writeAllStatus()
+	if !reflect.DeepEqual(queue.pushed, []Resource{owned}) {
+		t.Fatalf("expected only the owned resource to be written, got %v", queue.pushed)
+	}
+}
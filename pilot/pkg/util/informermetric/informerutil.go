@@ -16,7 +16,9 @@ package informermetric
 
 import (
 	"sync"
+	"time"
 
+	"go.uber.org/atomic"
 	"k8s.io/client-go/tools/cache"
 
 	"istio.io/istio/pkg/cluster"
@@ -26,18 +28,100 @@ import (
 
 var (
 	clusterLabel = monitoring.MustCreateLabel("cluster")
+	typeLabel    = monitoring.MustCreateLabel("type")
 
 	errorMetric = monitoring.NewSum(
 		"controller_sync_errors_total",
 		"Total number of errorMetric syncing controllers.",
 	)
 
+	lastEventMetric = monitoring.NewGauge(
+		"controller_last_event_timestamp_seconds",
+		"Unix time of the last informer event received from a cluster, by resource type.",
+		monitoring.WithLabels(clusterLabel, typeLabel),
+	)
+
 	mu       sync.RWMutex
 	handlers = map[cluster.ID]cache.WatchErrorHandler{}
+	stats    = map[cluster.ID]*clusterStats{}
 )
 
 func init() {
-	monitoring.MustRegister(errorMetric)
+	monitoring.MustRegister(errorMetric, lastEventMetric)
+}
+
+// clusterStats tracks the informer activity of a cluster.
+type clusterStats struct {
+	errors *atomic.Int64
+
+	// lastEvents maps a resource type to the unix time in nanoseconds of its last informer event.
+	lastEvents sync.Map
+}
+
+func statsForCluster(clusterID cluster.ID) *clusterStats {
+	mu.RLock()
+	s, ok := stats[clusterID]
+	mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if s, ok := stats[clusterID]; ok {
+		return s
+	}
+	s = &clusterStats{errors: atomic.NewInt64(0)}
+	stats[clusterID] = s
+	return s
+}
+
+// EventRecorder returns a function that records the time of an informer event of the given resource type from a
+// cluster. The returned function only stores an atomic timestamp, so it is cheap to call on every event.
+func EventRecorder(clusterID cluster.ID, otype string) func() {
+	v, _ := statsForCluster(clusterID).lastEvents.LoadOrStore(otype, atomic.NewInt64(0))
+	last := v.(*atomic.Int64)
+	return func() {
+		last.Store(time.Now().UnixNano())
+	}
+}
+
+// ClusterStats returns the number of watch errors of a cluster, and the time of its last informer event by
+// resource type.
+func ClusterStats(clusterID cluster.ID) (int64, map[string]time.Time) {
+	s := statsForCluster(clusterID)
+	lastEvents := map[string]time.Time{}
+	s.lastEvents.Range(func(k, v interface{}) bool {
+		if nanos := v.(*atomic.Int64).Load(); nanos > 0 {
+			lastEvents[k.(string)] = time.Unix(0, nanos)
+		}
+		return true
+	})
+	return s.errors.Load(), lastEvents
+}
+
+// RecordClusterMetrics records the time of the last informer event of each resource type of a cluster.
+func RecordClusterMetrics(clusterID cluster.ID) {
+	_, lastEvents := ClusterStats(clusterID)
+	for otype, t := range lastEvents {
+		lastEventMetric.With(clusterLabel.Value(clusterID.String()), typeLabel.Value(otype)).Record(float64(t.Unix()))
+	}
+}
+
+// DeleteCluster forgets the informer activity of a removed cluster and resets its metrics.
+func DeleteCluster(clusterID cluster.ID) {
+	mu.Lock()
+	s, ok := stats[clusterID]
+	delete(stats, clusterID)
+	delete(handlers, clusterID)
+	mu.Unlock()
+	if !ok {
+		return
+	}
+	s.lastEvents.Range(func(k, _ interface{}) bool {
+		lastEventMetric.With(clusterLabel.Value(clusterID.String()), typeLabel.Value(k.(string))).Record(0)
+		return true
+	})
 }
 
 // ErrorHandlerForCluster fetches or creates an ErrorHandler that emits a metric
@@ -50,11 +134,13 @@ func ErrorHandlerForCluster(clusterID cluster.ID) cache.WatchErrorHandler {
 		return handler
 	}
 
+	clusterErrors := statsForCluster(clusterID).errors
 	mu.Lock()
 	defer mu.Unlock()
 	clusterMetric := errorMetric.With(clusterLabel.Value(clusterID.String()))
 	h := func(_ *cache.Reflector, err error) {
 		clusterMetric.Increment()
+		clusterErrors.Inc()
 		log.Errorf("watch error in cluster %s: %v", clusterID, err)
 	}
 	handlers[clusterID] = h
@@ -0,0 +1,44 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package informermetric
+
+import (
+	"errors"
+	"testing"
+
+	"istio.io/istio/pkg/cluster"
+)
+
+func TestClusterStats(t *testing.T) {
+	clusterID := cluster.ID("remote")
+	recordPod := EventRecorder(clusterID, "Pods")
+	_ = EventRecorder(clusterID, "Services")
+	ErrorHandlerForCluster(clusterID)(nil, errors.New("boom"))
+
+	recordPod()
+	errs, lastEvents := ClusterStats(clusterID)
+	if errs != 1 {
+		t.Fatalf("expected 1 error, got %d", errs)
+	}
+	if len(lastEvents) != 1 || lastEvents["Pods"].IsZero() {
+		t.Fatalf("expected only a Pods event, got %v", lastEvents)
+	}
+
+	DeleteCluster(clusterID)
+	errs, lastEvents = ClusterStats(clusterID)
+	if errs != 0 || len(lastEvents) != 0 {
+		t.Fatalf("expected stats of a deleted cluster to be reset, got %d errors and events %v", errs, lastEvents)
+	}
+}
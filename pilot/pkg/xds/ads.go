@@ -85,6 +85,9 @@ type Connection struct {
 	// proxy is the client to which this connection is established.
 	proxy *model.Proxy
 
+	// pushPriority is the class of the proxy in the push queue, computed once the connection is initialized.
+	pushPriority PushPriority
+
 	// Sending on this channel results in a push.
 	pushChannel chan *Event
 
@@ -487,6 +490,7 @@ func (s *DiscoveryServer) initConnection(node *core.Node, con *Connection) error
 	con.ConID = connectionID(proxy.ID)
 	con.node = node
 	con.proxy = proxy
+	con.pushPriority = proxyPushPriority(proxy)
 	if features.EnableXDSIdentityCheck && con.Identities != nil {
 		// TODO: allow locking down, rejecting unauthenticated requests.
 		id, err := checkConnectionIdentity(con)
@@ -29,6 +29,7 @@ import (
 	adminapi "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/any"
@@ -186,6 +187,7 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/endpointShardz", "Info about the endpoint shards", s.endpointShardz)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez", "Info about the internal XDS caches", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez?sizes=true", "Info about the size of the internal XDS caches", s.cachez)
+	s.addDebugHandler(mux, internalMux, "/debug/cachez?types=true", "Number and size of the internal XDS cache entries by type", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez?clear=true", "Clear the XDS caches", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/configz", "Debug support for config", s.configz)
 	s.addDebugHandler(mux, internalMux, "/debug/sidecarz", "Debug sidecar scope for a proxy", s.sidecarz)
@@ -204,6 +206,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
 	s.addDebugHandler(mux, internalMux, "/debug/exportz", "List endpoints that been exported via MCS", s.exportz)
+	s.addDebugHandler(mux, internalMux, "/debug/shardz", "List istiod replicas sharding background work, "+
+		"and the owner of the given ?key=", s.shardz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/list", "List all supported debug commands in json", s.List)
 }
@@ -317,33 +321,71 @@ func (s *DiscoveryServer) cachez(w http.ResponseWriter, req *http.Request) {
 	}
 	if req.Form.Get("sizes") != "" {
 		snapshot := s.Cache.Snapshot()
-		res := make(map[string]string, len(snapshot))
+		sizes := make(map[string]int, len(snapshot))
 		totalSize := 0
 		for _, resource := range snapshot {
 			if resource == nil {
 				continue
 			}
-			resourceType := resource.Resource.TypeUrl
 			sz := len(resource.Resource.GetValue())
-			res[resourceType] += util.ByteCount(sz)
+			sizes[cachedResourceType(resource)] += sz
 			totalSize += sz
 		}
+		res := make(map[string]string, len(sizes)+1)
+		for resourceType, sz := range sizes {
+			res[resourceType] = util.ByteCount(sz)
+		}
 		res["total"] = util.ByteCount(totalSize)
 		writeJSON(w, res)
 		return
 	}
+	if req.Form.Get("types") != "" {
+		snapshot := s.Cache.Snapshot()
+		res := make(map[string]*CachezTypeResponse)
+		for _, resource := range snapshot {
+			if resource == nil {
+				continue
+			}
+			resourceType := cachedResourceType(resource)
+			if res[resourceType] == nil {
+				res[resourceType] = &CachezTypeResponse{}
+			}
+			res[resourceType].Entries++
+			res[resourceType].Bytes += len(resource.Resource.GetValue())
+		}
+		writeJSON(w, res)
+		return
+	}
 	snapshot := s.Cache.Snapshot()
 	resources := make(map[string][]string, len(snapshot)) // Key is typeUrl and value is resource names.
 	for key, resource := range snapshot {
 		if resource == nil {
 			continue
 		}
-		resourceType := resource.Resource.TypeUrl
+		resourceType := cachedResourceType(resource)
 		resources[resourceType] = append(resources[resourceType], resource.Name+"/"+key)
 	}
 	writeJSON(w, resources)
 }
 
+// CachezTypeResponse summarizes the XDS cache entries of a single type.
+type CachezTypeResponse struct {
+	Entries int `json:"entries"`
+	Bytes   int `json:"bytes"`
+}
+
+// cachedResourceType returns the type of a cached resource. Entries holding multiple resources, such as the
+// outbound listeners of a sidecar, are wrapped in a DiscoveryResponse carrying the type of its resources.
+func cachedResourceType(resource *discovery.Resource) string {
+	if resource.Resource.MessageIs(&discovery.DiscoveryResponse{}) {
+		wrapper := &discovery.DiscoveryResponse{}
+		if err := resource.Resource.UnmarshalTo(wrapper); err == nil {
+			return wrapper.TypeUrl
+		}
+	}
+	return resource.Resource.TypeUrl
+}
+
 type endpointzResponse struct {
 	Service   string                   `json:"svc"`
 	Endpoints []*model.ServiceInstance `json:"ep"`
@@ -606,7 +648,7 @@ func (s *DiscoveryServer) configDump(conn *Connection) (*adminapi.ConfigDump, er
 	}
 
 	dynamicActiveListeners := make([]*adminapi.ListenersConfigDump_DynamicListener, 0)
-	listeners := s.ConfigGenerator.BuildListeners(conn.proxy, req.Push)
+	listeners := s.ConfigGenerator.BuildListeners(conn.proxy, req)
 	for _, cs := range listeners {
 		listener, err := anypb.New(cs)
 		if err != nil {
@@ -899,6 +941,34 @@ func (s *DiscoveryServer) clusterz(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, s.ListRemoteClusters())
 }
 
+// ShardzResponse describes the istiod replicas sharding background work.
+type ShardzResponse struct {
+	// Name of this replica
+	Name string `json:"name"`
+	// Members are all live replicas, including this one
+	Members []string `json:"members"`
+	// Key and Owner are set when the owner of a specific key is requested
+	Key   string `json:"key,omitempty"`
+	Owner string `json:"owner,omitempty"`
+}
+
+func (s *DiscoveryServer) shardz(w http.ResponseWriter, req *http.Request) {
+	if s.Membership == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Replica sharding is not enabled\n"))
+		return
+	}
+	out := ShardzResponse{
+		Name:    s.Membership.Name(),
+		Members: s.Membership.Members(),
+	}
+	if key := req.URL.Query().Get("key"); key != "" {
+		out.Key = key
+		out.Owner = s.Membership.Owner(key)
+	}
+	writeJSON(w, out)
+}
+
 // handlePushRequest handles a ?push=true query param and triggers a push.
 // A boolean response is returned to indicate if the caller should continue
 func (s *DiscoveryServer) handlePushRequest(w http.ResponseWriter, req *http.Request) bool {
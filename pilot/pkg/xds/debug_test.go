@@ -20,13 +20,21 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"k8s.io/client-go/kubernetes/fake"
 
 	"istio.io/istio/istioctl/pkg/util/configdump"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
 )
 
 func TestSyncz(t *testing.T) {
@@ -213,3 +221,85 @@ func TestDebugHandlers(t *testing.T) {
 		t.Errorf("Error in generatating debug endpoint list")
 	}
 }
+
+func TestShardz(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux, internalMux := http.NewServeMux(), http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, internalMux, false, nil)
+
+	get := func() *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/debug/shardz?key=default/foo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		internalMux.ServeHTTP(rr, req)
+		return rr
+	}
+	if rr := get(); rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected sharding to be disabled, got %v", rr.Code)
+	}
+
+	s.Discovery.Membership = leaderelection.NewMembership("istio-system", "istiod-a", leaderelection.ShardingGroup, fake.NewSimpleClientset())
+	rr := get()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("wanted response code 200, got %v", rr.Code)
+	}
+	got := xds.ShardzResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "istiod-a" || got.Key != "default/foo" || got.Owner != "istiod-a" {
+		t.Fatalf("unexpected response %+v", got)
+	}
+}
+
+type testCacheEntry struct {
+	key     string
+	typeURL string
+}
+
+func (e testCacheEntry) Key() string                               { return e.key }
+func (e testCacheEntry) TypeURL() string                           { return e.typeURL }
+func (e testCacheEntry) DependentTypes() []config.GroupVersionKind { return nil }
+func (e testCacheEntry) DependentConfigs() []model.ConfigKey       { return nil }
+func (e testCacheEntry) Cacheable() bool                           { return true }
+
+func TestCachez(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	mux, internalMux := http.NewServeMux(), http.NewServeMux()
+	s.Discovery.AddDebugHandlers(mux, internalMux, false, nil)
+
+	s.Discovery.Cache.ClearAll()
+	req := &model.PushRequest{Start: time.Now()}
+	for _, name := range []string{"a", "b"} {
+		s.Discovery.Cache.Add(testCacheEntry{key: name, typeURL: v3.ClusterType}, req,
+			&discovery.Resource{Name: name, Resource: util.MessageToAny(&cluster.Cluster{Name: name})})
+	}
+	// Outbound listeners are cached together, wrapped in a DiscoveryResponse
+	s.Discovery.Cache.Add(testCacheEntry{key: "c", typeURL: v3.ListenerType}, req, &discovery.Resource{
+		Name: "outbound",
+		Resource: util.MessageToAny(&discovery.DiscoveryResponse{
+			TypeUrl:   v3.ListenerType,
+			Resources: []*anypb.Any{util.MessageToAny(&listener.Listener{Name: "c"})},
+		}),
+	})
+
+	r, err := http.NewRequest("GET", "/debug/cachez?types=true", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	internalMux.ServeHTTP(rr, r)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("wanted response code 200, got %v", rr.Code)
+	}
+	got := map[string]*xds.CachezTypeResponse{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[v3.ClusterType] == nil || got[v3.ClusterType].Entries != 2 ||
+		got[v3.ListenerType] == nil || got[v3.ListenerType].Entries != 1 || got[v3.ListenerType].Bytes == 0 {
+		t.Fatalf("unexpected response %s", rr.Body.String())
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/controller/workloadentry"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/apigen"
 	"istio.io/istio/pilot/pkg/networking/core"
@@ -67,6 +68,63 @@ type debounceOptions struct {
 
 	// enableEDSDebounce indicates whether EDS pushes should be debounced.
 	enableEDSDebounce bool
+
+	// adaptive, if set, replaces debounceAfter with a window adjusted to the push load.
+	adaptive *adaptiveDebounce
+}
+
+// adaptiveDebounce picks the debounce window of each round of config changes, between min and max.
+// The window is doubled while pushes are slow or proxies are still waiting for a previous push, so that
+// bursts of changes are merged into fewer full pushes, and is halved again once pushes keep up.
+// It is only accessed by the debounce goroutine.
+type adaptiveDebounce struct {
+	min time.Duration
+	max time.Duration
+	// pending returns the number of proxies waiting for a push
+	pending func() int
+
+	current         time.Duration
+	lastPush        time.Time
+	lastPushLatency time.Duration
+}
+
+func newAdaptiveDebounce(min, max time.Duration, pending func() int) *adaptiveDebounce {
+	if max < min {
+		max = min
+	}
+	return &adaptiveDebounce{
+		min:     min,
+		max:     max,
+		pending: pending,
+		current: min,
+	}
+}
+
+// next returns the debounce window for a round of changes starting at now.
+func (a *adaptiveDebounce) next(now time.Time) time.Duration {
+	switch {
+	case now.Sub(a.lastPush) > a.max:
+		// Nothing was pushed recently, push changes as soon as possible.
+		a.current = a.min
+	case a.lastPushLatency > a.current || a.pending() > 0:
+		a.current *= 2
+		if a.current > a.max {
+			a.current = a.max
+		}
+	default:
+		a.current /= 2
+		if a.current < a.min {
+			a.current = a.min
+		}
+	}
+	debounceWindow.Record(a.current.Seconds())
+	return a.current
+}
+
+// pushed records a push completed at now, which took latency.
+func (a *adaptiveDebounce) pushed(now time.Time, latency time.Duration) {
+	a.lastPush = now
+	a.lastPushLatency = latency
 }
 
 // DiscoveryServer is Pilot's gRPC implementation for Envoy's xds APIs
@@ -135,6 +193,9 @@ type DiscoveryServer struct {
 	StatusGen               *StatusGen
 	WorkloadEntryController *workloadentry.Controller
 
+	// Membership tracks the istiod replicas sharding background work. It is nil unless sharding is enabled.
+	Membership *leaderelection.Membership
+
 	// serverReady indicates caches have been synced up and server is ready to process requests.
 	serverReady atomic.Bool
 
@@ -377,7 +438,11 @@ func (s *DiscoveryServer) ConfigUpdate(req *model.PushRequest) {
 // It ensures that at minimum minQuiet time has elapsed since the last event before processing it.
 // It also ensures that at most maxDelay is elapsed between receiving an event and processing it.
 func (s *DiscoveryServer) handleUpdates(stopCh <-chan struct{}) {
-	debounce(s.pushChannel, stopCh, s.debounceOptions, s.Push, s.CommittedUpdates)
+	opts := s.debounceOptions
+	if features.EnableAdaptiveDebounce {
+		opts.adaptive = newAdaptiveDebounce(opts.debounceAfter, features.AdaptiveDebounceAfterMax, s.pushQueue.Pending)
+	}
+	debounce(s.pushChannel, stopCh, opts, s.Push, s.CommittedUpdates)
 }
 
 // The debounce helper function is implemented to enable mocking
@@ -388,24 +453,27 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 
 	pushCounter := 0
 	debouncedEvents := 0
+	debounceAfter := opts.debounceAfter
 
 	// Keeps track of the push requests. If updates are debounce they will be merged.
 	var req *model.PushRequest
 
 	free := true
-	freeCh := make(chan struct{}, 1)
+	// freeCh receives the duration of each push once it is done
+	freeCh := make(chan time.Duration, 1)
 
 	push := func(req *model.PushRequest, debouncedEvents int) {
+		start := time.Now()
 		pushFn(req)
 		updateSent.Add(int64(debouncedEvents))
-		freeCh <- struct{}{}
+		freeCh <- time.Since(start)
 	}
 
 	pushWorker := func() {
 		eventDelay := time.Since(startDebounce)
 		quietTime := time.Since(lastConfigUpdateTime)
 		// it has been too long or quiet enough
-		if eventDelay >= opts.debounceMax || quietTime >= opts.debounceAfter {
+		if eventDelay >= opts.debounceMax || quietTime >= debounceAfter {
 			if req != nil {
 				pushCounter++
 				if req.ConfigsUpdated == nil {
@@ -423,14 +491,17 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 				debouncedEvents = 0
 			}
 		} else {
-			timeChan = time.After(opts.debounceAfter - quietTime)
+			timeChan = time.After(debounceAfter - quietTime)
 		}
 	}
 
 	for {
 		select {
-		case <-freeCh:
+		case latency := <-freeCh:
 			free = true
+			if opts.adaptive != nil {
+				opts.adaptive.pushed(time.Now(), latency)
+			}
 			pushWorker()
 		case r := <-ch:
 			// If reason is not set, record it as an unknown reason
@@ -445,7 +516,10 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 
 			lastConfigUpdateTime = time.Now()
 			if debouncedEvents == 0 {
-				timeChan = time.After(opts.debounceAfter)
+				if opts.adaptive != nil {
+					debounceAfter = opts.adaptive.next(lastConfigUpdateTime)
+				}
+				timeChan = time.After(debounceAfter)
 				startDebounce = lastConfigUpdateTime
 			}
 			debouncedEvents++
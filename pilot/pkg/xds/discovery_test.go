@@ -326,6 +326,41 @@ func TestDebounce(t *testing.T) {
 	}
 }
 
+func TestAdaptiveDebounce(t *testing.T) {
+	pending := 0
+	a := newAdaptiveDebounce(100*time.Millisecond, time.Second, func() int { return pending })
+	now := time.Now()
+	expect := func(want time.Duration) {
+		t.Helper()
+		if got := a.next(now); got != want {
+			t.Fatalf("expected window %v, got %v", want, got)
+		}
+	}
+
+	// Nothing pushed yet
+	expect(100 * time.Millisecond)
+
+	// Proxies are still waiting for the previous push
+	a.pushed(now, 10*time.Millisecond)
+	pending = 10
+	expect(200 * time.Millisecond)
+	expect(400 * time.Millisecond)
+	expect(800 * time.Millisecond)
+	expect(time.Second)
+
+	// Pushes keep up again
+	pending = 0
+	expect(500 * time.Millisecond)
+
+	// Pushes are slower than the window
+	a.pushed(now, 600*time.Millisecond)
+	expect(time.Second)
+
+	// Idle
+	now = now.Add(2 * time.Second)
+	expect(100 * time.Millisecond)
+}
+
 func TestShouldRespond(t *testing.T) {
 	tests := []struct {
 		name       string
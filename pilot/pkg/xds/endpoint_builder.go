@@ -31,6 +31,7 @@ import (
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/security/authn/factory"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
@@ -159,6 +160,10 @@ func (b EndpointBuilder) Key() string {
 	return hex.EncodeToString(sum)
 }
 
+func (b EndpointBuilder) TypeURL() string {
+	return v3.EndpointType
+}
+
 func (b EndpointBuilder) Cacheable() bool {
 	// If service is not defined, we cannot do any caching as we will not have a way to
 	// invalidate the results.
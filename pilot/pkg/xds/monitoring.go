@@ -31,6 +31,8 @@ var (
 	typeTag    = monitoring.MustCreateLabel("type")
 	versionTag = monitoring.MustCreateLabel("version")
 
+	priorityTag = monitoring.MustCreateLabel("priority")
+
 	// pilot_total_xds_rejects should be used instead. This is for backwards compatibility
 	cdsReject = monitoring.NewGauge(
 		"pilot_xds_cds_reject",
@@ -144,6 +146,24 @@ var (
 		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
 	)
 
+	pushQueuePending = monitoring.NewGauge(
+		"pilot_push_queue_pending",
+		"Number of proxies waiting in the push queue, labeled by priority.",
+		monitoring.WithLabels(priorityTag),
+	)
+
+	pushQueueTime = monitoring.NewDistribution(
+		"pilot_push_queue_time",
+		"Time in seconds a proxy waits in the push queue since it was last enqueued, labeled by priority.",
+		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(priorityTag),
+	)
+
+	debounceWindow = monitoring.NewGauge(
+		"pilot_debounce_window_seconds",
+		"Debounce delay in seconds chosen for the latest round of config changes, when adaptive debouncing is enabled.",
+	)
+
 	pushTriggers = monitoring.NewSum(
 		"pilot_push_triggers",
 		"Total number of times a push was triggered, labeled by reason for the push.",
@@ -271,6 +291,9 @@ func init() {
 		pushTime,
 		proxiesConvergeDelay,
 		proxiesQueueTime,
+		pushQueuePending,
+		pushQueueTime,
+		debounceWindow,
 		pushContextErrors,
 		totalXDSInternalErrors,
 		inboundUpdates,
@@ -16,10 +16,83 @@ package xds
 
 import (
 	"sync"
+	"time"
 
 	"istio.io/istio/pilot/pkg/model"
 )
 
+// PushPriority is the class of a proxy in the push queue. Lower values are dequeued first.
+type PushPriority int
+
+const (
+	// PushPriorityHigh is used for gateways, and for proxies asking for it through the PUSH_PRIORITY metadata or the
+	// istio.io/push-priority label.
+	PushPriorityHigh PushPriority = iota
+	// PushPriorityAffected is used for incremental pushes, which only update the endpoints of the proxy.
+	PushPriorityAffected
+	// PushPriorityDefault is used for the full pushes of all other proxies. Most of these will skip the push entirely.
+	PushPriorityDefault
+	// PushPriorityLow is used for proxies asking for it through the PUSH_PRIORITY metadata or the
+	// istio.io/push-priority label.
+	PushPriorityLow
+
+	numPushPriorities = 4
+)
+
+// PushPriorityLabel is the workload label overriding the class of a proxy in the push queue, "high" or "low". The
+// PUSH_PRIORITY proxy metadata takes precedence over it.
+const PushPriorityLabel = "istio.io/push-priority"
+
+// pushPriorityWeights is the number of proxies of each class dequeued in a round while there are proxies of
+// lower classes waiting, so that a steady stream of gateway pushes cannot starve the other proxies.
+var pushPriorityWeights = [numPushPriorities]int{4, 2, 1, 1}
+
+func (p PushPriority) String() string {
+	switch p {
+	case PushPriorityHigh:
+		return "high"
+	case PushPriorityAffected:
+		return "affected"
+	case PushPriorityLow:
+		return "low"
+	default:
+		return "default"
+	}
+}
+
+// proxyPushPriority classifies a proxy when its connection is initialized. Sidecars without an override get
+// PushPriorityDefault, which pushPriority promotes for incremental pushes.
+func proxyPushPriority(proxy *model.Proxy) PushPriority {
+	override := proxy.Metadata.PushPriority
+	if override == "" {
+		override = proxy.Metadata.Labels[PushPriorityLabel]
+	}
+	switch override {
+	case "high":
+		return PushPriorityHigh
+	case "low":
+		return PushPriorityLow
+	}
+	if proxy.Type == model.Router {
+		return PushPriorityHigh
+	}
+	return PushPriorityDefault
+}
+
+// pushPriority classifies a connection for the given push. It only reads the class computed when the connection
+// was initialized, so enqueueing a push never waits on the proxy lock held while the proxy is being pushed.
+func pushPriority(con *Connection, request *model.PushRequest) PushPriority {
+	if con.pushPriority == PushPriorityDefault && !request.Full {
+		return PushPriorityAffected
+	}
+	return con.pushPriority
+}
+
+type queuedConnection struct {
+	priority PushPriority
+	enqueued time.Time
+}
+
 type PushQueue struct {
 	cond *sync.Cond
 
@@ -27,28 +100,39 @@ type PushQueue struct {
 	// the PushRequest will be merged.
 	pending map[*Connection]*model.PushRequest
 
-	// queue maintains ordering of the queue
-	queue []*Connection
+	// queues maintains ordering of the queue, for each priority
+	queues [numPushPriorities][]*Connection
+	// queued stores the priority and enqueue time of all connections in queues
+	queued map[*Connection]queuedConnection
+	// credits is the number of connections each priority may still dequeue in the current round
+	credits [numPushPriorities]int
 
 	// processing stores all connections that have been Dequeue(), but not MarkDone().
 	// The value stored will be initially be nil, but may be populated if the connection is Enqueue().
 	// If model.PushRequest is not nil, it will be Enqueued again once MarkDone has been called.
 	processing map[*Connection]*model.PushRequest
+	// processingPriority stores the highest priority a connection was Enqueue() with while it was processing
+	processingPriority map[*Connection]PushPriority
 
 	shuttingDown bool
 }
 
 func NewPushQueue() *PushQueue {
 	return &PushQueue{
-		pending:    make(map[*Connection]*model.PushRequest),
-		processing: make(map[*Connection]*model.PushRequest),
-		cond:       sync.NewCond(&sync.Mutex{}),
+		pending:            make(map[*Connection]*model.PushRequest),
+		queued:             make(map[*Connection]queuedConnection),
+		credits:            pushPriorityWeights,
+		processing:         make(map[*Connection]*model.PushRequest),
+		processingPriority: make(map[*Connection]PushPriority),
+		cond:               sync.NewCond(&sync.Mutex{}),
 	}
 }
 
 // Enqueue will mark a proxy as pending a push. If it is already pending, pushInfo will be merged.
 // ServiceEntry updates will be added together, and full will be set if either were full
 func (p *PushQueue) Enqueue(con *Connection, pushRequest *model.PushRequest) {
+	priority := pushPriority(con, pushRequest)
+
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 
@@ -59,36 +143,84 @@ func (p *PushQueue) Enqueue(con *Connection, pushRequest *model.PushRequest) {
 	// If its already in progress, merge the info and return
 	if request, f := p.processing[con]; f {
 		p.processing[con] = request.Merge(pushRequest)
+		if prev, f := p.processingPriority[con]; !f || priority < prev {
+			p.processingPriority[con] = priority
+		}
 		return
 	}
 
 	if request, f := p.pending[con]; f {
 		p.pending[con] = request.Merge(pushRequest)
+		if q := p.queued[con]; priority < q.priority {
+			// The new push makes the connection more urgent, move it to the back of the higher priority queue
+			p.remove(con, q.priority)
+			p.push(con, priority, q.enqueued)
+		}
 		return
 	}
 
 	p.pending[con] = pushRequest
-	p.queue = append(p.queue, con)
+	p.push(con, priority, time.Now())
 	// Signal waiters on Dequeue that a new item is available
 	p.cond.Signal()
 }
 
+func (p *PushQueue) push(con *Connection, priority PushPriority, enqueued time.Time) {
+	p.queues[priority] = append(p.queues[priority], con)
+	p.queued[con] = queuedConnection{priority: priority, enqueued: enqueued}
+	pushQueuePending.With(priorityTag.Value(priority.String())).Record(float64(len(p.queues[priority])))
+}
+
+func (p *PushQueue) remove(con *Connection, priority PushPriority) {
+	queue := p.queues[priority]
+	for i, c := range queue {
+		if c == con {
+			p.queues[priority] = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	delete(p.queued, con)
+	pushQueuePending.With(priorityTag.Value(priority.String())).Record(float64(len(p.queues[priority])))
+}
+
+// next removes the connection to push next. Priorities are served in order, but each may only dequeue as many
+// connections as its weight before the lower priorities get their turn. At least one queue must be non-empty.
+func (p *PushQueue) next() *Connection {
+	for {
+		for priority := range p.queues {
+			if len(p.queues[priority]) > 0 && p.credits[priority] > 0 {
+				p.credits[priority]--
+				con := p.queues[priority][0]
+				p.queues[priority] = p.queues[priority][1:]
+				return con
+			}
+		}
+		// Start a new round
+		p.credits = pushPriorityWeights
+	}
+}
+
 // Remove a proxy from the queue. If there are no proxies ready to be removed, this will block
 func (p *PushQueue) Dequeue() (con *Connection, request *model.PushRequest, shutdown bool) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 
 	// Block until there is one to remove. Enqueue will signal when one is added.
-	for len(p.queue) == 0 && !p.shuttingDown {
+	for len(p.queued) == 0 && !p.shuttingDown {
 		p.cond.Wait()
 	}
 
-	if len(p.queue) == 0 {
+	if len(p.queued) == 0 {
 		// We must be shutting down.
 		return nil, nil, true
 	}
 
-	con, p.queue = p.queue[0], p.queue[1:]
+	con = p.next()
+	q := p.queued[con]
+	delete(p.queued, con)
+	priority := priorityTag.Value(q.priority.String())
+	pushQueuePending.With(priority).Record(float64(len(p.queues[q.priority])))
+	pushQueueTime.With(priority).Record(time.Since(q.enqueued).Seconds())
 
 	request = p.pending[con]
 	delete(p.pending, con)
@@ -103,13 +235,15 @@ func (p *PushQueue) MarkDone(con *Connection) {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 	request := p.processing[con]
+	priority := p.processingPriority[con]
 	delete(p.processing, con)
+	delete(p.processingPriority, con)
 
 	// If the info is present, that means Enqueue was called while connection was not yet marked done.
 	// This means we need to add it back to the queue.
 	if request != nil {
 		p.pending[con] = request
-		p.queue = append(p.queue, con)
+		p.push(con, priority, time.Now())
 		p.cond.Signal()
 	}
 }
@@ -118,7 +252,7 @@ func (p *PushQueue) MarkDone(con *Connection) {
 func (p *PushQueue) Pending() int {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
-	return len(p.queue)
+	return len(p.queued)
 }
 
 // ShutDown will cause queue to ignore all new items added to it. As soon as the
@@ -369,3 +369,86 @@ func TestProxyQueue(t *testing.T) {
 		}
 	})
 }
+
+func TestPushQueuePriority(t *testing.T) {
+	newCon := func(id string, nodeType model.NodeType, priority string) *Connection {
+		proxy := &model.Proxy{Type: nodeType, Metadata: &model.NodeMetadata{PushPriority: priority}}
+		return &Connection{ConID: id, proxy: proxy, pushPriority: proxyPushPriority(proxy)}
+	}
+	unrelated := &model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: map[model.ConfigKey]struct{}{{Kind: gvk.VirtualService, Name: "vs", Namespace: "ns"}: {}},
+	}
+
+	t.Run("classes", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		unaffected := newCon("unaffected", model.SidecarProxy, "")
+		incremental := newCon("incremental", model.SidecarProxy, "")
+		gateway := newCon("gateway", model.Router, "")
+		critical := newCon("critical", model.SidecarProxy, "high")
+		demoted := newCon("demoted", model.Router, "low")
+
+		p.Enqueue(demoted, &model.PushRequest{})
+		p.Enqueue(unaffected, unrelated)
+		p.Enqueue(incremental, &model.PushRequest{Full: false})
+		p.Enqueue(gateway, unrelated)
+		p.Enqueue(critical, unrelated)
+
+		ExpectDequeue(t, p, gateway)
+		ExpectDequeue(t, p, critical)
+		ExpectDequeue(t, p, incremental)
+		ExpectDequeue(t, p, unaffected)
+		ExpectDequeue(t, p, demoted)
+	})
+
+	t.Run("label", func(t *testing.T) {
+		proxy := &model.Proxy{Type: model.SidecarProxy, Metadata: &model.NodeMetadata{
+			Labels: map[string]string{PushPriorityLabel: "high"},
+		}}
+		if got := proxyPushPriority(proxy); got != PushPriorityHigh {
+			t.Fatalf("expected the label to set the priority, got %v", got)
+		}
+		// the metadata takes precedence over the label
+		proxy.Metadata.PushPriority = "low"
+		if got := proxyPushPriority(proxy); got != PushPriorityLow {
+			t.Fatalf("expected the metadata to set the priority, got %v", got)
+		}
+	})
+
+	t.Run("promote on merge", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		first := newCon("first", model.SidecarProxy, "")
+		second := newCon("second", model.SidecarProxy, "")
+
+		p.Enqueue(first, unrelated)
+		p.Enqueue(second, unrelated)
+		p.Enqueue(second, &model.PushRequest{Full: false})
+
+		ExpectDequeue(t, p, second)
+		ExpectDequeue(t, p, first)
+	})
+
+	t.Run("fairness", func(t *testing.T) {
+		p := NewPushQueue()
+		defer p.ShutDown()
+		sidecar := newCon("sidecar", model.SidecarProxy, "")
+		p.Enqueue(sidecar, unrelated)
+		gateways := make([]*Connection, 0, 6)
+		for i := 0; i < 6; i++ {
+			gw := newCon(fmt.Sprintf("gateway-%d", i), model.Router, "")
+			gateways = append(gateways, gw)
+			p.Enqueue(gw, unrelated)
+		}
+
+		// The sidecar gets its turn once the gateways used up their share of the round
+		for _, gw := range gateways[:pushPriorityWeights[PushPriorityHigh]] {
+			ExpectDequeue(t, p, gw)
+		}
+		ExpectDequeue(t, p, sidecar)
+		for _, gw := range gateways[pushPriorityWeights[PushPriorityHigh]:] {
+			ExpectDequeue(t, p, gw)
+		}
+	})
+}
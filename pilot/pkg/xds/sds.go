@@ -27,6 +27,7 @@ import (
 	"istio.io/istio/pilot/pkg/model/credentials"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/secrets"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -58,6 +59,10 @@ func (sr SecretResource) Cacheable() bool {
 	return true
 }
 
+func (sr SecretResource) TypeURL() string {
+	return v3.SecretType
+}
+
 func needsUpdate(proxy *model.Proxy, updates model.XdsUpdates) bool {
 	if proxy.Type != model.Router {
 		return false
@@ -14,6 +14,8 @@
 
 package cluster
 
+import "time"
+
 // DebugInfo contains minimal information about remote clusters.
 // This struct is defined here, in a package that avoids many imports, since xds/debug usually
 // affects agent binary size. We avoid embedding other parts of a "remote cluster" struct like kube clients.
@@ -21,4 +23,16 @@ type DebugInfo struct {
 	ID         ID     `json:"id"`
 	SecretName string `json:"secretName"`
 	SyncStatus string `json:"syncStatus"`
+	// SyncStarted is when the informers of the cluster were started, and SyncDuration how long their initial sync took.
+	SyncStarted  *time.Time    `json:"syncStarted,omitempty"`
+	SyncDuration time.Duration `json:"syncDuration,omitempty"`
+	// LastEvents is the time of the last informer event received from the cluster, by resource type.
+	LastEvents map[string]time.Time `json:"lastEvents,omitempty"`
+	// APIServerErrors is the number of watch errors returned by the API server of the cluster.
+	APIServerErrors int64 `json:"apiServerErrors"`
+	// Services and Endpoints are the number of services and endpoint addresses the cluster contributes.
+	Services  int `json:"services"`
+	Endpoints int `json:"endpoints"`
+	// CredentialExpiry is when the credential of the kubeconfig in the remote secret expires, if it is known.
+	CredentialExpiry *time.Time `json:"credentialExpiry,omitempty"`
 }
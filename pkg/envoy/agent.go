@@ -63,6 +63,31 @@ type Agent struct {
 
 	// time to allow for the proxy to drain before terminating all remaining proxy processes
 	terminationDrainDuration time.Duration
+
+	// if set, the agent waits for the application to exit before draining the proxy
+	applicationWaiter *ApplicationWaiter
+}
+
+// ApplicationWaiter blocks termination of the proxy until the application has exited, so the application
+// can keep using the proxy while shutting down.
+type ApplicationWaiter struct {
+	// Exited reports whether all application processes have exited.
+	Exited func() bool
+	// Interval between two checks of Exited.
+	Interval time.Duration
+	// Timeout is the maximum time to wait for the application to exit. Once elapsed, the proxy is drained
+	// regardless.
+	Timeout time.Duration
+	// ExitWithApplication terminates the proxy once the application has started and all its processes have
+	// exited, without waiting for a termination signal. This allows pods running to completion, such as Jobs,
+	// to complete.
+	ExitWithApplication bool
+}
+
+// WaitForApplicationExit makes the agent wait for the application to exit before draining the proxy on
+// termination.
+func (a *Agent) WaitForApplicationExit(w *ApplicationWaiter) {
+	a.applicationWaiter = w
 }
 
 type exitStatus struct {
@@ -75,6 +100,11 @@ func (a *Agent) Run(ctx context.Context) {
 	log.Info("Starting proxy agent")
 	go a.runWait(0, a.abortCh)
 
+	var applicationExited <-chan struct{}
+	if a.applicationWaiter != nil && a.applicationWaiter.ExitWithApplication {
+		applicationExited = a.watchApplication(ctx)
+	}
+
 	select {
 	case status := <-a.statusCh:
 		if status.err != nil {
@@ -87,19 +117,29 @@ func (a *Agent) Run(ctx context.Context) {
 		}
 
 		log.Infof("No more active epochs, terminating")
+	case <-applicationExited:
+		log.Infof("Application has exited, terminating")
+		a.terminateAndWait()
 	case <-ctx.Done():
-		a.terminate()
-		status := <-a.statusCh
-		if status.err == errAbort {
-			log.Infof("Epoch %d aborted normally", status.epoch)
-		} else {
-			log.Warnf("Epoch %d aborted abnormally", status.epoch)
-		}
-		log.Info("Agent has successfully terminated")
+		a.terminateAndWait()
+	}
+}
+
+func (a *Agent) terminateAndWait() {
+	a.terminate()
+	status := <-a.statusCh
+	if status.err == errAbort {
+		log.Infof("Epoch %d aborted normally", status.epoch)
+	} else {
+		log.Warnf("Epoch %d aborted abnormally", status.epoch)
 	}
+	log.Info("Agent has successfully terminated")
 }
 
 func (a *Agent) terminate() {
+	if a.applicationWaiter != nil {
+		a.waitForApplicationExit()
+	}
 	log.Infof("Agent draining Proxy")
 	e := a.proxy.Drain()
 	if e != nil {
@@ -112,6 +152,55 @@ func (a *Agent) terminate() {
 	log.Warnf("Aborted all epochs")
 }
 
+// waitForApplicationExit blocks until the application has exited or the timeout elapses. The proxy keeps
+// serving traffic meanwhile, so requests made by the application while shutting down still succeed.
+func (a *Agent) waitForApplicationExit() {
+	w := a.applicationWaiter
+	log.Infof("Waiting up to %v for the application to exit", w.Timeout)
+	timeout := time.NewTimer(w.Timeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		if w.Exited() {
+			log.Infof("Application has exited")
+			return
+		}
+		select {
+		case <-timeout.C:
+			log.Warnf("Application did not exit within %v, draining anyways", w.Timeout)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchApplication returns a channel closed once the application processes have been seen running, and
+// have all exited since.
+func (a *Agent) watchApplication(ctx context.Context) <-chan struct{} {
+	exited := make(chan struct{})
+	go func() {
+		w := a.applicationWaiter
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+		started := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if !w.Exited() {
+				started = true
+			} else if started {
+				close(exited)
+				return
+			}
+		}
+	}()
+	return exited
+}
+
 // runWait runs the start-up command as a go routine and waits for it to finish
 func (a *Agent) runWait(epoch int, abortCh <-chan error) {
 	log.Infof("Epoch %d starting", epoch)
@@ -18,6 +18,8 @@ import (
 	"context"
 	"testing"
 	"time"
+
+	"go.uber.org/atomic"
 )
 
 // TestProxy sample struct for proxy
@@ -134,3 +136,94 @@ func TestRecovery(t *testing.T) {
 	<-time.After(100 * time.Millisecond)
 	cancel()
 }
+
+// TestWaitForApplicationExit ensures the proxy is only drained once the application has exited
+func TestWaitForApplicationExit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	blockChan := make(chan interface{}, 1)
+	start := func(_ int, abort <-chan error) error {
+		return <-abort
+	}
+	exited := atomic.NewBool(false)
+	a := NewAgent(TestProxy{run: start, blockChannel: blockChan}, 0)
+	a.WaitForApplicationExit(&ApplicationWaiter{
+		Exited:   exited.Load,
+		Interval: time.Millisecond,
+		Timeout:  time.Minute,
+	})
+	done := make(chan struct{})
+	go func() {
+		a.Run(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-blockChan:
+		t.Fatal("proxy drained before application exited")
+	case <-time.After(100 * time.Millisecond):
+	}
+	exited.Store(true)
+	select {
+	case <-blockChan:
+	case <-time.After(10 * time.Second):
+		t.Fatal("proxy not drained after application exited")
+	}
+	<-done
+}
+
+// TestWaitForApplicationExitTimeout ensures the proxy is drained if the application does not exit in time
+func TestWaitForApplicationExitTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	blockChan := make(chan interface{}, 1)
+	start := func(_ int, abort <-chan error) error {
+		return <-abort
+	}
+	a := NewAgent(TestProxy{run: start, blockChannel: blockChan}, 0)
+	a.WaitForApplicationExit(&ApplicationWaiter{
+		Exited:   func() bool { return false },
+		Interval: time.Millisecond,
+		Timeout:  10 * time.Millisecond,
+	})
+	cancel()
+	a.Run(ctx)
+	<-blockChan
+}
+
+// TestExitWithApplication ensures the proxy terminates once the application has started and exited, without
+// a termination signal
+func TestExitWithApplication(t *testing.T) {
+	blockChan := make(chan interface{}, 1)
+	start := func(_ int, abort <-chan error) error {
+		return <-abort
+	}
+	exited := atomic.NewBool(true)
+	a := NewAgent(TestProxy{run: start, blockChannel: blockChan}, 0)
+	a.WaitForApplicationExit(&ApplicationWaiter{
+		Exited:              exited.Load,
+		Interval:            time.Millisecond,
+		Timeout:             time.Minute,
+		ExitWithApplication: true,
+	})
+	done := make(chan struct{})
+	go func() {
+		a.Run(context.Background())
+		close(done)
+	}()
+
+	// The application has not started yet
+	select {
+	case <-done:
+		t.Fatal("proxy terminated before application started")
+	case <-time.After(50 * time.Millisecond):
+	}
+	exited.Store(false)
+	time.Sleep(50 * time.Millisecond)
+	exited.Store(true)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("proxy not terminated after application exited")
+	}
+	<-blockChan
+}
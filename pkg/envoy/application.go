@@ -0,0 +1,61 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"istio.io/pkg/log"
+)
+
+// ApplicationProcessesExited returns a check for whether all processes of other containers in the pod have
+// exited. This requires the pod to share its process namespace (`shareProcessNamespace: true`), so the
+// processes of the application containers are visible in procPath, usually /proc.
+//
+// Processes are attributed to containers by their cgroup: any process whose cgroup differs from the cgroup
+// of the agent belongs to another container. PID 1 is the pause container holding the shared namespace,
+// and is ignored.
+func ApplicationProcessesExited(procPath string) func() bool {
+	return func() bool {
+		self, err := os.ReadFile(filepath.Join(procPath, "self", "cgroup"))
+		if err != nil {
+			log.Warnf("failed to read own cgroup, cannot wait for application: %v", err)
+			return true
+		}
+		entries, err := os.ReadDir(procPath)
+		if err != nil {
+			log.Warnf("failed to list processes, cannot wait for application: %v", err)
+			return true
+		}
+		for _, e := range entries {
+			pid, err := strconv.Atoi(e.Name())
+			if err != nil || pid == 1 {
+				continue
+			}
+			cgroup, err := os.ReadFile(filepath.Join(procPath, e.Name(), "cgroup"))
+			if err != nil {
+				// Process exited while listing
+				continue
+			}
+			if string(cgroup) != string(self) {
+				log.Debugf("application process %d is still running", pid)
+				return false
+			}
+		}
+		return true
+	}
+}
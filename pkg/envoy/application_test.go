@@ -0,0 +1,53 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplicationProcessesExited(t *testing.T) {
+	proc := t.TempDir()
+	writeProcess := func(pid, cgroup string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(proc, pid), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(proc, pid, "cgroup"), []byte(cgroup), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeProcess("self", "0::/proxy\n")
+	writeProcess("1", "0::/pause\n")
+	writeProcess("10", "0::/proxy\n")
+	exited := ApplicationProcessesExited(proc)
+	if !exited() {
+		t.Fatal("expected no application processes")
+	}
+
+	writeProcess("20", "0::/app\n")
+	if exited() {
+		t.Fatal("expected application process to be detected")
+	}
+
+	if err := os.RemoveAll(filepath.Join(proc, "20")); err != nil {
+		t.Fatal(err)
+	}
+	if !exited() {
+		t.Fatal("expected application to have exited")
+	}
+}
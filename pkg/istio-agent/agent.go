@@ -189,6 +189,14 @@ type AgentOptions struct {
 	// Disables all envoy agent features
 	DisableEnvoy          bool
 	DownstreamGrpcOptions []grpc.ServerOption
+
+	// WaitForApplicationExit delays draining the proxy on termination until the other containers of the pod
+	// have exited, for up to ApplicationExitTimeout. Requires the pod to share its process namespace.
+	WaitForApplicationExit bool
+	ApplicationExitTimeout time.Duration
+	// ExitWithApplication terminates the proxy once the other containers of the pod have exited, even without
+	// a termination signal. Only used with WaitForApplicationExit.
+	ExitWithApplication bool
 }
 
 // NewAgent hosts the functionality for local SDS and XDS. This consists of the local SDS server and
@@ -289,6 +297,24 @@ func (a *Agent) initializeEnvoyAgent(ctx context.Context) error {
 
 	drainDuration, _ := types.DurationFromProto(a.proxyConfig.TerminationDrainDuration)
 	a.envoyAgent = envoy.NewAgent(envoyProxy, drainDuration)
+	if a.cfg.WaitForApplicationExit {
+		if os.Getpid() == 1 {
+			// Without a shared process namespace, the agent is the init process and cannot see the application.
+			log.Warnf("WAIT_FOR_APPLICATION_EXIT is set, but the pod does not set shareProcessNamespace; " +
+				"the application processes are not visible, so the proxy will not wait for them to exit")
+		}
+		a.envoyAgent.WaitForApplicationExit(&envoy.ApplicationWaiter{
+			Exited:              envoy.ApplicationProcessesExited("/proc"),
+			Interval:            time.Second,
+			Timeout:             a.cfg.ApplicationExitTimeout,
+			ExitWithApplication: a.cfg.ExitWithApplication,
+		})
+		if drainDuration+a.cfg.ApplicationExitTimeout >= 30*time.Second {
+			log.Warnf("APPLICATION_EXIT_TIMEOUT (%v) and the termination drain duration (%v) exceed the default termination "+
+				"grace period of 30s; make sure terminationGracePeriodSeconds is large enough for the proxy to drain",
+				a.cfg.ApplicationExitTimeout, drainDuration)
+		}
+	}
 	a.envoyWaitCh = make(chan error, 1)
 	if a.cfg.EnableDynamicBootstrap {
 		// Simulate an xDS request for a bootstrap
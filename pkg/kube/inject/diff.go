@@ -0,0 +1,277 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// DiffPathSuffix is appended to the injection path to request a diff rather than a patch.
+// For example, `/inject/cluster/c1/net/n1` becomes `/inject/diff/cluster/c1/net/n1`.
+const DiffPathSuffix = "/diff"
+
+// ChangeType describes how a field differs between the current and the injected pod.
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Removed  ChangeType = "removed"
+	Modified ChangeType = "modified"
+)
+
+// FieldDiff is a single changed field. Old and New are empty for added and removed fields respectively.
+type FieldDiff struct {
+	Name   string     `json:"name"`
+	Change ChangeType `json:"change"`
+	Old    string     `json:"old,omitempty"`
+	New    string     `json:"new,omitempty"`
+}
+
+// ContainerDiff describes the changes to a single container.
+type ContainerDiff struct {
+	Name   string      `json:"name"`
+	Change ChangeType  `json:"change"`
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// PodDiff describes the changes injection would make to a pod, compared to its current state.
+type PodDiff struct {
+	// InjectionSkipped is set if the pod would not be injected at all, per the injection policy.
+	InjectionSkipped bool            `json:"injectionSkipped,omitempty"`
+	Containers       []ContainerDiff `json:"containers,omitempty"`
+	InitContainers   []ContainerDiff `json:"initContainers,omitempty"`
+	Volumes          []FieldDiff     `json:"volumes,omitempty"`
+	Annotations      []FieldDiff     `json:"annotations,omitempty"`
+	Labels           []FieldDiff     `json:"labels,omitempty"`
+}
+
+// Empty returns true if the injected pod is identical to the current pod.
+func (d PodDiff) Empty() bool {
+	return len(d.Containers) == 0 && len(d.InitContainers) == 0 && len(d.Volumes) == 0 &&
+		len(d.Annotations) == 0 && len(d.Labels) == 0
+}
+
+// DiffPods compares a pod with the result of injecting it.
+func DiffPods(current, injected *corev1.Pod) PodDiff {
+	return PodDiff{
+		Containers:     diffContainers(current.Spec.Containers, injected.Spec.Containers),
+		InitContainers: diffContainers(current.Spec.InitContainers, injected.Spec.InitContainers),
+		Volumes:        diffVolumes(current.Spec.Volumes, injected.Spec.Volumes),
+		Annotations:    diffMaps(current.Annotations, injected.Annotations),
+		Labels:         diffMaps(current.Labels, injected.Labels),
+	}
+}
+
+// diffInjection strips any previous injection from the pod, runs the injection again, and compares the
+// result with the pod as it is today. Running the injection from scratch ensures settings removed from the
+// templates show up as removed, rather than being merged over the old sidecar.
+func diffInjection(req InjectionParameters) (PodDiff, error) {
+	current := req.pod.DeepCopy()
+	req.pod = stripPod(req)
+	injected, err := renderInjectedPod(req)
+	if err != nil {
+		return PodDiff{}, err
+	}
+	return DiffPods(current, injected), nil
+}
+
+func diffContainers(current, injected []corev1.Container) []ContainerDiff {
+	cur := map[string]corev1.Container{}
+	for _, c := range current {
+		cur[c.Name] = c
+	}
+	res := []ContainerDiff{}
+	seen := map[string]struct{}{}
+	for _, n := range injected {
+		seen[n.Name] = struct{}{}
+		c, f := cur[n.Name]
+		if !f {
+			res = append(res, ContainerDiff{Name: n.Name, Change: Added, Fields: diffValue("image", "", n.Image)})
+			continue
+		}
+		if fields := containerFields(c, n); len(fields) > 0 {
+			res = append(res, ContainerDiff{Name: n.Name, Change: Modified, Fields: fields})
+		}
+	}
+	for _, c := range current {
+		if _, f := seen[c.Name]; !f {
+			res = append(res, ContainerDiff{Name: c.Name, Change: Removed})
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// containerFields compares the fields of a container that injection commonly changes individually, and
+// the remaining spec as a whole.
+//
+// A pod read from the API server has defaults applied, such as terminationMessagePath or fieldRef.apiVersion,
+// which the rendered pod lacks. Only the fields set by the injection are compared, using semantic equality,
+// so that quantities like 2048Mi and 2Gi are considered equal. Numeric defaults cannot be told apart from unset
+// fields, so these are applied to the injected container first.
+func containerFields(current, injected corev1.Container) []FieldDiff {
+	injected = *injected.DeepCopy()
+	for _, p := range []*corev1.Probe{injected.LivenessProbe, injected.ReadinessProbe, injected.StartupProbe} {
+		setProbeDefaults(p)
+	}
+	res := []FieldDiff{}
+	res = append(res, diffValue("image", current.Image, injected.Image)...)
+	res = append(res, diffValue("args", strings.Join(current.Args, " "), strings.Join(injected.Args, " "))...)
+	for _, e := range diffMaps(envToMap(defaultedEnv(current.Env, injected.Env)), envToMap(injected.Env)) {
+		e.Name = "env " + e.Name
+		res = append(res, e)
+	}
+	if !equality.Semantic.DeepDerivative(injected.Resources, current.Resources) {
+		res = append(res, diffValue("resources", toJSONString(current.Resources), toJSONString(injected.Resources))...)
+	}
+
+	// Anything else is reported as a single change, as the full spec is too verbose to be readable
+	current.Image, injected.Image = "", ""
+	current.Args, injected.Args = nil, nil
+	current.Env, injected.Env = nil, nil
+	current.Resources, injected.Resources = corev1.ResourceRequirements{}, corev1.ResourceRequirements{}
+	if !equality.Semantic.DeepDerivative(injected, current) {
+		res = append(res, FieldDiff{Name: "spec", Change: Modified})
+	}
+	return res
+}
+
+// setProbeDefaults applies the defaults set by the API server to the numeric fields of a probe.
+func setProbeDefaults(p *corev1.Probe) {
+	if p == nil {
+		return
+	}
+	if p.TimeoutSeconds == 0 {
+		p.TimeoutSeconds = 1
+	}
+	if p.PeriodSeconds == 0 {
+		p.PeriodSeconds = 10
+	}
+	if p.SuccessThreshold == 0 {
+		p.SuccessThreshold = 1
+	}
+	if p.FailureThreshold == 0 {
+		p.FailureThreshold = 3
+	}
+}
+
+// defaultedEnv returns the current env, with the variables only differing by server side defaults replaced
+// by their injected value.
+func defaultedEnv(current, injected []corev1.EnvVar) []corev1.EnvVar {
+	inj := make(map[string]corev1.EnvVar, len(injected))
+	for _, e := range injected {
+		inj[e.Name] = e
+	}
+	res := make([]corev1.EnvVar, 0, len(current))
+	for _, e := range current {
+		if i, f := inj[e.Name]; f && equality.Semantic.DeepDerivative(i, e) {
+			e = i
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+func diffVolumes(current, injected []corev1.Volume) []FieldDiff {
+	inj := map[string]string{}
+	sources := map[string]corev1.VolumeSource{}
+	for _, v := range injected {
+		inj[v.Name] = toJSONString(v.VolumeSource)
+		sources[v.Name] = v.VolumeSource
+	}
+	cur := map[string]string{}
+	for _, v := range current {
+		// Ignore server side defaults, such as defaultMode, like for containers
+		if s, f := sources[v.Name]; f && equality.Semantic.DeepDerivative(s, v.VolumeSource) {
+			cur[v.Name] = inj[v.Name]
+			continue
+		}
+		cur[v.Name] = toJSONString(v.VolumeSource)
+	}
+	return diffMaps(cur, inj)
+}
+
+// diffMaps returns the changed keys between two maps, sorted by key.
+func diffMaps(current, injected map[string]string) []FieldDiff {
+	keys := map[string]struct{}{}
+	for k := range current {
+		keys[k] = struct{}{}
+	}
+	for k := range injected {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var res []FieldDiff
+	for _, k := range sorted {
+		o, hasOld := current[k]
+		n, hasNew := injected[k]
+		switch {
+		case !hasOld:
+			res = append(res, FieldDiff{Name: k, Change: Added, New: n})
+		case !hasNew:
+			res = append(res, FieldDiff{Name: k, Change: Removed, Old: o})
+		case o != n:
+			res = append(res, FieldDiff{Name: k, Change: Modified, Old: o, New: n})
+		}
+	}
+	return res
+}
+
+func diffValue(name, current, injected string) []FieldDiff {
+	if current == injected {
+		return nil
+	}
+	return diffMaps(nonEmpty(name, current), nonEmpty(name, injected))
+}
+
+func nonEmpty(k, v string) map[string]string {
+	if v == "" {
+		return nil
+	}
+	return map[string]string{k: v}
+}
+
+func envToMap(env []corev1.EnvVar) map[string]string {
+	res := make(map[string]string, len(env))
+	for _, e := range env {
+		if e.ValueFrom != nil {
+			res[e.Name] = toJSONString(e.ValueFrom)
+		} else {
+			res[e.Name] = e.Value
+		}
+	}
+	return res
+}
+
+func toJSONString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,262 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/annotation"
+)
+
+func diffTemplateConfig(version string) *Config {
+	return &Config{
+		Policy:           InjectionPolicyEnabled,
+		DefaultTemplates: []string{SidecarTemplateName},
+		Templates: map[string]string{SidecarTemplateName: `
+spec:
+  containers:
+  - name: istio-proxy
+    image: proxy:` + version + `
+    env:
+    - name: VERSION
+      value: "` + version + `"
+  volumes:
+  - name: istio-envoy
+`},
+	}
+}
+
+func TestServeInjectDiff(t *testing.T) {
+	wh, _ := createWebhook(t, diffTemplateConfig("1"))
+
+	serve := func(t *testing.T, pod *corev1.Pod, path string) PodDiff {
+		t.Helper()
+		body, err := json.Marshal(pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("POST", "http://sidecar-injector"+path, bytes.NewReader(body))
+		req.Header.Add("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		wh.serveInjectDiff(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status code %v: %v", w.Code, w.Body.String())
+		}
+		var diff PodDiff
+		if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil {
+			t.Fatal(err)
+		}
+		return diff
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", Annotations: map[string]string{}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+	}
+
+	// A pod that was never injected gets the sidecar added
+	diff := serve(t, pod, "/inject/diff")
+	if len(diff.Containers) != 1 || diff.Containers[0].Name != "istio-proxy" || diff.Containers[0].Change != Added {
+		t.Fatalf("expected istio-proxy to be added, got %+v", diff.Containers)
+	}
+
+	// Once injected, the same configuration produces no diff
+	params := wh.injectionParameters(pod.DeepCopy(), "/inject")
+	injected, err := renderInjectedPod(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := serve(t, injected, "/inject/diff"); !diff.Empty() {
+		t.Fatalf("expected no diff, got %+v", diff)
+	}
+
+	// Changing the template is reflected in the diff
+	wh.updateConfig(diffTemplateConfig("2"), wh.valuesConfig)
+	diff = serve(t, injected, "/inject/diff")
+	want := []ContainerDiff{{
+		Name:   "istio-proxy",
+		Change: Modified,
+		Fields: []FieldDiff{
+			{Name: "image", Change: Modified, Old: "proxy:1", New: "proxy:2"},
+			{Name: "env VERSION", Change: Modified, Old: "1", New: "2"},
+		},
+	}}
+	if !reflect.DeepEqual(diff.Containers, want) {
+		t.Fatalf("got containers %+v, want %+v", diff.Containers, want)
+	}
+
+	// Path parameters are applied like for the injection itself
+	diff = serve(t, injected, "/inject/diff/net/network2")
+	found := false
+	for _, l := range diff.Labels {
+		if l.Name == "topology.istio.io/network" && l.New == "network2" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected network label to be added, got %+v", diff.Labels)
+	}
+
+	// Pods opting out of injection are reported as skipped
+	pod.Annotations[annotation.SidecarInject.Name] = "false"
+	if diff := serve(t, pod, "/inject/diff"); !diff.InjectionSkipped {
+		t.Fatalf("expected injection to be skipped, got %+v", diff)
+	}
+}
+
+func TestDiffPods(t *testing.T) {
+	current := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{"app": "a", "old": "x"},
+			Annotations: map[string]string{"a": "1"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: "app"},
+				{Name: "stale", Image: "stale"},
+			},
+			Volumes: []corev1.Volume{{Name: "v1"}},
+		},
+	}
+	injected := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      map[string]string{"app": "a"},
+			Annotations: map[string]string{"a": "2"},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: "app", Args: []string{"--foo"}},
+			},
+			Volumes: []corev1.Volume{{Name: "v1"}, {Name: "v2"}},
+		},
+	}
+	got := DiffPods(current, injected)
+	want := PodDiff{
+		Containers: []ContainerDiff{
+			{Name: "app", Change: Modified, Fields: []FieldDiff{{Name: "args", Change: Added, New: "--foo"}}},
+			{Name: "stale", Change: Removed},
+		},
+		Volumes:     []FieldDiff{{Name: "v2", Change: Added, New: "{}"}},
+		Annotations: []FieldDiff{{Name: "a", Change: Modified, Old: "1", New: "2"}},
+		Labels:      []FieldDiff{{Name: "old", Change: Removed, Old: "x"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+// defaultedTemplateConfig is an injection template with fields the API server sets defaults for.
+func defaultedTemplateConfig() *Config {
+	return &Config{
+		Policy:           InjectionPolicyEnabled,
+		DefaultTemplates: []string{SidecarTemplateName},
+		Templates: map[string]string{SidecarTemplateName: `
+spec:
+  containers:
+  - name: istio-proxy
+    image: proxy:1
+    env:
+    - name: POD_NAME
+      valueFrom:
+        fieldRef:
+          fieldPath: metadata.name
+    ports:
+    - containerPort: 15090
+      name: http-envoy-prom
+    resources:
+      limits:
+        memory: 2048Mi
+    readinessProbe:
+      httpGet:
+        path: /healthz/ready
+        port: 15021
+  volumes:
+  - name: istio-token
+    secret:
+      secretName: istio-token
+`},
+	}
+}
+
+// setAPIServerDefaults applies the defaults set by the API server, as they show up on a pod read from the cluster.
+func setAPIServerDefaults(pod *corev1.Pod) {
+	defaultMode := int32(0o644)
+	for i := range pod.Spec.Volumes {
+		if s := pod.Spec.Volumes[i].Secret; s != nil {
+			s.DefaultMode = &defaultMode
+		}
+	}
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		c.TerminationMessagePath = corev1.TerminationMessagePathDefault
+		c.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+		c.ImagePullPolicy = corev1.PullIfNotPresent
+		for j := range c.Ports {
+			c.Ports[j].Protocol = corev1.ProtocolTCP
+		}
+		for j := range c.Env {
+			if c.Env[j].ValueFrom != nil && c.Env[j].ValueFrom.FieldRef != nil {
+				c.Env[j].ValueFrom.FieldRef.APIVersion = "v1"
+			}
+		}
+		if c.ReadinessProbe != nil {
+			c.ReadinessProbe.HTTPGet.Scheme = corev1.URISchemeHTTP
+			c.ReadinessProbe.TimeoutSeconds = 1
+			c.ReadinessProbe.PeriodSeconds = 10
+			c.ReadinessProbe.SuccessThreshold = 1
+			c.ReadinessProbe.FailureThreshold = 3
+		}
+		if mem, f := c.Resources.Limits[corev1.ResourceMemory]; f {
+			// The API server returns quantities in their canonical form
+			c.Resources.Limits[corev1.ResourceMemory] = resource.MustParse(mem.String())
+			c.Resources.Requests = c.Resources.Limits.DeepCopy()
+		}
+	}
+}
+
+// TestInjectDiffDefaultedPod checks that the defaults the API server adds to an injected pod are not
+// reported as changes.
+func TestInjectDiffDefaultedPod(t *testing.T) {
+	wh, _ := createWebhook(t, defaultedTemplateConfig())
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+	}
+	injected, err := renderInjectedPod(wh.injectionParameters(pod, "/inject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setAPIServerDefaults(injected)
+	if got := injected.Spec.Containers[1].Resources.Limits.Memory().String(); got != "2Gi" {
+		t.Fatalf("expected canonical quantity, got %v", got)
+	}
+
+	diff, err := diffInjection(wh.injectionParameters(injected, "/inject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Fatalf("expected no diff, got %+v", diff)
+	}
+}
@@ -162,10 +162,53 @@ func UnmarshalConfig(yml []byte) (Config, error) {
 		log.Warnf("injection templates are empty." +
 			" This may be caused by using an injection template from an older version of Istio." +
 			" Please ensure the template is correct; mismatch template versions can lead to unexpected results, including pods not being injected.")
+		return injectConfig, nil
+	}
+	if err := validateTemplates(injectConfig); err != nil {
+		return injectConfig, fmt.Errorf("invalid injection templates: %v", err)
 	}
 	return injectConfig, nil
 }
 
+// validateTemplates checks that all templates parse, and that all templates referenced by the default
+// templates and aliases exist. Without this, a broken template only surfaces when a pod requesting it
+// is injected.
+func validateTemplates(c Config) error {
+	var errs error
+	funcMap := CreateInjectionFuncmap()
+	// render is bound to the template data at injection time
+	funcMap["render"] = func(string) string { return "" }
+	names := knownTemplates(c.Templates)
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := template.New(name).Funcs(sprig.TxtFuncMap()).Funcs(funcMap).Parse(c.Templates[name]); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("template %q: %v", name, err))
+		}
+	}
+	aliases := make([]string, 0, len(c.Aliases))
+	for alias := range c.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		for _, name := range c.Aliases[alias] {
+			if _, f := c.Templates[name]; !f {
+				errs = multierror.Append(errs, fmt.Errorf("alias %q references unknown template %q", alias, name))
+			}
+		}
+	}
+	for _, name := range c.DefaultTemplates {
+		if _, f := c.Templates[name]; f {
+			continue
+		}
+		if _, f := c.Aliases[name]; f {
+			continue
+		}
+		errs = multierror.Append(errs, fmt.Errorf("default template %q not found", name))
+	}
+	return errs
+}
+
 func injectRequired(ignored []string, config *Config, podSpec *corev1.PodSpec, metadata metav1.ObjectMeta) bool { // nolint: lll
 	// Skip injection when host networking is enabled. The problem is
 	// that the iptables changes are assumed to be within the pod when,
@@ -402,6 +445,10 @@ func selectTemplates(params InjectionParameters) []string {
 		names := []string{}
 		for _, tmplName := range strings.Split(a, ",") {
 			name := strings.TrimSpace(tmplName)
+			if name == "" {
+				// Tolerate trailing or repeated separators, such as "sidecar,debug,"
+				continue
+			}
 			names = append(names, name)
 		}
 		return resolveAliases(params, names)
@@ -522,6 +522,96 @@ spec:
 `
 	runWebhook(t, webhook, []byte(input), []byte(fmt.Sprintf(expected, "sidecar,init")), false)
 	runWebhook(t, webhook, []byte(inputAlias), []byte(fmt.Sprintf(expected, "both")), false)
+	// Empty entries in the annotation are ignored
+	inputTrailing := strings.Replace(input, "sidecar,init", "sidecar, ,init,", 1)
+	runWebhook(t, webhook, []byte(inputTrailing), []byte(fmt.Sprintf(expected, "sidecar, ,init,")), false)
+}
+
+func TestUnmarshalConfigValidatesTemplates(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "valid",
+			config: `
+defaultTemplates: [sidecar, extras]
+aliases:
+  extras: [debug, spire]
+templates:
+  sidecar: |
+    metadata:
+      labels:
+        rev: {{ .Revision | default "default" }}
+  debug: |
+    spec: {}
+  spire: |
+    metadata:
+      annotations:
+        spire: {{ render "true" }}
+`,
+		},
+		{
+			name: "legacy config without templates",
+			config: `
+policy: enabled
+`,
+		},
+		{
+			name: "unparsable template",
+			config: `
+templates:
+  sidecar: |
+    metadata: {{ .Revision
+`,
+			err: `template "sidecar"`,
+		},
+		{
+			name: "unknown function",
+			config: `
+templates:
+  sidecar: |
+    metadata: {{ notAFunction }}
+`,
+			err: `function "notAFunction" not defined`,
+		},
+		{
+			name: "unknown default template",
+			config: `
+defaultTemplates: [sidecar, missing]
+templates:
+  sidecar: |
+    spec: {}
+`,
+			err: `default template "missing" not found`,
+		},
+		{
+			name: "alias to unknown template",
+			config: `
+aliases:
+  both: [sidecar, missing]
+templates:
+  sidecar: |
+    spec: {}
+`,
+			err: `alias "both" references unknown template "missing"`,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalConfig([]byte(tt.config))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
 }
 
 // TestStrategicMerge ensures we can use https://github.com/kubernetes/community/blob/master/contributors/devel/sig-api-machinery/strategic-merge-patch.md
@@ -19,6 +19,8 @@ import (
 )
 
 var (
+	namespaceTag = monitoring.MustCreateLabel("namespace")
+
 	totalInjections = monitoring.NewSum(
 		"sidecar_injection_requests_total",
 		"Total number of sidecar injection requests.",
@@ -38,6 +40,17 @@ var (
 		"sidecar_injection_skip_total",
 		"Total number of skipped sidecar injection requests.",
 	)
+
+	staleSidecars = monitoring.NewGauge(
+		"sidecar_injection_stale_pods",
+		"Number of injected pods whose sidecar differs from the current injection configuration of their revision.",
+		monitoring.WithLabels(namespaceTag),
+	)
+
+	staleSidecarRestarts = monitoring.NewSum(
+		"sidecar_injection_stale_restarts_total",
+		"Total number of deployments restarted to update stale sidecars.",
+	)
 )
 
 func init() {
@@ -46,5 +59,7 @@ func init() {
 		totalSuccessfulInjections,
 		totalFailedInjections,
 		totalSkippedInjections,
+		staleSidecars,
+		staleSidecarRestarts,
 	)
 }
@@ -0,0 +1,336 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	appslisterv1 "k8s.io/client-go/listers/apps/v1"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/protomarshal"
+	"istio.io/pkg/log"
+)
+
+// StaleSidecarOptions configures the StaleSidecarReconciler.
+type StaleSidecarOptions struct {
+	// Interval between two checks of all pods.
+	Interval time.Duration
+	// RestartsPerMinute is the maximum number of Deployments restarted per minute to pick up the current
+	// injection configuration. If zero, stale pods are only reported.
+	RestartsPerMinute int
+}
+
+// StaleSidecarReconciler periodically compares the pods injected by this revision with the output of
+// the current injection configuration. Pods whose injected containers, volumes or injection status
+// differ are considered stale, as they would only pick up the current configuration once restarted.
+// The number of stale pods per namespace is exported as a metric, and the owning Deployments of stale
+// pods can optionally be restarted, at a limited rate.
+type StaleSidecarReconciler struct {
+	webhook *Webhook
+	client  kube.Client
+	opts    StaleSidecarOptions
+
+	podInformer cache.SharedIndexInformer
+	podLister   listerv1.PodLister
+	// ReplicaSets are only watched if restarts are enabled, to find the Deployments owning stale pods
+	replicaSetInformer cache.SharedIndexInformer
+	replicaSetLister   appslisterv1.ReplicaSetLister
+
+	restartLimiter *rate.Limiter
+	// namespaces with stale pods in the last check, so their metric can be reset once they are up to date
+	staleNamespaces map[string]struct{}
+}
+
+// NewStaleSidecarReconciler creates a StaleSidecarReconciler for the pods injected by the webhook.
+func NewStaleSidecarReconciler(wh *Webhook, client kube.Client, opts StaleSidecarOptions) *StaleSidecarReconciler {
+	pods := client.KubeInformer().Core().V1().Pods()
+	r := &StaleSidecarReconciler{
+		webhook:         wh,
+		client:          client,
+		opts:            opts,
+		podInformer:     pods.Informer(),
+		podLister:       pods.Lister(),
+		staleNamespaces: map[string]struct{}{},
+	}
+	if opts.RestartsPerMinute > 0 {
+		// Restarts only happen once per interval, so allow a burst of all the restarts accumulated since the
+		// previous check.
+		burst := int(int64(opts.RestartsPerMinute) * int64(opts.Interval) / int64(time.Minute))
+		if burst < 1 {
+			burst = 1
+		}
+		r.restartLimiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(opts.RestartsPerMinute)), burst)
+		replicaSets := client.KubeInformer().Apps().V1().ReplicaSets()
+		r.replicaSetInformer = replicaSets.Informer()
+		r.replicaSetLister = replicaSets.Lister()
+	}
+	return r
+}
+
+// Run checks for stale pods every interval until stop is closed.
+func (r *StaleSidecarReconciler) Run(stop <-chan struct{}) {
+	synced := []cache.InformerSynced{r.podInformer.HasSynced}
+	if r.replicaSetInformer != nil {
+		synced = append(synced, r.replicaSetInformer.HasSynced)
+	}
+	if !cache.WaitForCacheSync(stop, synced...) {
+		log.Error("Failed to sync stale sidecar reconciler cache")
+		return
+	}
+	log.Infof("Stale sidecar reconciler started, checking every %v", r.opts.Interval)
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		r.Reconcile()
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile checks all pods once, records the stale pod metric and restarts Deployments if enabled.
+func (r *StaleSidecarReconciler) Reconcile() {
+	pods, err := r.podLister.List(klabels.Everything())
+	if err != nil {
+		log.Errorf("failed to list pods: %v", err)
+		return
+	}
+	stale := map[string]int{}
+	deployments := map[types.NamespacedName]struct{}{}
+	for _, pod := range pods {
+		reason, err := r.staleReason(pod)
+		if err != nil {
+			log.Debugf("failed to check pod %s/%s for stale sidecar: %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		if reason == "" {
+			continue
+		}
+		log.Debugf("pod %s/%s has a stale sidecar: %s", pod.Namespace, pod.Name, reason)
+		stale[pod.Namespace]++
+		if r.restartLimiter == nil {
+			continue
+		}
+		if name, f := r.owningDeployment(pod); f {
+			deployments[name] = struct{}{}
+		}
+	}
+
+	for ns := range r.staleNamespaces {
+		if _, f := stale[ns]; !f {
+			staleSidecars.With(namespaceTag.Value(ns)).Record(0)
+		}
+	}
+	r.staleNamespaces = map[string]struct{}{}
+	for ns, count := range stale {
+		staleSidecars.With(namespaceTag.Value(ns)).Record(float64(count))
+		r.staleNamespaces[ns] = struct{}{}
+	}
+
+	if r.restartLimiter != nil {
+		hash, err := r.configHash()
+		if err != nil {
+			log.Errorf("failed to hash injection configuration: %v", err)
+			return
+		}
+		r.restartDeployments(deployments, hash)
+	}
+}
+
+// configHash returns a hash of the injection configuration, recorded on restarted Deployments so each Deployment is
+// restarted at most once per configuration.
+func (r *StaleSidecarReconciler) configHash() (string, error) {
+	wh := r.webhook
+	wh.mu.RLock()
+	defer wh.mu.RUnlock()
+	h := sha256.New()
+	cfg, err := json.Marshal(wh.Config)
+	if err != nil {
+		return "", err
+	}
+	h.Write(cfg)
+	h.Write([]byte(wh.valuesConfig))
+	if wh.meshConfig != nil {
+		mesh, err := protomarshal.ToJSON(wh.meshConfig)
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte(mesh))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// staleReason returns why a pod is stale, or an empty string if the pod is up to date or was not injected by
+// this revision.
+func (r *StaleSidecarReconciler) staleReason(pod *corev1.Pod) (string, error) {
+	status := injectionStatus(pod)
+	if status == nil || pod.DeletionTimestamp != nil {
+		// Not injected, or going away anyways
+		return "", nil
+	}
+	wh := r.webhook
+	wh.mu.RLock()
+	if !revisionMatches(status.Revision, wh.revision) {
+		wh.mu.RUnlock()
+		return "", nil
+	}
+	if !injectRequired(IgnoredNamespaces, wh.Config, &pod.Spec, pod.ObjectMeta) {
+		wh.mu.RUnlock()
+		return "", nil
+	}
+	params := wh.injectionParameters(pod.DeepCopy(), "")
+	wh.mu.RUnlock()
+	// The webhook may be called with cluster and network parameters in the path; keep the ones the pod was
+	// injected with, so they do not show up as changes.
+	params.proxyEnvs = proxyEnvsFromPod(pod)
+
+	diff, err := diffInjection(params)
+	if err != nil {
+		return "", err
+	}
+	return staleReasonFromDiff(diff), nil
+}
+
+// staleReasonFromDiff determines whether the differences between a running pod and the injection output
+// require a restart. Differences in labels and annotations other than the injection status are ignored,
+// as they do not affect the running proxy.
+func staleReasonFromDiff(diff PodDiff) string {
+	for _, c := range diff.Containers {
+		return fmt.Sprintf("container %s %s", c.Name, c.Change)
+	}
+	for _, c := range diff.InitContainers {
+		return fmt.Sprintf("init container %s %s", c.Name, c.Change)
+	}
+	for _, v := range diff.Volumes {
+		return fmt.Sprintf("volume %s %s", v.Name, v.Change)
+	}
+	for _, a := range diff.Annotations {
+		if a.Name == annotation.SidecarStatus.Name {
+			return "injection status changed"
+		}
+	}
+	return ""
+}
+
+func revisionMatches(podRevision, revision string) bool {
+	if podRevision == "" {
+		podRevision = "default"
+	}
+	if revision == "" {
+		revision = "default"
+	}
+	return podRevision == revision
+}
+
+// proxyEnvsFromPod extracts the injection parameters that may be passed through the webhook path from the
+// injected proxy of a pod.
+func proxyEnvsFromPod(pod *corev1.Pod) map[string]string {
+	envs := map[string]string{}
+	for _, c := range pod.Spec.Containers {
+		if c.Name != ProxyContainerName {
+			continue
+		}
+		for _, e := range c.Env {
+			for _, env := range URLParameterToEnv {
+				if e.Name == env && e.Value != "" {
+					envs[env] = e.Value
+				}
+			}
+		}
+	}
+	return envs
+}
+
+// owningDeployment finds the Deployment controlling a pod through its ReplicaSet.
+func (r *StaleSidecarReconciler) owningDeployment(pod *corev1.Pod) (types.NamespacedName, bool) {
+	rsRef := metav1.GetControllerOf(pod)
+	if rsRef == nil || rsRef.Kind != "ReplicaSet" {
+		return types.NamespacedName{}, false
+	}
+	rs, err := r.replicaSetLister.ReplicaSets(pod.Namespace).Get(rsRef.Name)
+	if err != nil {
+		return types.NamespacedName{}, false
+	}
+	depRef := metav1.GetControllerOf(rs)
+	if depRef == nil || depRef.Kind != "Deployment" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: pod.Namespace, Name: depRef.Name}, true
+}
+
+// restartDeployments triggers a rolling restart of the Deployments, the same way `kubectl rollout restart` does.
+// Deployments with a rollout in progress are skipped, as their remaining old pods are expected to be stale.
+// Deployments already restarted for the configuration hash are skipped as well: if their new pods are still
+// considered stale, restarting them again would not help and would loop forever.
+func (r *StaleSidecarReconciler) restartDeployments(deployments map[types.NamespacedName]struct{}, hash string) {
+	names := make([]types.NamespacedName, 0, len(deployments))
+	for name := range deployments {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i].String() < names[j].String()
+	})
+	for _, name := range names {
+		deployments := r.client.Kube().AppsV1().Deployments(name.Namespace)
+		d, err := deployments.Get(context.TODO(), name.Name, metav1.GetOptions{})
+		if err != nil {
+			log.Warnf("failed to get deployment %v: %v", name, err)
+			continue
+		}
+		if d.Generation != d.Status.ObservedGeneration || d.Status.UpdatedReplicas != d.Status.Replicas {
+			log.Debugf("skipping restart of deployment %v, rollout in progress", name)
+			continue
+		}
+		if d.Annotations[restartedForConfigAnnotation] == hash {
+			log.Debugf("skipping restart of deployment %v, already restarted for the current injection configuration", name)
+			continue
+		}
+		if !r.restartLimiter.Allow() {
+			// Remaining deployments will be restarted in a later check
+			return
+		}
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}},"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`,
+			restartedForConfigAnnotation, hash, restartedAtAnnotation, time.Now().Format(time.RFC3339))
+		if _, err := deployments.Patch(context.TODO(), name.Name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			log.Warnf("failed to restart deployment %v: %v", name, err)
+			continue
+		}
+		staleSidecarRestarts.Increment()
+		log.Infof("restarted deployment %v to update stale sidecars", name)
+	}
+}
+
+// restartedAtAnnotation is the annotation set by `kubectl rollout restart`.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// restartedForConfigAnnotation records the hash of the injection configuration a Deployment was last restarted for.
+const restartedForConfigAnnotation = "sidecar.istio.io/restartedForConfig"
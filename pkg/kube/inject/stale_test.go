@@ -0,0 +1,171 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestStaleSidecarReconciler(t *testing.T) {
+	wh, _ := createWebhook(t, diffTemplateConfig("1"))
+	client := kube.NewFakeClient()
+	r := NewStaleSidecarReconciler(wh, client, StaleSidecarOptions{Interval: time.Minute, RestartsPerMinute: 60})
+	stop := make(chan struct{})
+	defer close(stop)
+	client.RunAndWait(stop)
+
+	controller := true
+	dep := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "dep"}}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+		Name:            "app-1234",
+		Namespace:       "default",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "app", UID: "dep", Controller: &controller}},
+	}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "app-1234-abcde",
+			Namespace:       "default",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "app-1234", Controller: &controller}},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+	}
+	injected, err := renderInjectedPod(wh.injectionParameters(pod, "/inject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Kube().AppsV1().Deployments("default").Create(context.TODO(), dep, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Kube().AppsV1().ReplicaSets("default").Create(context.TODO(), rs, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Kube().CoreV1().Pods("default").Create(context.TODO(), injected, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		if _, err := r.podLister.Pods("default").Get(injected.Name); err != nil {
+			return err
+		}
+		if _, err := r.replicaSetLister.ReplicaSets("default").Get(rs.Name); err != nil {
+			return err
+		}
+		return nil
+	})
+
+	restartedAt := func() string {
+		d, err := client.Kube().AppsV1().Deployments("default").Get(context.TODO(), "app", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d.Spec.Template.Annotations[restartedAtAnnotation]
+	}
+	restarts := func() int {
+		n := 0
+		for _, a := range client.Kube().(*fake.Clientset).Actions() {
+			if a.GetVerb() == "patch" && a.GetResource().Resource == "deployments" {
+				n++
+			}
+		}
+		return n
+	}
+
+	// A freshly injected pod is up to date
+	if reason, err := r.staleReason(injected); err != nil || reason != "" {
+		t.Fatalf("expected pod to be up to date, got %q, %v", reason, err)
+	}
+	r.Reconcile()
+	if got := restartedAt(); got != "" {
+		t.Fatalf("expected no restart, got %v", got)
+	}
+
+	// Once the template changes, the pod is stale and its deployment is restarted
+	wh.updateConfig(diffTemplateConfig("2"), wh.valuesConfig)
+	if reason, err := r.staleReason(injected); err != nil || reason != fmt.Sprintf("container istio-proxy %s", Modified) {
+		t.Fatalf("expected pod to be stale, got %q, %v", reason, err)
+	}
+	r.Reconcile()
+	if got := restartedAt(); got == "" {
+		t.Fatalf("expected deployment to be restarted")
+	}
+
+	// The pod is still stale, but the deployment was already restarted for this configuration
+	r.Reconcile()
+	if got := restarts(); got != 1 {
+		t.Fatalf("expected a single restart for the same configuration, got %v", got)
+	}
+
+	// Another configuration change restarts the deployment again
+	wh.updateConfig(diffTemplateConfig("3"), wh.valuesConfig)
+	r.Reconcile()
+	if got := restarts(); got != 2 {
+		t.Fatalf("expected a restart for the new configuration, got %v restarts", got)
+	}
+
+	// Pods of other revisions are ignored
+	wh.mu.Lock()
+	wh.revision = "canary"
+	wh.mu.Unlock()
+	if reason, err := r.staleReason(injected); err != nil || reason != "" {
+		t.Fatalf("expected pod of other revision to be ignored, got %q, %v", reason, err)
+	}
+}
+
+func TestStaleSidecarDefaultedPod(t *testing.T) {
+	wh, _ := createWebhook(t, defaultedTemplateConfig())
+	r := NewStaleSidecarReconciler(wh, kube.NewFakeClient(), StaleSidecarOptions{Interval: time.Minute})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}},
+	}
+	injected, err := renderInjectedPod(wh.injectionParameters(pod, "/inject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A pod read from the API server has defaults set, which must not make it look stale
+	setAPIServerDefaults(injected)
+	if reason, err := r.staleReason(injected); err != nil || reason != "" {
+		t.Fatalf("expected defaulted pod to be up to date, got %q, %v", reason, err)
+	}
+}
+
+func TestStaleSidecarRestartBurst(t *testing.T) {
+	wh, _ := createWebhook(t, diffTemplateConfig("1"))
+	cases := []struct {
+		interval time.Duration
+		perMin   int
+		burst    int
+	}{
+		{interval: 5 * time.Minute, perMin: 2, burst: 10},
+		{interval: time.Minute, perMin: 3, burst: 3},
+		{interval: time.Second, perMin: 1, burst: 1},
+	}
+	for _, tt := range cases {
+		r := NewStaleSidecarReconciler(wh, kube.NewFakeClient(), StaleSidecarOptions{Interval: tt.interval, RestartsPerMinute: tt.perMin})
+		if got := r.restartLimiter.Burst(); got != tt.burst {
+			t.Errorf("%v restarts per minute every %v: got burst %v, want %v", tt.perMin, tt.interval, got, tt.burst)
+		}
+	}
+}
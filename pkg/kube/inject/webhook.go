@@ -157,6 +157,8 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 
 	p.Mux.HandleFunc("/inject", wh.serveInject)
 	p.Mux.HandleFunc("/inject/", wh.serveInject)
+	p.Mux.HandleFunc("/inject"+DiffPathSuffix, wh.serveInjectDiff)
+	p.Mux.HandleFunc("/inject"+DiffPathSuffix+"/", wh.serveInjectDiff)
 
 	p.Env.Watcher.AddMeshHandler(func() {
 		wh.mu.Lock()
@@ -338,8 +340,8 @@ func getInjectionStatus(podSpec corev1.PodSpec, revision string) string {
 // The injection logic works by first applying the rendered injection template on
 // top of the input pod This is done using a Strategic Patch Merge
 // (https://github.com/kubernetes/community/blob/master/contributors/devel/sig-api-machinery/strategic-merge-patch.md)
-// Currently only a single template is supported, although in the future the template to use will be configurable
-// and multiple templates will be supported by applying them in successive order.
+// The templates to use are selected by the TemplatesAnnotation, falling back to the DefaultTemplates. When multiple
+// templates are selected, they are applied in successive order, each rendered with the same template data.
 //
 // In addition to the plain templating, there is some post processing done to
 // handle cases that cannot feasibly be covered in the template, such as
@@ -353,6 +355,22 @@ func injectPod(req InjectionParameters) ([]byte, error) {
 		return nil, err
 	}
 
+	mergedPod, err := renderInjectedPod(req)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := createPatch(mergedPod, originalPodSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patch: %v", err)
+	}
+
+	log.Debugf("AdmissionResponse: patch=%v\n", string(patch))
+	return patch, nil
+}
+
+// renderInjectedPod runs the injection templates and post processing, returning the fully injected pod.
+func renderInjectedPod(req InjectionParameters) (*corev1.Pod, error) {
 	// Run the injection template, giving us a partial pod spec
 	mergedPod, injectedPodData, err := RunTemplate(req)
 	if err != nil {
@@ -368,14 +386,7 @@ func injectPod(req InjectionParameters) ([]byte, error) {
 	if err := postProcessPod(mergedPod, *injectedPodData, req); err != nil {
 		return nil, fmt.Errorf("failed to process pod: %v", err)
 	}
-
-	patch, err := createPatch(mergedPod, originalPodSpec)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create patch: %v", err)
-	}
-
-	log.Debugf("AdmissionResponse: patch=%v\n", string(patch))
-	return patch, nil
+	return mergedPod, nil
 }
 
 // OverrideAnnotation is used to store the overrides for injected containers
@@ -741,20 +752,7 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 		}
 	}
 
-	deploy, typeMeta := kube.GetDeployMetaFromPod(&pod)
-	params := InjectionParameters{
-		pod:                 &pod,
-		deployMeta:          deploy,
-		typeMeta:            typeMeta,
-		templates:           wh.Config.Templates,
-		defaultTemplate:     wh.Config.DefaultTemplates,
-		aliases:             wh.Config.Aliases,
-		meshConfig:          wh.meshConfig,
-		valuesConfig:        wh.valuesConfig,
-		revision:            wh.revision,
-		injectedAnnotations: wh.Config.InjectedAnnotations,
-		proxyEnvs:           parseInjectEnvs(path),
-	}
+	params := wh.injectionParameters(&pod, path)
 	wh.mu.RUnlock()
 
 	patchBytes, err := injectPod(params)
@@ -775,6 +773,78 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 	return &reviewResponse
 }
 
+// injectionParameters builds the parameters to inject a pod with the current configuration.
+// The caller must hold wh.mu.
+func (wh *Webhook) injectionParameters(pod *corev1.Pod, path string) InjectionParameters {
+	deploy, typeMeta := kube.GetDeployMetaFromPod(pod)
+	return InjectionParameters{
+		pod:                 pod,
+		deployMeta:          deploy,
+		typeMeta:            typeMeta,
+		templates:           wh.Config.Templates,
+		defaultTemplate:     wh.Config.DefaultTemplates,
+		aliases:             wh.Config.Aliases,
+		meshConfig:          wh.meshConfig,
+		valuesConfig:        wh.valuesConfig,
+		revision:            wh.revision,
+		injectedAnnotations: wh.Config.InjectedAnnotations,
+		proxyEnvs:           parseInjectEnvs(path),
+	}
+}
+
+// serveInjectDiff runs a pod through the current injection configuration, and returns a PodDiff
+// describing how the injected pod would differ from the pod as provided. This is typically used with an
+// already running pod, to determine whether it needs to be restarted to pick up configuration changes.
+// The request body is the JSON encoded pod. The path accepts the same parameters as /inject, for example
+// /inject/diff/cluster/cluster1/net/network1.
+func (wh *Webhook) serveInjectDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
+		http.Error(w, "invalid Content-Type, want `application/json`", http.StatusUnsupportedMediaType)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || len(body) == 0 {
+		http.Error(w, "no body found", http.StatusBadRequest)
+		return
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal(body, &pod); err != nil {
+		http.Error(w, fmt.Sprintf("could not unmarshal pod: %v", err), http.StatusBadRequest)
+		return
+	}
+	pod.ManagedFields = nil
+	path := strings.Replace(r.URL.Path, "/inject"+DiffPathSuffix, "/inject", 1)
+	log.Debugf("Sidecar injection diff request for %v/%v", pod.Namespace, potentialPodName(pod.ObjectMeta))
+
+	var diff PodDiff
+	wh.mu.RLock()
+	if !injectRequired(IgnoredNamespaces, wh.Config, &pod.Spec, pod.ObjectMeta) {
+		wh.mu.RUnlock()
+		diff = PodDiff{InjectionSkipped: true}
+	} else {
+		params := wh.injectionParameters(&pod, path)
+		wh.mu.RUnlock()
+		if diff, err = diffInjection(params); err != nil {
+			http.Error(w, fmt.Sprintf("failed to inject pod: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	resp, err := json.Marshal(diff)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("could not encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		log.Errorf("Could not write response: %v", err)
+	}
+}
+
 func (wh *Webhook) serveInject(w http.ResponseWriter, r *http.Request) {
 	totalInjections.Increment()
 	var body []byte
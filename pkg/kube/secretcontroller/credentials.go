@@ -0,0 +1,62 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretcontroller
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	"istio.io/istio/security/pkg/util"
+)
+
+// credentialExpiry returns when the credential of the current context of a kubeconfig expires. Client certificates
+// expire at their NotAfter time and bearer tokens at their exp claim. It returns false if the credential does not
+// expire or its expiry can't be determined, for example for exec or auth provider plugins.
+func credentialExpiry(kubeConfig []byte) (time.Time, bool) {
+	rawConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return time.Time{}, false
+	}
+	ctx := rawConfig.Contexts[rawConfig.CurrentContext]
+	if ctx == nil {
+		return time.Time{}, false
+	}
+	authInfo := rawConfig.AuthInfos[ctx.AuthInfo]
+	if authInfo == nil {
+		return time.Time{}, false
+	}
+	if len(authInfo.ClientCertificateData) > 0 {
+		block, _ := pem.Decode(authInfo.ClientCertificateData)
+		if block == nil {
+			return time.Time{}, false
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return cert.NotAfter, true
+	}
+	if authInfo.Token != "" {
+		exp, err := util.GetExp(authInfo.Token)
+		if err != nil || exp.IsZero() {
+			return time.Time{}, false
+		}
+		return exp, true
+	}
+	return time.Time{}, false
+}
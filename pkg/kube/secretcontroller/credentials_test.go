@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretcontroller
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func kubeConfigWithUser(user string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://remote.example.com
+  name: remote
+contexts:
+- context:
+    cluster: remote
+    user: remote
+  name: remote
+current-context: remote
+users:
+- name: remote
+  user:
+%s
+`, user))
+}
+
+func TestCredentialExpiry(t *testing.T) {
+	exp := time.Unix(1700000000, 0)
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	token := "eyJhbGciOiJSUzI1NiJ9." + payload + ".c2lnbmF0dXJl"
+
+	cases := []struct {
+		name       string
+		kubeConfig []byte
+		want       time.Time
+		wantFound  bool
+	}{
+		{
+			name:       "token with exp claim",
+			kubeConfig: kubeConfigWithUser("    token: " + token),
+			want:       exp,
+			wantFound:  true,
+		},
+		{
+			name: "exec plugin",
+			kubeConfig: kubeConfigWithUser(`    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: get-token`),
+		},
+		{
+			name:       "invalid kubeconfig",
+			kubeConfig: []byte("not a kubeconfig"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, found := credentialExpiry(c.kubeConfig)
+			if found != c.wantFound || !got.Equal(c.want) {
+				t.Fatalf("got %v %v, want %v %v", got, found, c.want, c.wantFound)
+			}
+		})
+	}
+}
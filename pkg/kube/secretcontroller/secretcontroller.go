@@ -24,6 +24,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hashicorp/go-multierror"
 	"go.uber.org/atomic"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,15 +48,51 @@ const (
 	initialSyncSignal       = "INIT"
 	MultiClusterSecretLabel = "istio/multiCluster"
 	maxRetries              = 5
+
+	// TokenRequestServiceAccountAnnotation marks a remote secret whose kubeconfigs use short-lived TokenRequest tokens
+	// of a service account of the remote cluster, as namespace/name. Istiod requests a new token with the bootstrap
+	// token of the cluster before the current one expires, and updates the secret.
+	TokenRequestServiceAccountAnnotation = "networking.istio.io/token-request-service-account"
+	// TokenRequestDurationAnnotation is the lifetime of the requested tokens, for example "1h".
+	TokenRequestDurationAnnotation = "networking.istio.io/token-request-duration"
+	// TokenRequestBootstrapTokenSuffix is appended to the cluster ID for the key of the bootstrap token of a cluster in
+	// a secret annotated with TokenRequestServiceAccountAnnotation. The bootstrap token is a long-lived token of a
+	// separate service account, which may only request tokens of the annotated service account. The short-lived
+	// tokens cannot request new tokens, so a leaked one is only valid until it expires. Since tokens are requested
+	// with the bootstrap token, istiod recovers on its own after being down for longer than the token lifetime; if
+	// the bootstrap token was revoked, the secret must be created again.
+	TokenRequestBootstrapTokenSuffix = ".token-request-bootstrap"
 )
 
 func init() {
-	monitoring.MustRegister(timeouts)
+	monitoring.MustRegister(timeouts, syncDuration, credentialExpiration, tokenRefreshFailures)
 }
 
-var timeouts = monitoring.NewSum(
-	"remote_cluster_sync_timeouts_total",
-	"Number of times remote clusters took too long to sync, causing slow startup that excludes remote clusters.",
+var (
+	clusterLabel = monitoring.MustCreateLabel("cluster")
+
+	timeouts = monitoring.NewSum(
+		"remote_cluster_sync_timeouts_total",
+		"Number of times remote clusters took too long to sync, causing slow startup that excludes remote clusters.",
+	)
+
+	syncDuration = monitoring.NewGauge(
+		"remote_cluster_sync_duration_seconds",
+		"Time the informers of a remote cluster took for their initial sync.",
+		monitoring.WithLabels(clusterLabel),
+	)
+
+	credentialExpiration = monitoring.NewGauge(
+		"remote_cluster_credential_expiry_timestamp_seconds",
+		"Unix time when the credential of the kubeconfig of a remote cluster expires.",
+		monitoring.WithLabels(clusterLabel),
+	)
+
+	tokenRefreshFailures = monitoring.NewSum(
+		"remote_cluster_token_refresh_failures_total",
+		"Number of failed attempts to request a new token of a remote cluster or to write it to its secret.",
+		monitoring.WithLabels(clusterLabel),
+	)
 )
 
 // newClientCallback prototype for the add secret callback function.
@@ -66,11 +103,15 @@ type removeClientCallback func(clusterID cluster.ID) error
 
 // Controller is the controller implementation for Secret resources
 type Controller struct {
-	namespace string
-	queue     workqueue.RateLimitingInterface
-	informer  cache.SharedIndexInformer
+	namespace     string
+	kubeclientset kubernetes.Interface
+	queue         workqueue.RateLimitingInterface
+	informer      cache.SharedIndexInformer
 
 	cs *ClusterStore
+	// pendingUpdates are the clusters built from an updated kubeconfig whose update callback has not completed yet.
+	// Protected by the ClusterStore lock.
+	pendingUpdates map[cluster.ID]*Cluster
 
 	addCallback    newClientCallback
 	updateCallback newClientCallback
@@ -96,13 +137,27 @@ type Cluster struct {
 	initialSync *atomic.Bool
 	// SyncTimeout is marked after features.RemoteClusterTimeout
 	SyncTimeout *atomic.Bool
+	// syncStarted and syncDone are the times in Unix nanoseconds when Run started and completed the initial sync
+	syncStarted *atomic.Int64
+	syncDone    *atomic.Int64
+	// credentialExpiry is when the credential of the kubeconfig expires, zero if unknown
+	credentialExpiry time.Time
+	// tokenSource rotates the token of clusters whose secret is annotated with TokenRequestServiceAccountAnnotation
+	tokenSource *tokenRequestSource
 }
 
 // Run starts the cluster's informers and waits for caches to sync. Once caches are synced, we mark the cluster synced.
 // This should be called after each of the handlers have registered informers, and should be run in a goroutine.
 func (r *Cluster) Run() {
+	start := time.Now()
+	r.syncStarted.Store(start.UnixNano())
+	if r.tokenSource != nil {
+		go r.tokenSource.run(r.tokenSource.bootstrapClient, r.Stop)
+	}
 	r.Client.RunAndWait(r.Stop)
+	r.syncDone.Store(time.Now().UnixNano())
 	r.initialSync.Store(true)
+	syncDuration.With(clusterLabel.Value(r.clusterID)).Record(time.Since(start).Seconds())
 }
 
 func (r *Cluster) HasSynced() bool {
@@ -209,7 +264,9 @@ func NewController(
 
 	controller := &Controller{
 		namespace:      namespace,
+		kubeclientset:  kubeclientset,
 		cs:             newClustersStore(),
+		pendingUpdates: make(map[cluster.ID]*Cluster),
 		informer:       secretsInformer,
 		queue:          queue,
 		addCallback:    addCallback,
@@ -283,6 +340,10 @@ func (c *Controller) close() {
 			close(cluster.Stop)
 		}
 	}
+	for clusterID, pending := range c.pendingUpdates {
+		close(pending.Stop)
+		delete(c.pendingUpdates, clusterID)
+	}
 }
 
 func (c *Controller) hasSynced() bool {
@@ -381,7 +442,9 @@ func (c *Controller) processItem(key string) error {
 	}
 	if exists {
 		log.Debugf("secret %s exists in informer cache, processing it", key)
-		c.addSecret(key, obj.(*corev1.Secret))
+		if err := c.addSecret(key, obj.(*corev1.Secret)); err != nil {
+			return err
+		}
 	} else {
 		log.Debugf("secret %s does not exist in informer cache, deleting it", key)
 		c.deleteSecret(key)
@@ -414,24 +477,43 @@ var BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
 	return clients, nil
 }
 
-func (c *Controller) createRemoteCluster(kubeConfig []byte, clusterID string) (*Cluster, error) {
-	clients, err := BuildClientsFromConfig(kubeConfig)
+func (c *Controller) createRemoteCluster(kubeConfig []byte, clusterID string, tokenSource *tokenRequestSource) (*Cluster, error) {
+	var clients kube.Client
+	var err error
+	if tokenSource != nil {
+		clients, err = buildTokenRequestClients(kubeConfig, tokenSource)
+	} else {
+		clients, err = BuildClientsFromConfig(kubeConfig)
+	}
 	if err != nil {
 		return nil, err
 	}
+	expiry, ok := credentialExpiry(kubeConfig)
+	kubeConfigSha := sha256.Sum256(kubeConfig)
+	if tokenSource != nil {
+		kubeConfigSha = tokenSource.kubeConfigSha
+	}
+	if ok {
+		credentialExpiration.With(clusterLabel.Value(clusterID)).Record(float64(expiry.Unix()))
+	}
 	return &Cluster{
 		clusterID: clusterID,
 		Client:    clients,
 		// access outside this package should only be reading
 		Stop: make(chan struct{}),
 		// for use inside the package, to close on cleanup
-		initialSync:   atomic.NewBool(false),
-		SyncTimeout:   &c.remoteSyncTimeout,
-		kubeConfigSha: sha256.Sum256(kubeConfig),
+		initialSync:      atomic.NewBool(false),
+		SyncTimeout:      &c.remoteSyncTimeout,
+		kubeConfigSha:    kubeConfigSha,
+		syncStarted:      atomic.NewInt64(0),
+		syncDone:         atomic.NewInt64(0),
+		credentialExpiry: expiry,
+		tokenSource:      tokenSource,
 	}, nil
 }
 
-func (c *Controller) addSecret(secretKey string, s *corev1.Secret) {
+func (c *Controller) addSecret(secretKey string, s *corev1.Secret) error {
+	var errs *multierror.Error
 	// First delete clusters
 	existingClusters := c.cs.GetExistingClustersFor(secretKey)
 	for _, existingCluster := range existingClusters {
@@ -441,22 +523,58 @@ func (c *Controller) addSecret(secretKey string, s *corev1.Secret) {
 	}
 
 	for clusterID, kubeConfig := range s.Data {
+		if isBootstrapTokenKey(clusterID) {
+			continue
+		}
 		action, callback := "Adding", c.addCallback
-		if prev := c.cs.Get(secretKey, cluster.ID(clusterID)); prev != nil {
+		prev := c.cs.Get(secretKey, cluster.ID(clusterID))
+		if prev != nil {
 			action, callback = "Updating", c.updateCallback
+		}
+		tokenSource, err := c.newTokenRequestSource(secretKey, s, clusterID, kubeConfig)
+		if err != nil {
+			// The token of the kubeconfig is still valid for a while, so use it as it is rather than dropping the
+			// cluster, and retry
+			log.Errorf("%s cluster_id=%v from secret=%v without token refresh: %v", action, clusterID, secretKey, err)
+			errs = multierror.Append(errs, fmt.Errorf("token refresh of cluster_id=%v: %v", clusterID, err))
+			tokenSource = nil
+		}
+		if prev != nil {
 			// clusterID must be unique even across multiple secrets
 			// TODO： warning
 			kubeConfigSha := sha256.Sum256(kubeConfig)
+			if tokenSource != nil {
+				kubeConfigSha = tokenSource.kubeConfigSha
+			}
 			if bytes.Equal(kubeConfigSha[:], prev.kubeConfigSha[:]) {
+				if prev.tokenSource != nil && tokenSource != nil {
+					// only the token changed, which is rotated without rebuilding the clients
+					prev.tokenSource.update(tokenSource)
+				}
 				log.Infof("skipping update of cluster_id=%v from secret=%v: (kubeconfig are identical)", clusterID, secretKey)
 				continue
 			}
+			if pending := c.pendingUpdate(cluster.ID(clusterID)); pending != nil && bytes.Equal(kubeConfigSha[:], pending.kubeConfigSha[:]) {
+				if pending.tokenSource != nil && tokenSource != nil {
+					pending.tokenSource.update(tokenSource)
+				}
+				log.Infof("skipping update of cluster_id=%v from secret=%v: (update in progress)", clusterID, secretKey)
+				continue
+			}
 		}
 		log.Infof("%s cluster %v from secret %v", action, clusterID, secretKey)
 
-		remoteCluster, err := c.createRemoteCluster(kubeConfig, clusterID)
+		remoteCluster, err := c.createRemoteCluster(kubeConfig, clusterID, tokenSource)
 		if err != nil {
 			log.Errorf("%s cluster_id=%v from secret=%v: %v", action, clusterID, secretKey, err)
+			if prev != nil {
+				// keep using the previous kubeconfig, and retry the update
+				errs = multierror.Append(errs, fmt.Errorf("updating cluster_id=%v: %v", clusterID, err))
+			}
+			continue
+		}
+		if prev != nil {
+			c.updateMemberCluster(secretKey, cluster.ID(clusterID), prev, remoteCluster)
 			continue
 		}
 		c.cs.Store(secretKey, cluster.ID(clusterID), remoteCluster)
@@ -469,6 +587,62 @@ func (c *Controller) addSecret(secretKey string, s *corev1.Secret) {
 	}
 
 	log.Infof("Number of remote clusters: %d", c.cs.Len())
+	return errs.ErrorOrNil()
+}
+
+// pendingUpdate returns the cluster built from an updated kubeconfig, if its update has not completed yet.
+func (c *Controller) pendingUpdate(clusterID cluster.ID) *Cluster {
+	c.cs.RLock()
+	defer c.cs.RUnlock()
+	return c.pendingUpdates[clusterID]
+}
+
+// updateMemberCluster replaces prev with the cluster built from its updated kubeconfig. The update callback may wait
+// for the informers of the new client to sync, so it is called in a goroutine rather than blocking the processing of
+// other secrets. The previous cluster is only stopped once the callback has switched over to the new one; if the
+// callback fails, the previous kubeconfig stays in use and the secret is requeued to retry the update.
+func (c *Controller) updateMemberCluster(secretKey string, clusterID cluster.ID, prev, remoteCluster *Cluster) {
+	c.cs.Lock()
+	if pending := c.pendingUpdates[clusterID]; pending != nil {
+		// superseded by this update
+		close(pending.Stop)
+	}
+	c.pendingUpdates[clusterID] = remoteCluster
+	c.cs.Unlock()
+
+	go func() {
+		err := c.updateCallback(clusterID, remoteCluster)
+		c.cs.Lock()
+		defer c.cs.Unlock()
+		if c.pendingUpdates[clusterID] != remoteCluster {
+			// superseded by a later update, or the cluster was removed; both stopped remoteCluster already
+			return
+		}
+		delete(c.pendingUpdates, clusterID)
+		if err != nil {
+			log.Errorf("Updating cluster_id=%v from secret=%v: %v", clusterID, secretKey, err)
+			close(remoteCluster.Stop)
+			c.queue.AddRateLimited(secretKey)
+			return
+		}
+		if c.cs.remoteClusters[secretKey][clusterID] != prev {
+			close(remoteCluster.Stop)
+			return
+		}
+		c.cs.remoteClusters[secretKey][clusterID] = remoteCluster
+		close(prev.Stop)
+		log.Infof("finished callback for %s and starting to sync", clusterID)
+		go remoteCluster.Run()
+	}()
+}
+
+// cancelPendingUpdate stops the cluster of an update that has not completed yet. Must be called with the
+// ClusterStore lock held.
+func (c *Controller) cancelPendingUpdate(clusterID cluster.ID) {
+	if pending := c.pendingUpdates[clusterID]; pending != nil {
+		close(pending.Stop)
+		delete(c.pendingUpdates, clusterID)
+	}
 }
 
 func (c *Controller) deleteSecret(secretKey string) {
@@ -485,6 +659,7 @@ func (c *Controller) deleteSecret(secretKey string) {
 				clusterID, secretKey, err)
 		}
 		close(cluster.Stop)
+		c.cancelPendingUpdate(clusterID)
 		delete(c.cs.remoteClusters[secretKey], clusterID)
 	}
 	delete(c.cs.remoteClusters, secretKey)
@@ -503,6 +678,7 @@ func (c *Controller) deleteMemberCluster(secretKey string, clusterID cluster.ID)
 			clusterID, secretKey, err)
 	}
 	close(c.cs.remoteClusters[secretKey][clusterID].Stop)
+	c.cancelPendingUpdate(clusterID)
 	delete(c.cs.remoteClusters[secretKey], clusterID)
 }
 
@@ -518,11 +694,26 @@ func (c *Controller) ListRemoteClusters() []cluster.DebugInfo {
 				syncStatus = "timeout"
 			}
 
-			out = append(out, cluster.DebugInfo{
+			info := cluster.DebugInfo{
 				ID:         clusterID,
 				SecretName: secretName,
 				SyncStatus: syncStatus,
-			})
+			}
+			if started := c.syncStarted.Load(); started != 0 {
+				t := time.Unix(0, started)
+				info.SyncStarted = &t
+				if done := c.syncDone.Load(); done != 0 {
+					info.SyncDuration = time.Duration(done - started)
+				}
+			}
+			if c.tokenSource != nil {
+				t := c.tokenSource.expiryTime()
+				info.CredentialExpiry = &t
+			} else if !c.credentialExpiry.IsZero() {
+				t := c.credentialExpiry
+				info.CredentialExpiry = &t
+			}
+			out = append(out, info)
 		}
 	}
 	return out
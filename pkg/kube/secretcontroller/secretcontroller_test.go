@@ -187,3 +187,73 @@ func Test_SecretController(t *testing.T) {
 		})
 	}
 }
+
+func Test_SecretControllerUpdateAsync(t *testing.T) {
+	BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
+		return kube.NewFakeClient(), nil
+	}
+	clientset := kube.NewFakeClient()
+	var (
+		updateMu   sync.Mutex
+		updates    int
+		addedIDs   []cluster.ID
+		unblock    = make(chan struct{})
+		failUpdate = true
+	)
+	add := func(id cluster.ID, _ *Cluster) error {
+		updateMu.Lock()
+		defer updateMu.Unlock()
+		addedIDs = append(addedIDs, id)
+		return nil
+	}
+	update := func(id cluster.ID, _ *Cluster) error {
+		<-unblock
+		updateMu.Lock()
+		defer updateMu.Unlock()
+		updates++
+		if failUpdate {
+			failUpdate = false
+			return fmt.Errorf("sync timeout")
+		}
+		return nil
+	}
+	stopCh := make(chan struct{})
+	t.Cleanup(func() {
+		close(stopCh)
+	})
+	c := StartSecretController(clientset, add, update, deleteCallback, secretNamespace, time.Microsecond, stopCh)
+	kube.WaitForCacheSyncInterval(stopCh, time.Microsecond, c.informer.HasSynced)
+	clientset.RunAndWait(stopCh)
+	g := NewWithT(t)
+
+	_, err := clientset.CoreV1().Secrets(secretNamespace).Create(context.TODO(),
+		makeSecret("s0", clusterCredential{"c0", []byte("kubeconfig0-0")}), metav1.CreateOptions{})
+	g.Expect(err).Should(BeNil())
+	g.Eventually(func() *Cluster { return c.cs.Get("istio-system/s0", "c0") }, 10*time.Second).ShouldNot(BeNil())
+	prev := c.cs.Get("istio-system/s0", "c0")
+
+	_, err = clientset.CoreV1().Secrets(secretNamespace).Update(context.TODO(),
+		makeSecret("s0", clusterCredential{"c0", []byte("kubeconfig0-1")}), metav1.UpdateOptions{})
+	g.Expect(err).Should(BeNil())
+
+	// A pending update does not block other secrets
+	_, err = clientset.CoreV1().Secrets(secretNamespace).Create(context.TODO(),
+		makeSecret("s1", clusterCredential{"c1", []byte("kubeconfig1-0")}), metav1.CreateOptions{})
+	g.Expect(err).Should(BeNil())
+	g.Eventually(func() []cluster.ID {
+		updateMu.Lock()
+		defer updateMu.Unlock()
+		return addedIDs
+	}, 10*time.Second).Should(ConsistOf(cluster.ID("c0"), cluster.ID("c1")))
+	g.Expect(c.cs.Get("istio-system/s0", "c0")).To(BeIdenticalTo(prev))
+
+	// The failed update keeps the previous cluster and is retried
+	close(unblock)
+	g.Eventually(func() int {
+		updateMu.Lock()
+		defer updateMu.Unlock()
+		return updates
+	}, 10*time.Second).Should(Equal(2))
+	g.Eventually(func() bool { return c.cs.Get("istio-system/s0", "c0") != prev }, 10*time.Second).Should(BeTrue())
+	g.Expect(prev.Stop).To(BeClosed())
+}
@@ -0,0 +1,305 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretcontroller
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/atomic"
+	"golang.org/x/oauth2"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/transport"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/security/pkg/util"
+	"istio.io/pkg/log"
+)
+
+const (
+	// defaultTokenRequestDuration is the lifetime of requested tokens if the secret does not set one.
+	defaultTokenRequestDuration = time.Hour
+	// minTokenRequestDuration is the shortest token lifetime accepted by the TokenRequest API.
+	minTokenRequestDuration = 10 * time.Minute
+)
+
+// tokenRefreshRetryInterval is how often a failed token refresh is retried. Refreshes start with a fifth of the token
+// lifetime left, so there are several retries before the current token expires. This is overridden for testing only.
+var tokenRefreshRetryInterval = 30 * time.Second
+
+// tokenRequestSource is the token source of a remote cluster whose secret is annotated with
+// TokenRequestServiceAccountAnnotation. It starts with the token of the kubeconfig, and requests a new token of the
+// service account from the remote cluster, authenticated with the bootstrap token of the secret, once 80% of its
+// lifetime has passed. The token is rotated in the client transport, so the clients of the cluster are not rebuilt.
+type tokenRequestSource struct {
+	clusterID      string
+	namespace      string
+	serviceAccount string
+	duration       time.Duration
+	// bootstrapToken authenticates the token requests, see TokenRequestBootstrapTokenSuffix
+	bootstrapToken string
+	// bootstrapClient is the client of the remote cluster authenticated with bootstrapToken, set when the clients of
+	// the cluster are built.
+	bootstrapClient kubernetes.Interface
+	// kubeConfigSha is the hash of the kubeconfig without its token, which changes on every refresh, and of the
+	// bootstrap token.
+	kubeConfigSha [sha256.Size]byte
+	// persist writes a new token back to the secret, so that restarted istiods start with a valid token.
+	persist func(token string) error
+
+	token *atomic.String
+	// expiry is when the current token expires, in Unix nanoseconds
+	expiry *atomic.Int64
+	// persisted is false while the current token has not been written to the secret
+	persisted *atomic.Bool
+}
+
+var _ oauth2.TokenSource = &tokenRequestSource{}
+
+// newTokenRequestSource returns the token source of a cluster of a secret, or nil if the secret is not annotated with
+// TokenRequestServiceAccountAnnotation.
+func (c *Controller) newTokenRequestSource(secretKey string, s *corev1.Secret, clusterID string,
+	kubeConfig []byte) (*tokenRequestSource, error) {
+	serviceAccount := s.Annotations[TokenRequestServiceAccountAnnotation]
+	if serviceAccount == "" {
+		return nil, nil
+	}
+	saNamespace, saName, err := cache.SplitMetaNamespaceKey(serviceAccount)
+	if err != nil || saNamespace == "" {
+		return nil, fmt.Errorf("invalid %s annotation %q: expected namespace/name",
+			TokenRequestServiceAccountAnnotation, serviceAccount)
+	}
+	duration := defaultTokenRequestDuration
+	if d, ok := s.Annotations[TokenRequestDurationAnnotation]; ok {
+		if duration, err = time.ParseDuration(d); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation %q: %v", TokenRequestDurationAnnotation, d, err)
+		}
+		if duration < minTokenRequestDuration {
+			return nil, fmt.Errorf("invalid %s annotation %q: must be at least %v",
+				TokenRequestDurationAnnotation, d, minTokenRequestDuration)
+		}
+	}
+	token, err := currentToken(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := util.GetExp(token)
+	if err != nil || exp.IsZero() {
+		return nil, fmt.Errorf("failed reading the expiry of the token: %v", err)
+	}
+	bootstrapToken := string(s.Data[clusterID+TokenRequestBootstrapTokenSuffix])
+	if bootstrapToken == "" {
+		return nil, fmt.Errorf("missing bootstrap token %s", clusterID+TokenRequestBootstrapTokenSuffix)
+	}
+	withoutToken, err := replaceToken(kubeConfig, "")
+	if err != nil {
+		return nil, err
+	}
+	return &tokenRequestSource{
+		clusterID:      clusterID,
+		namespace:      saNamespace,
+		serviceAccount: saName,
+		duration:       duration,
+		bootstrapToken: bootstrapToken,
+		kubeConfigSha:  sha256.Sum256(append(withoutToken, bootstrapToken...)),
+		persist: func(token string) error {
+			return c.persistToken(secretKey, clusterID, token)
+		},
+		token:     atomic.NewString(token),
+		expiry:    atomic.NewInt64(exp.UnixNano()),
+		persisted: atomic.NewBool(true),
+	}, nil
+}
+
+// isBootstrapTokenKey returns true if the key of a secret holds the bootstrap token of a cluster rather than a
+// kubeconfig.
+func isBootstrapTokenKey(key string) bool {
+	return strings.HasSuffix(key, TokenRequestBootstrapTokenSuffix)
+}
+
+// Token implements oauth2.TokenSource.
+func (s *tokenRequestSource) Token() (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: s.token.Load()}, nil
+}
+
+func (s *tokenRequestSource) expiryTime() time.Time {
+	return time.Unix(0, s.expiry.Load())
+}
+
+// update switches to the token of another source of the same cluster if it expires later, which is the case when
+// the secret was updated with a token refreshed by another istiod.
+func (s *tokenRequestSource) update(other *tokenRequestSource) {
+	if other.expiry.Load() > s.expiry.Load() {
+		s.token.Store(other.token.Load())
+		s.expiry.Store(other.expiry.Load())
+		s.persisted.Store(true)
+		credentialExpiration.With(clusterLabel.Value(s.clusterID)).Record(float64(other.expiryTime().Unix()))
+	}
+}
+
+// run refreshes the token until stop is closed. Failures are retried every tokenRefreshRetryInterval.
+func (s *tokenRequestSource) run(client kubernetes.Interface, stop <-chan struct{}) {
+	timer := time.NewTimer(time.Until(s.refreshAt()))
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		next, err := s.sync(client)
+		if err != nil {
+			next = tokenRefreshRetryInterval
+			tokenRefreshFailures.With(clusterLabel.Value(s.clusterID)).Increment()
+			log.Errorf("failed refreshing the token of cluster %s, retrying in %v: %v", s.clusterID, next, err)
+		}
+		timer.Reset(next)
+	}
+}
+
+// sync requests a new token once the current one is due for a refresh, and writes it to the secret. It returns when
+// the next refresh is due.
+func (s *tokenRequestSource) sync(client kubernetes.Interface) (time.Duration, error) {
+	// another istiod may have refreshed the token in the meantime
+	if !time.Now().Before(s.refreshAt()) {
+		if err := s.refresh(client); err != nil {
+			return 0, fmt.Errorf("failed requesting token of %s/%s: %v", s.namespace, s.serviceAccount, err)
+		}
+	}
+	if !s.persisted.Load() {
+		// the cluster keeps using the new token, but restarted istiods need it once the previous one expires
+		if err := s.persist(s.token.Load()); err != nil {
+			return 0, fmt.Errorf("failed writing the token to the secret: %v", err)
+		}
+		s.persisted.Store(true)
+	}
+	return time.Until(s.refreshAt()), nil
+}
+
+func (s *tokenRequestSource) refreshAt() time.Time {
+	return s.expiryTime().Add(-s.duration / 5)
+}
+
+// refresh requests a new token with the bootstrap client.
+func (s *tokenRequestSource) refresh(client kubernetes.Interface) error {
+	seconds := int64(s.duration.Seconds())
+	tr, err := client.CoreV1().ServiceAccounts(s.namespace).CreateToken(context.TODO(), s.serviceAccount,
+		&authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds}},
+		metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	expiry := tr.Status.ExpirationTimestamp.Time
+	if expiry.IsZero() {
+		expiry = time.Now().Add(s.duration)
+	}
+	s.token.Store(tr.Status.Token)
+	s.expiry.Store(expiry.UnixNano())
+	s.persisted.Store(false)
+	credentialExpiration.With(clusterLabel.Value(s.clusterID)).Record(float64(expiry.Unix()))
+	log.Infof("requested new token of %s/%s for cluster_id=%v, expiring at %v",
+		s.namespace, s.serviceAccount, s.clusterID, expiry)
+	return nil
+}
+
+// persistToken writes the token of a cluster back to its secret. Updates that only change the token are not
+// propagated to the clients, but let other and restarted istiods start with a valid token.
+func (c *Controller) persistToken(secretKey string, clusterID string, token string) error {
+	obj, exists, err := c.informer.GetIndexer().GetByKey(secretKey)
+	if err != nil || !exists {
+		return fmt.Errorf("secret %s not found: %v", secretKey, err)
+	}
+	s := obj.(*corev1.Secret).DeepCopy()
+	kubeConfig, ok := s.Data[clusterID]
+	if !ok {
+		return fmt.Errorf("secret %s has no kubeconfig for cluster %s", secretKey, clusterID)
+	}
+	if s.Data[clusterID], err = replaceToken(kubeConfig, token); err != nil {
+		return err
+	}
+	_, err = c.kubeclientset.CoreV1().Secrets(s.Namespace).Update(context.TODO(), s, metav1.UpdateOptions{})
+	if kerrors.IsConflict(err) {
+		log.Infof("secret %s was updated concurrently, skipping writing the token of cluster %s", secretKey, clusterID)
+		return nil
+	}
+	return err
+}
+
+// buildTokenRequestClients creates kube.Clients from the provided kubeconfig which authenticate with the token of the
+// token source rather than the token of the kubeconfig. It also creates the bootstrap client of the token source.
+func buildTokenRequestClients(kubeConfig []byte, ts *tokenRequestSource) (kube.Client, error) {
+	rawConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig cannot be loaded: %v", err)
+	}
+	if err := clientcmd.Validate(*rawConfig); err != nil {
+		return nil, fmt.Errorf("kubeconfig is not valid: %v", err)
+	}
+	restConfig, err := clientcmd.NewDefaultClientConfig(*rawConfig, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	bootstrapConfig := rest.CopyConfig(restConfig)
+	bootstrapConfig.BearerToken = ts.bootstrapToken
+	if ts.bootstrapClient, err = kubernetes.NewForConfig(bootstrapConfig); err != nil {
+		return nil, fmt.Errorf("failed to create bootstrap client: %v", err)
+	}
+	// The static token would take precedence over the token source
+	restConfig.BearerToken = ""
+	restConfig.Wrap(transport.TokenSourceWrapTransport(ts))
+	clients, err := kube.NewClient(kube.NewClientConfigForRestConfig(restConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kube clients: %v", err)
+	}
+	return clients, nil
+}
+
+// currentToken returns the bearer token of the current context of a kubeconfig.
+func currentToken(kubeConfig []byte) (string, error) {
+	rawConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return "", err
+	}
+	ctx := rawConfig.Contexts[rawConfig.CurrentContext]
+	if ctx == nil {
+		return "", fmt.Errorf("current context %q not found", rawConfig.CurrentContext)
+	}
+	authInfo := rawConfig.AuthInfos[ctx.AuthInfo]
+	if authInfo == nil || authInfo.Token == "" {
+		return "", fmt.Errorf("user %q has no token", ctx.AuthInfo)
+	}
+	return authInfo.Token, nil
+}
+
+// replaceToken returns the kubeconfig with the bearer token of its current context replaced. The token is replaced in
+// place, which keeps the rest of the kubeconfig as it is.
+func replaceToken(kubeConfig []byte, token string) ([]byte, error) {
+	current, err := currentToken(kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Replace(kubeConfig, []byte(current), []byte(token), -1), nil
+}
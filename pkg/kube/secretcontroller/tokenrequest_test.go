@@ -0,0 +1,250 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secretcontroller
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func tokenExpiringAt(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJSUzI1NiJ9." + payload + ".c2lnbmF0dXJl"
+}
+
+func tokenRequestSecret(token string) *corev1.Secret {
+	secret := makeSecret("s0", clusterCredential{"c0", kubeConfigWithUser("    token: " + token)})
+	secret.Annotations = map[string]string{
+		TokenRequestServiceAccountAnnotation: "istio-system/istio-reader-service-account",
+		TokenRequestDurationAnnotation:       "1h",
+	}
+	secret.Data["c0"+TokenRequestBootstrapTokenSuffix] = []byte("bootstrap-token")
+	return secret
+}
+
+// fakeTokenRequests makes the remote client return newToken for token requests, after failing the given number of them.
+func fakeTokenRequests(remoteClient kube.Client, newToken string, failures int) *atomic.Int32 {
+	requests := atomic.NewInt32(0)
+	remoteClient.Kube().(*fake.Clientset).PrependReactor("create", "serviceaccounts",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() != "token" {
+				return false, nil, nil
+			}
+			if int(requests.Inc()) <= failures {
+				return true, nil, errors.New("unavailable")
+			}
+			requested := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+			return true, &authenticationv1.TokenRequest{Status: authenticationv1.TokenRequestStatus{
+				Token:               newToken,
+				ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Duration(*requested.Spec.ExpirationSeconds) * time.Second)),
+			}}, nil
+		})
+	return requests
+}
+
+func TestTokenRequestSourceRefresh(t *testing.T) {
+	tokenRefreshRetryInterval = 10 * time.Millisecond
+	defer func() { tokenRefreshRetryInterval = 30 * time.Second }()
+
+	oldToken := tokenExpiringAt(time.Now().Add(5 * time.Minute))
+	secret := tokenRequestSecret(oldToken)
+	clientset := fake.NewSimpleClientset(secret)
+	controller := NewController(clientset, secretNamespace, nil, nil, nil)
+	if err := controller.informer.GetIndexer().Add(secret); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := controller.newTokenRequestSource("istio-system/s0", secret, "c0", secret.Data["c0"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok, _ := ts.Token(); tok.AccessToken != oldToken {
+		t.Fatalf("expected the token of the kubeconfig, got %q", tok.AccessToken)
+	}
+
+	// the first write of the new token to the secret fails
+	updates := atomic.NewInt32(0)
+	clientset.PrependReactor("update", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if updates.Inc() == 1 {
+			return true, nil, errors.New("conflict")
+		}
+		return false, nil, nil
+	})
+	remoteClient := kube.NewFakeClient()
+	requests := fakeTokenRequests(remoteClient, "new-token", 2)
+	stop := make(chan struct{})
+	defer close(stop)
+	go ts.run(remoteClient.Kube(), stop)
+
+	// failed refreshes are retried until one succeeds
+	retry.UntilSuccessOrFail(t, func() error {
+		if tok, _ := ts.Token(); tok.AccessToken != "new-token" {
+			return fmt.Errorf("token was not refreshed after %d requests", requests.Load())
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+	if requests.Load() != 3 {
+		t.Fatalf("expected 3 token requests, got %d", requests.Load())
+	}
+	if until := time.Until(ts.expiryTime()); until < 55*time.Minute {
+		t.Fatalf("expected the new token to expire in an hour, got %v", until)
+	}
+
+	// the new token is written back to the secret once the failed write is retried, without requesting another token
+	want, err := replaceToken(secret.Data["c0"], "new-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry.UntilSuccessOrFail(t, func() error {
+		got, err := clientset.CoreV1().Secrets(secretNamespace).Get(context.TODO(), "s0", metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if string(got.Data["c0"]) != string(want) {
+			return fmt.Errorf("got kubeconfig:\n%s\nwant:\n%s", got.Data["c0"], want)
+		}
+		return nil
+	}, retry.Timeout(5*time.Second))
+	if requests.Load() != 3 {
+		t.Fatalf("expected 3 token requests, got %d", requests.Load())
+	}
+}
+
+func TestTokenRequestTransport(t *testing.T) {
+	authorization := atomic.NewString("")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kind":"NamespaceList","apiVersion":"v1","items":[]}`))
+	}))
+	defer srv.Close()
+
+	oldToken := tokenExpiringAt(time.Now().Add(time.Hour))
+	secret := tokenRequestSecret(oldToken)
+	kubeConfig := []byte(strings.Replace(string(secret.Data["c0"]), "https://remote.example.com", srv.URL, 1))
+	controller := NewController(fake.NewSimpleClientset(), secretNamespace, nil, nil, nil)
+	ts, err := controller.newTokenRequestSource("istio-system/s0", secret, "c0", kubeConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients, err := buildTokenRequestClients(kubeConfig, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{oldToken, "new-token"} {
+		ts.token.Store(token)
+		if _, err := clients.Kube().CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if got := authorization.Load(); got != "Bearer "+token {
+			t.Fatalf("got authorization %q, want the token %q", got, token)
+		}
+	}
+
+	// tokens are requested with the bootstrap token rather than the token they replace
+	if _, err := ts.bootstrapClient.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := authorization.Load(); got != "Bearer bootstrap-token" {
+		t.Fatalf("got authorization %q, want the bootstrap token", got)
+	}
+}
+
+func TestTokenRequestSecretUpdate(t *testing.T) {
+	updates := atomic.NewInt32(0)
+	controller := NewController(fake.NewSimpleClientset(), secretNamespace,
+		func(cluster.ID, *Cluster) error { return nil },
+		func(cluster.ID, *Cluster) error {
+			updates.Inc()
+			return nil
+		}, nil)
+	defer controller.close()
+
+	if err := controller.addSecret("istio-system/s0", tokenRequestSecret(tokenExpiringAt(time.Now().Add(time.Hour)))); err != nil {
+		t.Fatal(err)
+	}
+	first := controller.cs.Get("istio-system/s0", "c0")
+	if first == nil || first.tokenSource == nil {
+		t.Fatal("expected a cluster with a token source")
+	}
+	// the bootstrap token is not a cluster
+	if n := controller.cs.Len(); n != 1 {
+		t.Fatalf("expected 1 cluster, got %d", n)
+	}
+
+	// a token refreshed by another istiod is used without rebuilding the cluster
+	newToken := tokenExpiringAt(time.Now().Add(2 * time.Hour))
+	_ = controller.addSecret("istio-system/s0", tokenRequestSecret(newToken))
+	if updates.Load() != 0 || controller.cs.Get("istio-system/s0", "c0") != first {
+		t.Fatal("expected the cluster not to be rebuilt for a new token")
+	}
+	if tok, _ := first.tokenSource.Token(); tok.AccessToken != newToken {
+		t.Fatalf("expected the token of the updated secret, got %q", tok.AccessToken)
+	}
+
+	// an older token does not replace the current one
+	_ = controller.addSecret("istio-system/s0", tokenRequestSecret(tokenExpiringAt(time.Now().Add(time.Minute))))
+	if tok, _ := first.tokenSource.Token(); tok.AccessToken != newToken {
+		t.Fatalf("expected the token to be kept, got %q", tok.AccessToken)
+	}
+}
+
+func TestTokenRequestInvalidAnnotation(t *testing.T) {
+	controller := NewController(fake.NewSimpleClientset(), secretNamespace, nil, nil, nil)
+	secret := tokenRequestSecret(tokenExpiringAt(time.Now().Add(time.Hour)))
+	secret.Annotations[TokenRequestServiceAccountAnnotation] = "istio-reader-service-account"
+	if _, err := controller.newTokenRequestSource("istio-system/s0", secret, "c0", secret.Data["c0"]); err == nil {
+		t.Fatal("expected error for service account without namespace")
+	}
+}
+
+func TestTokenRequestMissingBootstrapToken(t *testing.T) {
+	controller := NewController(fake.NewSimpleClientset(), secretNamespace,
+		func(cluster.ID, *Cluster) error { return nil }, nil, nil)
+	defer controller.close()
+
+	secret := tokenRequestSecret(tokenExpiringAt(time.Now().Add(time.Hour)))
+	delete(secret.Data, "c0"+TokenRequestBootstrapTokenSuffix)
+	// the cluster is added with the token of its kubeconfig, and the error is retried
+	if err := controller.addSecret("istio-system/s0", secret); err == nil {
+		t.Fatal("expected error for missing bootstrap token")
+	}
+	c := controller.cs.Get("istio-system/s0", "c0")
+	if c == nil {
+		t.Fatal("expected the cluster to be added")
+	}
+	if c.tokenSource != nil {
+		t.Fatal("expected the cluster not to have a token source")
+	}
+}